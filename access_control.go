@@ -0,0 +1,97 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/comments"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/policy"
+	"github.com/kickback-app/api/utils"
+)
+
+// Enforce guards every route listed in table by resolving the kickback the
+// request is scoped to and checking the caller's relationship to it
+func (s S) Enforce(table policy.Table) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, ok := table.Lookup(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+		action := describeAction(c)
+		eventID, ownerID, err := s.resolveScope(c)
+		if err != nil {
+			handlers.EncodeError(c, err)
+			c.Abort()
+			return
+		}
+		if eventID == "" {
+			logger.Error(c, "route %s is in the policy table but is not scoped to a kickback", c.FullPath())
+			handlers.EncodeError(c, handlers.ForbiddenError{Action: action})
+			c.Abort()
+			return
+		}
+		event, err := s.EventService.GetEvent(c, eventID)
+		if err != nil {
+			handlers.EncodeError(c, handlers.NotFound("event", err))
+			c.Abort()
+			return
+		}
+		userID := utils.CurrentUser(c).ID
+		rel := policy.Resolve(event, userID)
+		rel.IsOwner = ownerID != "" && ownerID == userID
+		if !rule(rel) {
+			logger.Warn(c, "denied %s for user %s (%s) on kickback %s", action, userID, rel.Role, eventID)
+			handlers.EncodeError(c, handlers.ForbiddenError{Action: action})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// resolveScope finds the kickback a request targets and, for routes addressing
+// a single task, expense or media comment, who created it
+func (s S) resolveScope(c *gin.Context) (string, string, error) {
+	if commentID := c.Param("commentId"); commentID != "" {
+		author, err := s.Comments.Get(commentID)
+		if err == comments.ErrNotFound {
+			// written before authors were kept, nobody owns it
+			return c.Param("kickbackId"), "", nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if author.KickbackID != c.Param("kickbackId") || author.ItemID != c.Param("itemId") {
+			return "", "", handlers.NotFoundError{Resource: "comment"}
+		}
+		return author.KickbackID, author.UserID, nil
+	}
+	if eventID := c.Param("eventId"); eventID != "" {
+		return eventID, "", nil
+	}
+	if kickbackID := c.Param("kickbackId"); kickbackID != "" {
+		return kickbackID, "", nil
+	}
+	if taskID := c.Param("taskId"); taskID != "" {
+		task, err := s.TaskService.GetTask(c, taskID)
+		if err != nil {
+			return "", "", handlers.NotFound("task", err)
+		}
+		return task.ParentID, task.CreatedBy, nil
+	}
+	if expenseID := c.Param("expenseId"); expenseID != "" {
+		expense, err := s.ExpenseService.GetExpense(c, expenseID)
+		if err != nil {
+			return "", "", handlers.NotFound("expense", err)
+		}
+		return expense.ParentID, expense.CreatedBy, nil
+	}
+	return "", "", nil
+}
+
+func describeAction(c *gin.Context) string {
+	return strings.ToLower(c.Request.Method) + " " + c.FullPath()
+}
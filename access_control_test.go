@@ -0,0 +1,81 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/internal/services"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/comments"
+	"github.com/kickback-app/api/server/config"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestCommentPermissions(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.SigningToken = "mock-signing-token"
+	s, err := server.New(cfg, gin.New(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.EventService = services.EventService{
+		DBClient: utils.MockDBClient{
+			CallCount: new(int),
+			DefaultResponse: `{"_id": "mockEventId", "createdBy": "mockHostId", "members": [
+				{"userId": "mockHostId", "status": "going"},
+				{"userId": "mockUserId", "status": "going"},
+				{"userId": "mockAuthorId", "status": "going"}
+			]}`,
+		},
+	}
+	s.Comments = comments.NewMemoryStore()
+	author := comments.Author{CommentID: "CMT_1", KickbackID: "mockEventId", ItemID: "MDA_1", UserID: "mockAuthorId"}
+	if err := s.Comments.Save(author); err != nil {
+		t.Fatal(err)
+	}
+	s.AttachRoutes()
+	tokens, err := s.TokenIssuer.NewSession("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name   string
+		Method string
+		Path   string
+		Body   string
+	}{
+		{
+			Name:   "members can't edit the comments of others",
+			Method: http.MethodPut,
+			Path:   "/v1/kickbacks/mockEventId/media/MDA_1/comment/CMT_1",
+			Body:   `{"message": "edited"}`,
+		},
+		{
+			Name:   "members can't delete the comments of others",
+			Method: http.MethodDelete,
+			Path:   "/v1/kickbacks/mockEventId/media/MDA_1/comment/CMT_1",
+		},
+		{
+			Name:   "comments without a known author are left to the hosts",
+			Method: http.MethodDelete,
+			Path:   "/v1/kickbacks/mockEventId/media/MDA_1/comment/CMT_2",
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.Method, c.Path, strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-JWT", tokens.AccessToken)
+		s.Engine().ServeHTTP(w, req)
+		assert.EqualValues(t, http.StatusForbidden, w.Code, c.Name)
+		assert.Equal(t, "FORBIDDEN", gjson.Get(w.Body.String(), "meta.error.errorCode").String(), c.Name)
+	}
+}
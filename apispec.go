@@ -0,0 +1,628 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/etag"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/health"
+	"github.com/kickback-app/api/server/ical"
+	"github.com/kickback-app/api/server/idempotency"
+	"github.com/kickback-app/api/server/openapi"
+	"github.com/kickback-app/api/server/paging"
+	"github.com/kickback-app/api/server/patch"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/waitlist"
+)
+
+// apiSpec describes every route attached by AttachRoutes. Request and result
+// schemas are generated from the types the handlers decode and encode, the
+// contract test fails when a route is registered without being described here.
+func apiSpec() *openapi.Document {
+	d := specBuilder{openapi.New(openapi.Info{
+		Title:       "Kickback API",
+		Description: "Every JSON response is wrapped in the APIResponse envelope, errors are reported in meta.error. Request bodies are checked against their schema and fields a schema doesn't list are rejected.",
+		Version:     "1",
+	})}
+	d.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"jwt":             {Type: "apiKey", In: "header", Name: "X-JWT", Description: "access token issued by /otp/verify or /auth/refresh"},
+		"inviteToken":     {Type: "apiKey", In: "query", Name: "token", Description: "signed token from the link in an invite SMS"},
+		"twilioSignature": {Type: "apiKey", In: "header", Name: "X-Twilio-Signature"},
+	}
+	d.Security = []openapi.SecurityRequirement{{"jwt": {}}}
+	d.Describe("MemberStatus", models.MemberStatus(""), openapi.Enum(
+		string(models.MemberStatusInvited),
+		string(models.MemberStatusGoing),
+		string(models.MemberStatusNotGoing),
+		string(models.MemberStatusMaybe),
+		string(memberStatusNoResponse),
+	))
+	d.SchemaOf(handlers.APIResponse{})
+	d.Components.Responses["Error"] = &openapi.Response{
+		Description: "the request failed, meta.error holds the errorCode clients branch on",
+		Content:     jsonContent(openapi.Ref("APIResponse")),
+	}
+
+	d.constraints()
+
+	d.operations()
+	d.events()
+	d.tasks()
+	d.expenses()
+	d.notes()
+	d.media()
+	d.chats()
+	d.notifications()
+	d.users()
+	return d.Document
+}
+
+type specBuilder struct {
+	*openapi.Document
+}
+
+var (
+	public       = &[]openapi.SecurityRequirement{}
+	byInvite     = &[]openapi.SecurityRequirement{{"inviteToken": {}}}
+	byTwilio     = &[]openapi.SecurityRequirement{{"twilioSignature": {}}}
+	ifMatch      = header(etag.HeaderIfMatch, "only apply the change if the resource still has this ETag")
+	ifNoneMatch  = header(etag.HeaderIfNoneMatch, "answer 304 Not Modified if the resource still has this ETag")
+	noContent    = map[string]*openapi.Response{"204": {Description: "No Content"}}
+	emptySuccess = map[string]*openapi.Response{"200": {Description: "OK"}}
+	calendar     = map[string]*openapi.Response{"200": {Description: "OK", Content: map[string]openapi.MediaType{ical.ContentType: {Schema: openapi.String()}}}}
+	minLimit     = 1.0
+	maxLimit     = float64(paging.MaxLimit)
+	maxGuests    = float64(maxGuestsPerMember)
+	maxReminder  = float64(maxReminderHours)
+	windowFrom   = query("from", "start of the window to list occurrences in, now by default", &openapi.Schema{Type: "string", Format: "date-time"})
+	windowTo     = query("to", "end of the window, at most 366 days after from and 90 days by default", &openapi.Schema{Type: "string", Format: "date-time"})
+	// see editScope
+	editScopeParams = []openapi.Parameter{
+		query("scope", "which occurrences of a recurring event the change applies to, all by default", openapi.Enum(editScopes...)),
+		query("occurrence", "occurrenceId the scope starts at, required unless scope is all", openapi.String()),
+	}
+)
+
+// add documents an operation. Every operation may answer with an error, and
+// POSTs to /v1 accept an Idempotency-Key.
+func (d specBuilder) add(method, path string, op openapi.Operation) {
+	op.Responses = withResponse(op.Responses, "default", &openapi.Response{Ref: "#/components/responses/Error"})
+	if method == http.MethodPost && strings.HasPrefix(path, "/v1/") {
+		op.Parameters = append(op.Parameters, header(idempotency.Header, "retries with the same key replay the first response instead of repeating the request"))
+	}
+	d.Add(method, path, op)
+}
+
+// conditionalGet documents a read answering If-None-Match
+func (d specBuilder) conditionalGet(path string, op openapi.Operation) {
+	op.Parameters = append(op.Parameters, ifNoneMatch)
+	op.Responses = withResponse(op.Responses, "304", &openapi.Response{Description: "Not Modified"})
+	d.add(http.MethodGet, path, op)
+}
+
+// guarded documents a write honouring If-Match, see guardWrite
+func (d specBuilder) guarded(method, path string, op openapi.Operation) {
+	op.Parameters = append(op.Parameters, ifMatch)
+	op.Responses = withResponse(op.Responses, "412", &openapi.Response{Ref: "#/components/responses/Error"})
+	d.add(method, path, op)
+}
+
+// withResponse copies responses, they are shared between operations
+func withResponse(responses map[string]*openapi.Response, status string, r *openapi.Response) map[string]*openapi.Response {
+	out := map[string]*openapi.Response{status: r}
+	for k, v := range responses {
+		out[k] = v
+	}
+	return out
+}
+
+// body is a JSON request body shaped like v
+func (d specBuilder) body(v interface{}) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: jsonContent(d.SchemaOf(v))}
+}
+
+// mergePatch is a merge patch of the resource v, restricted to allowed
+func (d specBuilder) mergePatch(v interface{}, allowed []string) *openapi.RequestBody {
+	schema := d.SchemaOf(v)
+	return &openapi.RequestBody{
+		Required:    true,
+		Description: fmt.Sprintf("RFC 7396 merge patch, only %s may be changed", strings.Join(allowed, ", ")),
+		Content: map[string]openapi.MediaType{
+			patch.ContentType:  {Schema: schema},
+			"application/json": {Schema: schema},
+		},
+	}
+}
+
+// ok is a 200 with result in the envelope
+func ok(result *openapi.Schema) map[string]*openapi.Response {
+	return map[string]*openapi.Response{"200": {
+		Description: "OK",
+		Content:     jsonContent(enveloped(result)),
+	}}
+}
+
+// page is a page of a listing, meta.next_cursor is set when there are more
+func page(result *openapi.Schema) map[string]*openapi.Response {
+	r := ok(result)
+	r["200"].Description = "a page of results, pass meta.next_cursor as cursor to get the next one"
+	return r
+}
+
+// pageParams documents the paging parameters accepted for spec, see paging.Parse
+func pageParams(spec paging.Spec) []openapi.Parameter {
+	sorts := []string{}
+	for _, s := range spec.Sorts {
+		sorts = append(sorts, s, "-"+s)
+	}
+	params := []openapi.Parameter{
+		query("limit", fmt.Sprintf("page size, %d by default", paging.DefaultLimit), &openapi.Schema{Type: "integer", Minimum: &minLimit, Maximum: &maxLimit}),
+		query("cursor", "next_cursor of the previous page", openapi.String()),
+		query("sort", fmt.Sprintf("field to sort by, prefixed with - for descending order. Defaults to %s", spec.DefaultSort), openapi.Enum(sorts...)),
+	}
+	for _, f := range spec.Filters {
+		schema := openapi.String()
+		if len(f.Values) > 0 {
+			schema = openapi.Enum(f.Values...)
+		}
+		params = append(params, query(f.Param, "", schema))
+	}
+	return params
+}
+
+func query(name, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func header(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "header", Description: description, Schema: openapi.String()}
+}
+
+func jsonContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: schema}}
+}
+
+func enveloped(result *openapi.Schema) *openapi.Schema {
+	return &openapi.Schema{AllOf: []*openapi.Schema{
+		openapi.Ref("APIResponse"),
+		openapi.Object(map[string]*openapi.Schema{"result": result}),
+	}}
+}
+
+// constraints are checked by ValidateRequests before the handlers run, the
+// handlers and validation.go still make their own checks
+func (d specBuilder) constraints() {
+	for _, v := range []interface{}{models.Event{}, createEventRequest{}, models.Task{}, models.Expense{}, models.Channel{}} {
+		require(d.Component(v), "name")
+	}
+	for _, v := range []interface{}{
+		models.Event{}, createEventRequest{}, models.EventUpdates{},
+		models.Task{}, models.TaskUpdates{},
+		models.Expense{}, models.ExpenseUpdates{},
+		models.Channel{}, models.ChannelUpdates{},
+	} {
+		maxLength(d.Component(v), "name", maxNameLength)
+	}
+	maxLength(d.Component(models.Event{}), "description", maxDescriptionLength)
+	maxLength(d.Component(createEventRequest{}), "description", maxDescriptionLength)
+	maxLength(d.Component(models.EventUpdates{}), "description", maxDescriptionLength)
+	schedule := d.Component(recurrence.Schedule{})
+	require(schedule, "start", "end", "time_zone")
+	schedule.Properties["rrule"].Description = "iCalendar RRULE, FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH are supported. Without one the event happens once."
+	minimum(d.Component(createEventRequest{}), "capacity", 1)
+	capacity := d.Component(capacityRequest{})
+	require(capacity, "capacity")
+	minimum(capacity, "capacity", 1)
+	capacity.Properties["capacity"].Description = "how many members can be going, null lifts the limit"
+	require(d.Component(waitlistMoveRequest{}), "position")
+	minimum(d.Component(waitlistMoveRequest{}), "position", 1)
+
+	phoneNumber := func(s *openapi.Schema, field string) {
+		require(s, field)
+		s.Properties[field].Pattern = e164PhoneNumber.String()
+	}
+	phoneNumber(d.Component(sendOTPRequest{}), "phoneNumber")
+	phoneNumber(d.Component(verifyOTPRequest{}), "phoneNumber")
+	require(d.Component(verifyOTPRequest{}), "code")
+	phoneNumber(d.Component(inviteUserRequest{}), "phone_number")
+	require(d.Component(refreshTokenRequest{}), "refreshToken")
+	rsvp := d.Component(rsvpRequest{})
+	require(rsvp, "status")
+	minimum(rsvp, "guests", 0)
+	rsvp.Properties["guests"].Description = "how many guests the member brings, leave out to keep the guests they brought before"
+	policy := d.Component(guests.Policy{})
+	minimum(policy, "max_per_member", 0)
+	policy.Properties["max_per_member"].Maximum = &maxGuests
+	deadline := d.Component(reminders.Policy{})
+	deadline.Properties["reminders"].Description = "when to remind the members who haven't answered, leave out for 3 days and 1 day before the deadline"
+	reminder := d.Component(reminders.Reminder{})
+	minimum(reminder, "hours_before", 1)
+	reminder.Properties["hours_before"].Maximum = &maxReminder
+	require(d.Component(commentRequest{}), "message")
+	require(d.Component(assigneeUpdateRequest{}), "assignee")
+	members := d.Component(inviteMembersRequest{})
+	require(members.Properties["users"].Items, "userId")
+	phoneNumber(members.Properties["new_users"].Items, "phone_number")
+}
+
+// require marks the fields of s as required, fields the schema doesn't have
+// are skipped
+func require(s *openapi.Schema, fields ...string) {
+	for _, f := range fields {
+		if _, ok := s.Properties[f]; ok {
+			s.Required = append(s.Required, f)
+		}
+	}
+}
+
+func maxLength(s *openapi.Schema, field string, max int) {
+	if p, ok := s.Properties[field]; ok {
+		p.MaxLength = &max
+	}
+}
+
+func minimum(s *openapi.Schema, field string, min float64) {
+	if p, ok := s.Properties[field]; ok {
+		p.Minimum = &min
+	}
+}
+
+func (d specBuilder) operations() {
+	text := map[string]*openapi.Response{"200": {Description: "OK", Content: map[string]openapi.MediaType{"text/plain": {Schema: openapi.String()}}}}
+	status := map[string]*openapi.Response{"200": {Description: "OK", Content: jsonContent(openapi.Object(map[string]*openapi.Schema{"status": openapi.String()}))}}
+	d.add(http.MethodGet, "/", openapi.Operation{OperationID: "welcome", Tags: []string{"Operations"}, Security: public, Responses: text})
+	d.add(http.MethodGet, "/openapi.json", openapi.Operation{OperationID: "getOpenAPI", Summary: "this document", Tags: []string{"Operations"}, Security: public, Responses: emptySuccess})
+	d.add(http.MethodGet, "/healthcheck", openapi.Operation{OperationID: "healthcheck", Summary: "liveness probe, kept for older monitors", Tags: []string{"Operations"}, Security: public, Responses: status})
+	d.add(http.MethodGet, "/health/live", openapi.Operation{OperationID: "liveness", Summary: "liveness probe", Tags: []string{"Operations"}, Security: public, Responses: status})
+	d.add(http.MethodGet, "/health/ready", openapi.Operation{
+		OperationID: "readiness",
+		Summary:     "readiness probe, answers 503 while draining or when a critical dependency is down",
+		Tags:        []string{"Operations"},
+		Security:    public,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "OK", Content: jsonContent(openapi.Object(map[string]*openapi.Schema{
+				"status": openapi.String(),
+				"checks": openapi.MapOf(d.SchemaOf(health.Result{})),
+			}))},
+			"503": {Description: "Service Unavailable"},
+		},
+	})
+
+	twilioForm := &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{"application/x-www-form-urlencoded": {Schema: openapi.MapOf(openapi.String())}}}
+	d.add(http.MethodPost, "/sms-status", openapi.Operation{OperationID: "sentSMSStatus", Summary: "twilio status callback of a sent SMS", Tags: []string{"Twilio"}, Security: byTwilio, RequestBody: twilioForm, Responses: status})
+	d.add(http.MethodPost, "/sms-inbound", openapi.Operation{
+		OperationID: "receiveSMS",
+		Summary:     "twilio messaging webhook, RSVPs to the pending invites of the sender",
+		Tags:        []string{"Twilio"},
+		Security:    byTwilio,
+		RequestBody: twilioForm,
+		Responses:   map[string]*openapi.Response{"200": {Description: "TwiML reply", Content: map[string]openapi.MediaType{"text/xml": {Schema: openapi.String()}}}},
+	})
+
+	tokens := d.SchemaOf(auth.TokenPair{})
+	d.add(http.MethodPost, "/otp/send", openapi.Operation{
+		OperationID: "sendOTP",
+		Summary:     "text a one time password to a phone number",
+		Tags:        []string{"Auth"},
+		Security:    public,
+		RequestBody: d.body(sendOTPRequest{}),
+		Responses:   map[string]*openapi.Response{"201": {Description: "Created"}},
+	})
+	d.add(http.MethodPost, "/otp/verify", openapi.Operation{
+		OperationID: "verifyOTP",
+		Summary:     "check a one time password, tokens are only returned once it is approved",
+		Description: "The result is the verifier's report, not wrapped in the envelope. When the code is approved and the phone number belongs to a user, tokens is added to it.",
+		Tags:        []string{"Auth"},
+		Security:    public,
+		RequestBody: d.body(verifyOTPRequest{}),
+		Responses: map[string]*openapi.Response{"200": {Description: "OK", Content: jsonContent(openapi.Object(map[string]*openapi.Schema{
+			"tokens": tokens,
+		}))}},
+	})
+	d.add(http.MethodPost, "/auth/refresh", openapi.Operation{OperationID: "refreshToken", Summary: "exchange a refresh token for a new token pair", Tags: []string{"Auth"}, Security: public, RequestBody: d.body(refreshTokenRequest{}), Responses: ok(tokens)})
+	d.add(http.MethodPost, "/auth/logout", openapi.Operation{OperationID: "logout", Summary: "revoke a refresh token", Tags: []string{"Auth"}, Security: public, RequestBody: d.body(refreshTokenRequest{}), Responses: noContent})
+}
+
+func (d specBuilder) events() {
+	event := d.SchemaOf(models.Event{})
+	tags := []string{"Events"}
+	d.add(http.MethodGet, "/v1/events", openapi.Operation{
+		OperationID: "getUsersEvents",
+		Summary:     "kickbacks the caller is a member of",
+		Tags:        tags,
+		Description: "With from or to, recurring events are listed with the occurrences in the window and left out when they have none.",
+		Parameters: append(pageParams(eventListing),
+			query("before", "only events created before this unix timestamp", &openapi.Schema{Type: "integer"}),
+			query("after", "only events created after this unix timestamp", &openapi.Schema{Type: "integer"}),
+			windowFrom,
+			windowTo,
+		),
+		Responses: page(openapi.Object(map[string]*openapi.Schema{"events": openapi.ArrayOf(event)})),
+	})
+	d.add(http.MethodPost, "/v1/events", openapi.Operation{OperationID: "createEvent", Tags: tags, RequestBody: d.body(createEventRequest{}), Responses: ok(event)})
+	d.conditionalGet("/v1/events/:eventId", openapi.Operation{OperationID: "getEvent", Tags: tags, Responses: ok(event)})
+	d.guarded(http.MethodPut, "/v1/events/:eventId", openapi.Operation{
+		OperationID: "updateEvent",
+		Description: "For recurring events, scope=this overrides the details of a single occurrence and answers with it. scope=following ends the series before the occurrence and answers with the new event holding the rest of it.",
+		Tags:        tags,
+		Parameters:  editScopeParams,
+		RequestBody: d.body(models.EventUpdates{}),
+		Responses:   ok(event),
+	})
+	d.guarded(http.MethodPatch, "/v1/events/:eventId", openapi.Operation{OperationID: "patchEvent", Tags: tags, RequestBody: d.mergePatch(models.Event{}, eventPatchable), Responses: ok(event)})
+	d.guarded(http.MethodDelete, "/v1/events/:eventId", openapi.Operation{
+		OperationID: "deleteEvent",
+		Description: "For recurring events, scope=this cancels a single occurrence and scope=following ends the series before it.",
+		Tags:        tags,
+		Parameters:  editScopeParams,
+		Responses:   noContent,
+	})
+
+	settings := openapi.Object(map[string]*openapi.Schema{"settings": d.SchemaOf(eventSettings{})})
+	d.conditionalGet("/v1/events/:eventId/settings", openapi.Operation{OperationID: "getEventSettings", Tags: tags, Responses: ok(settings)})
+	d.add(http.MethodPut, "/v1/events/:eventId/settings", openapi.Operation{OperationID: "updateEventSettings", Tags: tags, RequestBody: d.body(models.M{}), Responses: noContent})
+	d.guarded(http.MethodPatch, "/v1/events/:eventId/settings", openapi.Operation{OperationID: "patchEventSettings", Tags: tags, RequestBody: d.mergePatch(eventSettings{}, eventSettingsPatchable), Responses: noContent})
+
+	d.add(http.MethodGet, "/v1/events/:eventId/members", openapi.Operation{
+		OperationID: "getEventMembers",
+		Tags:        tags,
+		Summary:     "members with the guests they bring, and headcounts by status with the members on the waitlist counted as waitlisted",
+		Responses: ok(openapi.Object(map[string]*openapi.Schema{
+			"members":    openapi.ArrayOf(openapi.MapOf(openapi.Any())),
+			"headcounts": openapi.MapOf(d.SchemaOf(headcount{})),
+		})),
+	})
+	d.add(http.MethodPost, "/v1/events/:eventId/members", openapi.Operation{
+		OperationID: "inviteEventMembers",
+		Tags:        tags,
+		RequestBody: d.body(inviteMembersRequest{}),
+		Responses: ok(openapi.Object(map[string]*openapi.Schema{
+			"failures":     openapi.MapOf(openapi.Any()),
+			"invites_sent": openapi.Integer(),
+		})),
+	})
+
+	rsvp := openapi.Operation{
+		OperationID: "rsvp",
+		Summary:     "set the caller's status for the event",
+		Tags:        tags,
+		Description: "Going to an event at capacity puts the caller on its waitlist instead, answered with 202 and their place on it. Occurrences at capacity answer 409 EVENT_FULL. Once the RSVP deadline set by the hosts passes, going and maybe answer 409 RSVP_CLOSED, not_going is still taken, and members who never answered become no_response.",
+		Parameters: []openapi.Parameter{
+			query("source", "where the RSVP was made, e.g. web", openapi.String()),
+			query("occurrence", "occurrenceId of the single occurrence of a recurring event the RSVP is for", openapi.String()),
+		},
+		RequestBody: d.body(rsvpRequest{}),
+		Responses: withResponse(ok(openapi.MapOf(openapi.Ref("MemberStatus"))), "202", &openapi.Response{
+			Description: "the event is full, the caller is on the waitlist",
+			Content:     jsonContent(enveloped(d.SchemaOf(waitlist.Entry{}))),
+		}),
+	}
+	d.add(http.MethodPut, "/v1/events/:eventId/rsvp", rsvp)
+
+	schedule := d.SchemaOf(recurrence.Schedule{})
+	occurrence := d.SchemaOf(recurrence.Occurrence{})
+	d.add(http.MethodGet, "/v1/events/:eventId/schedule", openapi.Operation{OperationID: "getEventSchedule", Tags: tags, Responses: ok(schedule)})
+	d.add(http.MethodPut, "/v1/events/:eventId/schedule", openapi.Operation{
+		OperationID: "updateEventSchedule",
+		Summary:     "set when the event happens, exceptions and RSVPs of occurrences it no longer has are dropped",
+		Tags:        tags,
+		RequestBody: d.body(recurrence.Schedule{}),
+		Responses:   ok(schedule),
+	})
+	d.add(http.MethodGet, "/v1/events/:eventId/occurrences", openapi.Operation{
+		OperationID: "getEventOccurrences",
+		Summary:     "occurrences of a recurring event, the next 90 days by default",
+		Tags:        tags,
+		Parameters:  []openapi.Parameter{windowFrom, windowTo},
+		Responses:   ok(openapi.Object(map[string]*openapi.Schema{"occurrences": openapi.ArrayOf(occurrence)})),
+	})
+	d.add(http.MethodPut, "/v1/events/:eventId/occurrences/:occurrenceId", openapi.Operation{
+		OperationID: "updateOccurrence",
+		Summary:     "move, cancel or restore a single occurrence",
+		Tags:        tags,
+		RequestBody: d.body(occurrenceRequest{}),
+		Responses:   ok(occurrence),
+	})
+	export := openapi.Operation{
+		OperationID: "exportEventCalendar",
+		Summary:     "the scheduled event as an iCalendar file, with an override for every occurrence that was changed",
+		Tags:        tags,
+		Responses:   calendar,
+	}
+	d.add(http.MethodGet, "/v1/events/:eventId/calendar.ics", export)
+
+	waiting := d.SchemaOf(waitlistResponse{})
+	d.add(http.MethodPut, "/v1/events/:eventId/capacity", openapi.Operation{
+		OperationID: "updateEventCapacity",
+		Summary:     "limit how many members can be going, raising or lifting the limit promotes members from the waitlist",
+		Tags:        tags,
+		RequestBody: d.body(capacityRequest{}),
+		Responses:   withResponse(ok(waiting), "204", &openapi.Response{Description: "the limit was lifted"}),
+	})
+	d.add(http.MethodGet, "/v1/events/:eventId/waitlist", openapi.Operation{OperationID: "getWaitlist", Summary: "members waiting for a spot, in the order they get promoted", Tags: tags, Responses: ok(waiting)})
+	d.add(http.MethodPut, "/v1/events/:eventId/waitlist/:userId", openapi.Operation{
+		OperationID: "moveWaitlistMember",
+		Summary:     "put a waiting member at a new position",
+		Tags:        tags,
+		RequestBody: d.body(waitlistMoveRequest{}),
+		Responses:   ok(waiting),
+	})
+	d.add(http.MethodPost, "/v1/events/:eventId/waitlist/:userId/promote", openapi.Operation{
+		OperationID: "promoteWaitlistMember",
+		Summary:     "give a waiting member a spot, even when the event is full",
+		Tags:        tags,
+		Responses:   ok(waiting),
+	})
+
+	// the web app opened from an invite link
+	webGet := openapi.Operation{OperationID: "getInvitedEvent", Tags: []string{"Web"}, Security: byInvite, Responses: ok(event)}
+	d.conditionalGet("/events/:eventId", webGet)
+	rsvp.OperationID = "rsvpByInvite"
+	rsvp.Tags = []string{"Web"}
+	rsvp.Security = byInvite
+	d.add(http.MethodPut, "/events/:eventId/rsvp", rsvp)
+	export.OperationID = "exportInvitedEventCalendar"
+	export.Tags = []string{"Web"}
+	export.Security = byInvite
+	d.add(http.MethodGet, "/events/:eventId/calendar.ics", export)
+}
+
+func (d specBuilder) tasks() {
+	task := d.SchemaOf(models.Task{})
+	tags := []string{"Tasks"}
+	d.add(http.MethodPost, "/v1/kickbacks/:kickbackId/tasks", openapi.Operation{OperationID: "createTask", Tags: tags, RequestBody: d.body(models.Task{}), Responses: ok(openapi.Object(map[string]*openapi.Schema{"taskId": openapi.String()}))})
+	d.add(http.MethodGet, "/v1/kickbacks/:kickbackId/tasks", openapi.Operation{OperationID: "getTasks", Tags: tags, Parameters: pageParams(taskListing), Responses: page(openapi.Object(map[string]*openapi.Schema{"tasks": openapi.ArrayOf(task)}))})
+	d.conditionalGet("/v1/tasks/:taskId", openapi.Operation{OperationID: "getTask", Tags: tags, Responses: ok(task)})
+	d.guarded(http.MethodPut, "/v1/tasks/:taskId", openapi.Operation{OperationID: "updateTask", Tags: tags, RequestBody: d.body(models.TaskUpdates{}), Responses: noContent})
+	d.guarded(http.MethodPatch, "/v1/tasks/:taskId", openapi.Operation{OperationID: "patchTask", Tags: tags, RequestBody: d.mergePatch(models.Task{}, taskPatchable), Responses: noContent})
+	d.guarded(http.MethodDelete, "/v1/tasks/:taskId", openapi.Operation{OperationID: "deleteTask", Tags: tags, Responses: noContent})
+}
+
+func (d specBuilder) expenses() {
+	expense := d.SchemaOf(models.Expense{})
+	wrapped := openapi.Object(map[string]*openapi.Schema{"expense": expense})
+	tags := []string{"Expenses"}
+	d.add(http.MethodGet, "/v1/kickbacks/:kickbackId/expenses", openapi.Operation{
+		OperationID: "getExpenses",
+		Summary:     "expenses of the kickback, the totals cover every expense and not only the page",
+		Tags:        tags,
+		Parameters:  pageParams(expenseListing),
+		Responses: page(openapi.Object(map[string]*openapi.Schema{
+			"total_collected": openapi.Number(),
+			"total_owed":      openapi.Number(),
+			"total_paid":      openapi.Number(),
+			"total_owes":      openapi.Number(),
+			"expenses_owed":   openapi.ArrayOf(expense),
+			"expenses_owes":   openapi.ArrayOf(expense),
+			"total_expenses":  openapi.Integer(),
+			"user":            openapi.Any(),
+		})),
+	})
+	d.add(http.MethodPost, "/v1/kickbacks/:kickbackId/expenses", openapi.Operation{OperationID: "createExpense", Tags: tags, RequestBody: d.body(models.Expense{}), Responses: ok(openapi.Object(map[string]*openapi.Schema{"expenseId": openapi.String()}))})
+	d.conditionalGet("/v1/expenses/:expenseId", openapi.Operation{OperationID: "getExpense", Tags: tags, Responses: ok(expense)})
+	d.guarded(http.MethodPut, "/v1/expenses/:expenseId", openapi.Operation{OperationID: "updateExpense", Tags: tags, RequestBody: d.body(models.ExpenseUpdates{}), Responses: ok(wrapped)})
+	d.guarded(http.MethodPatch, "/v1/expenses/:expenseId", openapi.Operation{OperationID: "patchExpense", Tags: tags, RequestBody: d.mergePatch(models.Expense{}, expensePatchable), Responses: ok(wrapped)})
+	d.guarded(http.MethodDelete, "/v1/expenses/:expenseId", openapi.Operation{OperationID: "deleteExpense", Tags: tags, Responses: noContent})
+	d.guarded(http.MethodPut, "/v1/expenses/:expenseId/assignees", openapi.Operation{OperationID: "updateExpenseAssignee", Summary: "mark an assignee's share as paid or unpaid", Tags: tags, RequestBody: d.body(assigneeUpdateRequest{}), Responses: ok(wrapped)})
+}
+
+func (d specBuilder) notes() {
+	tags := []string{"Notes"}
+	d.conditionalGet("/v1/notes/:kickbackId", openapi.Operation{OperationID: "getNote", Tags: tags, Responses: ok(d.SchemaOf(models.Note{}))})
+	d.guarded(http.MethodPut, "/v1/notes/:kickbackId", openapi.Operation{OperationID: "updateNote", Tags: tags, RequestBody: d.body(map[string]string{}), Responses: noContent})
+}
+
+func (d specBuilder) media() {
+	media := d.SchemaOf(models.Media{})
+	tags := []string{"Media"}
+	d.add(http.MethodGet, "/v1/kickbacks/:kickbackId/media", openapi.Operation{OperationID: "getMediaItemsMetadata", Tags: tags, Parameters: pageParams(mediaListing), Responses: page(openapi.Object(map[string]*openapi.Schema{"mediaItems": openapi.ArrayOf(media)}))})
+	d.add(http.MethodGet, "/v1/kickbacks/:kickbackId/media/:itemId", openapi.Operation{OperationID: "getMediaItem", Tags: tags, Responses: ok(media)})
+	d.add(http.MethodPost, "/v1/kickbacks/:kickbackId/media", openapi.Operation{OperationID: "createMediaItem", Tags: tags, RequestBody: d.body(models.Media{}), Responses: ok(media)})
+	d.add(http.MethodPut, "/v1/kickbacks/:kickbackId/media/:itemId/metadata", openapi.Operation{OperationID: "updateMediaItemMetadata", Tags: tags, RequestBody: d.body(models.MediaUpdates{}), Responses: noContent})
+	d.guarded(http.MethodPatch, "/v1/kickbacks/:kickbackId/media/:itemId/metadata", openapi.Operation{OperationID: "patchMediaItemMetadata", Tags: tags, RequestBody: d.mergePatch(models.Media{}, mediaPatchable), Responses: noContent})
+	d.add(http.MethodDelete, "/v1/kickbacks/:kickbackId/media/:itemId", openapi.Operation{OperationID: "deleteMediaItem", Tags: tags, Responses: noContent})
+	d.add(http.MethodPost, "/v1/kickbacks/:kickbackId/media/:itemId/comment", openapi.Operation{OperationID: "addMediaItemComment", Tags: tags, RequestBody: d.body(commentRequest{}), Responses: noContent})
+	d.add(http.MethodPut, "/v1/kickbacks/:kickbackId/media/:itemId/comment/:commentId", openapi.Operation{OperationID: "updateMediaItemComment", Tags: tags, RequestBody: d.body(commentRequest{}), Responses: noContent})
+	d.add(http.MethodDelete, "/v1/kickbacks/:kickbackId/media/:itemId/comment/:commentId", openapi.Operation{OperationID: "deleteMediaItemComment", Tags: tags, Responses: noContent})
+}
+
+func (d specBuilder) chats() {
+	channel := d.SchemaOf(models.Channel{})
+	pinned := d.SchemaOf(models.PinnedMessage{})
+	tags := []string{"Chats"}
+	d.add(http.MethodGet, "/v1/kickbacks/:kickbackId/channels", openapi.Operation{OperationID: "getChannels", Tags: tags, Parameters: pageParams(channelListing), Responses: page(openapi.Object(map[string]*openapi.Schema{"chats": openapi.ArrayOf(channel)}))})
+	d.add(http.MethodPost, "/v1/kickbacks/:kickbackId/channels", openapi.Operation{OperationID: "createChannel", Tags: tags, RequestBody: d.body(models.Channel{}), Responses: ok(openapi.Object(map[string]*openapi.Schema{"chatId": openapi.String()}))})
+	d.add(http.MethodPut, "/v1/chats/:kickbackId/channels/:channelId", openapi.Operation{OperationID: "updateChannel", Tags: tags, RequestBody: d.body(models.ChannelUpdates{}), Responses: noContent})
+	d.guarded(http.MethodPatch, "/v1/chats/:kickbackId/channels/:channelId", openapi.Operation{OperationID: "patchChannel", Tags: tags, RequestBody: d.mergePatch(models.Channel{}, channelPatchable), Responses: noContent})
+	d.add(http.MethodDelete, "/v1/chats/:kickbackId/channels/:channelId", openapi.Operation{OperationID: "deleteChannel", Tags: tags, Responses: noContent})
+	d.add(http.MethodPut, "/v1/chats/:kickbackId/channels/:channelId/members", openapi.Operation{OperationID: "updateChannelMembers", Tags: tags, RequestBody: d.body(channelMembersRequest{}), Responses: noContent})
+	d.add(http.MethodPost, "/v1/chats/:kickbackId/channels/:channelId/pinned", openapi.Operation{OperationID: "pinChatMessage", Tags: tags, RequestBody: d.body(models.PinnedMessage{}), Responses: noContent})
+	d.add(http.MethodDelete, "/v1/chats/:kickbackId/channels/:channelId/pinned", openapi.Operation{OperationID: "unpinChatMessage", Tags: tags, RequestBody: d.body(models.PinnedMessage{}), Responses: noContent})
+	d.add(http.MethodGet, "/v1/chats/:kickbackId/pinned", openapi.Operation{
+		OperationID: "listPinnedChatMessages",
+		Tags:        tags,
+		Parameters:  append(pageParams(pinnedMessageListing), query("channelId", "only messages pinned in this channel", openapi.String())),
+		Responses:   page(openapi.Object(map[string]*openapi.Schema{"pinned_messages": openapi.ArrayOf(pinned)})),
+	})
+}
+
+func (d specBuilder) notifications() {
+	tags := []string{"Notifications"}
+	report := d.SchemaOf(notificationReport{})
+	d.add(http.MethodGet, "/v1/notifications", openapi.Operation{OperationID: "getNotifications", Tags: tags, Parameters: pageParams(notificationListing), Responses: page(openapi.ArrayOf(d.SchemaOf(models.Notification{})))})
+	d.add(http.MethodPost, "/v1/notifications", openapi.Operation{
+		OperationID: "sendNotification",
+		Tags:        tags,
+		RequestBody: d.body(models.Notification{}),
+		Responses:   ok(openapi.Object(map[string]*openapi.Schema{"notificationId": openapi.String(), "errors": report})),
+	})
+	d.add(http.MethodGet, "/v1/notifications/reports/:notificationId", openapi.Operation{
+		OperationID: "getNotificationReport",
+		Summary:     "delivery of the SMS sent for a notification, as reported by twilio",
+		Description: "Only the user who sent the notification can read its report, it is not found for anyone else.",
+		Tags:        tags,
+		Responses: ok(openapi.Object(map[string]*openapi.Schema{
+			"notificationId": openapi.String(),
+			"errors":         report,
+			"sms_statuses":   openapi.ArrayOf(d.SchemaOf(smsDelivery{})),
+		})),
+	})
+	d.add(http.MethodGet, "/v1/notifications/settings", openapi.Operation{
+		OperationID: "getUsersNotificationSettings",
+		Tags:        tags,
+		Responses: ok(openapi.Object(map[string]*openapi.Schema{
+			"mute_all_notifications": openapi.Boolean(),
+			"mute_all_events":        openapi.Boolean(),
+			"mute_all_groups":        openapi.Boolean(),
+			"events":                 openapi.ArrayOf(openapi.MapOf(openapi.Any())),
+			"groups":                 openapi.ArrayOf(openapi.Any()),
+		})),
+	})
+	d.add(http.MethodPut, "/v1/notifications/settings", openapi.Operation{OperationID: "updateUsersNotificationSettings", Tags: tags, RequestBody: d.body(models.UserNotificationSettingUpdates{}), Responses: noContent})
+}
+
+func (d specBuilder) users() {
+	user := d.SchemaOf(models.User{})
+	users := openapi.Object(map[string]*openapi.Schema{"users": openapi.ArrayOf(openapi.Any())})
+	tags := []string{"Users"}
+	d.add(http.MethodGet, "/v1/users/:userId", openapi.Operation{OperationID: "getUser", Tags: tags, Responses: ok(user)})
+	d.add(http.MethodPost, "/v1/users", openapi.Operation{OperationID: "createUser", Tags: tags, RequestBody: d.body(models.User{}), Responses: ok(openapi.Object(map[string]*openapi.Schema{"userId": openapi.String()}))})
+	d.add(http.MethodPut, "/v1/users", openapi.Operation{OperationID: "updateUser", Summary: "update the caller's profile", Tags: tags, RequestBody: d.body(models.UserUpdates{}), Responses: noContent})
+	d.guarded(http.MethodPatch, "/v1/users", openapi.Operation{OperationID: "patchUser", Summary: "update the caller's profile", Tags: tags, RequestBody: d.mergePatch(models.User{}, userPatchable), Responses: noContent})
+	d.add(http.MethodDelete, "/v1/users", openapi.Operation{OperationID: "deleteUser", Summary: "delete the caller's account", Tags: tags, Responses: noContent})
+	d.add(http.MethodGet, "/v1/users/connections", openapi.Operation{OperationID: "getUsersConnections", Tags: tags, Responses: ok(openapi.Object(map[string]*openapi.Schema{"connections": openapi.ArrayOf(openapi.Any())}))})
+	d.add(http.MethodGet, "/v1/users/following/default", openapi.Operation{OperationID: "getSponsoredUsers", Tags: tags, Responses: ok(users)})
+	d.add(http.MethodPost, "/v1/users/search", openapi.Operation{OperationID: "searchUsers", Tags: tags, RequestBody: d.body(models.UserSearch{}), Responses: ok(users)})
+	d.add(http.MethodPost, "/v1/users/invite", openapi.Operation{
+		OperationID: "inviteUser",
+		Summary:     "invite someone to kickback by SMS",
+		Tags:        tags,
+		RequestBody: d.body(inviteUserRequest{}),
+		Responses: ok(openapi.Object(map[string]*openapi.Schema{
+			"userId":         openapi.String(),
+			"sentSMS":        openapi.Boolean(),
+			"alreadyCreated": openapi.Boolean(),
+		})),
+	})
+	d.add(http.MethodPost, "/v1/users/calendar", openapi.Operation{
+		OperationID: "createCalendarFeed",
+		Summary:     "issue the url of the caller's calendar feed, the previous url stops working",
+		Tags:        tags,
+		Responses:   map[string]*openapi.Response{"201": {Description: "Created", Content: jsonContent(enveloped(d.SchemaOf(calendarFeedResponse{})))}},
+	})
+	d.add(http.MethodDelete, "/v1/users/calendar", openapi.Operation{OperationID: "deleteCalendarFeed", Summary: "revoke the url of the caller's calendar feed", Tags: tags, Responses: noContent})
+	d.conditionalGet("/calendar/:token", openapi.Operation{
+		OperationID: "getCalendarFeed",
+		Summary:     "the scheduled events of the user the feed was issued to, for calendar apps to subscribe to",
+		Description: "The token issued by createCalendarFeed authenticates the feed, a .ics suffix is accepted.",
+		Tags:        tags,
+		Security:    public,
+		Responses:   calendar,
+	})
+}
@@ -0,0 +1,89 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/config"
+	"github.com/kickback-app/api/server/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPISpecCoversRoutes is the contract between the router and the served
+// OpenAPI document: every registered route must be described
+func TestAPISpecCoversRoutes(t *testing.T) {
+	s, err := server.New(config.Default(), gin.New(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AttachRoutes()
+	w := httptest.NewRecorder()
+	s.Engine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+	for _, route := range s.Engine().Routes() {
+		if !doc.Has(route.Method, route.Path) {
+			t.Errorf("%s %s is not in the OpenAPI document, describe it in apispec.go", route.Method, route.Path)
+		}
+	}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			declared := map[string]bool{}
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					declared[p.Name] = true
+				}
+			}
+			for _, seg := range strings.Split(path, "/") {
+				if strings.HasPrefix(seg, "{") && !declared[strings.Trim(seg, "{}")] {
+					t.Errorf("%s %s doesn't declare the path parameter %s", method, path, seg)
+				}
+			}
+		}
+	}
+
+	// every reference must resolve
+	var raw interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range refs(raw) {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		var found bool
+		switch parts[0] {
+		case "schemas":
+			_, found = doc.Components.Schemas[parts[1]]
+		case "responses":
+			_, found = doc.Components.Responses[parts[1]]
+		}
+		assert.True(t, found, "dangling reference %s", ref)
+	}
+}
+
+func refs(v interface{}) []string {
+	var out []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if s, ok := child.(string); ok && k == "$ref" {
+				out = append(out, s)
+			}
+			out = append(out, refs(child)...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			out = append(out, refs(child)...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+var ErrFeedTokenNotFound = errors.New("calendar feed token not found")
+
+// FeedStore keeps the secret tokens in the URLs of calendar feeds. Calendar
+// apps can't send headers so the URL is the credential; each user has at most
+// one and issuing a new one revokes the previous URL. Only the sha256 of a
+// token is kept.
+type FeedStore interface {
+	Issue(userID string) (string, error)
+	// Lookup returns the user the token was issued to
+	Lookup(token string) (string, error)
+	Revoke(userID string) error
+}
+
+// feedDoc is the feed token of a user, keyed by the user ID so issuing a new
+// token replaces the previous one in a single write
+type feedDoc struct {
+	UserID    string `json:"userId"`
+	TokenHash string `json:"tokenHash"`
+}
+
+// DBFeedStore keeps the feed tokens in the database, so feed URLs keep
+// working across instances and restarts
+type DBFeedStore struct {
+	docs docstore.Collection
+}
+
+func NewDBFeedStore(docs docstore.Collection) *DBFeedStore {
+	return &DBFeedStore{docs: docs}
+}
+
+// NewMemoryFeedStore keeps the feed tokens in memory, for tests and single
+// instance deployments
+func NewMemoryFeedStore() *DBFeedStore {
+	return NewDBFeedStore(docstore.NewMemory().Collection("calendar_feeds"))
+}
+
+func (d *DBFeedStore) Issue(userID string) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = docstore.Retry(func() error {
+		var doc feedDoc
+		version, err := d.docs.Get(userID, &doc)
+		if err != nil && err != docstore.ErrNotFound {
+			return err
+		}
+		_, err = d.docs.Put(userID, version, feedDoc{UserID: userID, TokenHash: hashToken(token)})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (d *DBFeedStore) Lookup(token string) (string, error) {
+	var docs []feedDoc
+	if err := d.docs.Find(map[string]interface{}{"tokenHash": hashToken(token)}, &docs); err != nil {
+		return "", err
+	}
+	if len(docs) == 0 {
+		return "", ErrFeedTokenNotFound
+	}
+	return docs[0].UserID, nil
+}
+
+func (d *DBFeedStore) Revoke(userID string) error {
+	return docstore.Retry(func() error {
+		var doc feedDoc
+		version, err := d.docs.Get(userID, &doc)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.docs.Delete(userID, version)
+	})
+}
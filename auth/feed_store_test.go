@@ -0,0 +1,48 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedTokens(t *testing.T) {
+	store := auth.NewMemoryFeedStore()
+	first, err := store.Issue("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := store.Lookup(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "mockUserId", userID)
+
+	second, err := store.Issue("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, first, second)
+	_, err = store.Lookup(first)
+	assert.ErrorIs(t, err, auth.ErrFeedTokenNotFound, "issuing a new token revokes the previous one")
+
+	assert.NoError(t, store.Revoke("mockUserId"))
+	_, err = store.Lookup(second)
+	assert.ErrorIs(t, err, auth.ErrFeedTokenNotFound)
+}
+
+func TestFeedTokensAcrossInstances(t *testing.T) {
+	docs := docstore.NewMemory().Collection("calendar_feeds")
+	first, second := auth.NewDBFeedStore(docs), auth.NewDBFeedStore(docs)
+	token, err := first.Issue("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := second.Lookup(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "mockUserId", userID)
+
+	assert.NoError(t, second.Revoke("mockUserId"))
+	_, err = first.Lookup(token)
+	assert.ErrorIs(t, err, auth.ErrFeedTokenNotFound)
+}
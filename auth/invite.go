@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const PurposeRSVP = "rsvp"
+
+const defaultInviteTTL = 30 * 24 * time.Hour
+
+// InviteClaims identify the invitee a link was generated for
+type InviteClaims struct {
+	EventID   string `json:"e"`
+	UserID    string `json:"u"`
+	Purpose   string `json:"p"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// InviteSigner creates the compact HMAC signed tokens embedded in invite links.
+// They are deliberately not JWTs so they can never be mistaken for (or
+// replayed as) access tokens, and they only carry a single purpose.
+type InviteSigner struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+func (s InviteSigner) Sign(eventID, userID, purpose string) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", fmt.Errorf("no invite signing secret configured")
+	}
+	now := time.Now()
+	payload, err := json.Marshal(InviteClaims{
+		EventID:   eventID,
+		UserID:    userID,
+		Purpose:   purpose,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttlOrDefault(s.TTL, defaultInviteTTL)).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signature(encoded), nil
+}
+
+// Verify checks token was issued by us for eventID and purpose and has not
+// expired. Callers still need to check the invitee is a member of the event,
+// that is what revokes links of members who have been removed.
+func (s InviteSigner) Verify(token, eventID, purpose string) (InviteClaims, error) {
+	if len(s.Secret) == 0 {
+		return InviteClaims{}, fmt.Errorf("no invite signing secret configured")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return InviteClaims{}, InvalidTokenError{Reason: "malformed invite token"}
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(s.signature(parts[0]))) {
+		return InviteClaims{}, InvalidTokenError{Reason: "invalid invite token"}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return InviteClaims{}, InvalidTokenError{Reason: "malformed invite token"}
+	}
+	var claims InviteClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return InviteClaims{}, InvalidTokenError{Reason: "malformed invite token"}
+	}
+	if claims.Purpose != purpose || claims.EventID != eventID {
+		return InviteClaims{}, InvalidTokenError{Reason: "invite token is not valid for this request"}
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return InviteClaims{}, InvalidTokenError{Reason: "invite token is expired"}
+	}
+	return claims, nil
+}
+
+func (s InviteSigner) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
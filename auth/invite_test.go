@@ -0,0 +1,62 @@
+package auth_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteToken(t *testing.T) {
+	signer := auth.InviteSigner{Secret: []byte("invite-secret")}
+	token, err := signer.Sign("EVT_Mock", "mockUserId", auth.PurposeRSVP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := signer.Verify(token, "EVT_Mock", auth.PurposeRSVP)
+	assert.NoError(t, err)
+	assert.Equal(t, "mockUserId", claims.UserID)
+
+	_, err = signer.Verify(token, "EVT_Other", auth.PurposeRSVP)
+	assert.EqualError(t, err, "invite token is not valid for this request")
+	_, err = signer.Verify(token, "EVT_Mock", "calendar")
+	assert.EqualError(t, err, "invite token is not valid for this request")
+
+	other := auth.InviteSigner{Secret: []byte("another-secret")}
+	_, err = other.Verify(token, "EVT_Mock", auth.PurposeRSVP)
+	assert.EqualError(t, err, "invalid invite token")
+
+	// swapping the payload for another user's invalidates the signature
+	forged, _ := signer.Sign("EVT_Mock", "someoneElse", auth.PurposeRSVP)
+	tampered := strings.Split(forged, ".")[0] + "." + strings.Split(token, ".")[1]
+	_, err = signer.Verify(tampered, "EVT_Mock", auth.PurposeRSVP)
+	assert.EqualError(t, err, "invalid invite token")
+
+	_, err = signer.Verify("garbage", "EVT_Mock", auth.PurposeRSVP)
+	assert.EqualError(t, err, "malformed invite token")
+}
+
+func TestInviteTokenExpiry(t *testing.T) {
+	secret := []byte("invite-secret")
+	signer := auth.InviteSigner{Secret: secret}
+	issuedAt := time.Now().Add(-time.Hour)
+	payload, _ := json.Marshal(auth.InviteClaims{
+		EventID:   "EVT_Mock",
+		UserID:    "mockUserId",
+		Purpose:   auth.PurposeRSVP,
+		IssuedAt:  issuedAt.Unix(),
+		ExpiresAt: issuedAt.Add(time.Minute).Unix(),
+	})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	token := encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	_, err := signer.Verify(token, "EVT_Mock", auth.PurposeRSVP)
+	assert.EqualError(t, err, "invite token is expired")
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const defaultAccessTokenTTL = 15 * time.Minute
+const defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// TokenPair is handed to the client after a successful login or refresh
+type TokenPair struct {
+	AccessToken      string `json:"accessToken"`
+	AccessExpiresAt  int64  `json:"accessTokenExpiresAt"`
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresAt int64  `json:"refreshTokenExpiresAt"`
+}
+
+// Issuer mints short lived access tokens signed with the HMAC key KeyID from
+// Keys, and rotating refresh tokens persisted in Store. Each refresh token can
+// be used exactly once; presenting a used one is treated as theft and revokes
+// every token descended from the same login.
+type Issuer struct {
+	Keys       *KeySet
+	KeyID      string
+	Issuer     string
+	Audience   string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	Store      RefreshStore
+}
+
+// NewSession starts a new refresh token family for userID
+func (i *Issuer) NewSession(userID string) (TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return i.issue(userID, familyID)
+}
+
+// Refresh exchanges a refresh token for a new token pair
+func (i *Issuer) Refresh(refreshToken string) (TokenPair, error) {
+	if i.Store == nil {
+		return TokenPair{}, fmt.Errorf("no refresh token store configured")
+	}
+	record, err := i.Store.Consume(hashToken(refreshToken))
+	if err == ErrRefreshTokenReused {
+		if err := i.Store.RevokeFamily(record.FamilyID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, InvalidTokenError{Reason: err.Error()}
+	}
+	if err == ErrRefreshTokenNotFound || err == ErrRefreshTokenRevoked {
+		return TokenPair{}, InvalidTokenError{Reason: "invalid refresh token"}
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return i.issue(record.UserID, record.FamilyID)
+}
+
+// Revoke ends the session refreshToken belongs to
+func (i *Issuer) Revoke(refreshToken string) error {
+	if i.Store == nil {
+		return fmt.Errorf("no refresh token store configured")
+	}
+	record, err := i.Store.Lookup(hashToken(refreshToken))
+	if err == ErrRefreshTokenNotFound || err == ErrRefreshTokenRevoked {
+		return InvalidTokenError{Reason: "invalid refresh token"}
+	}
+	if err != nil {
+		return err
+	}
+	return i.Store.RevokeFamily(record.FamilyID)
+}
+
+func (i *Issuer) issue(userID, familyID string) (TokenPair, error) {
+	if i.Store == nil {
+		return TokenPair{}, fmt.Errorf("no refresh token store configured")
+	}
+	now := time.Now()
+	accessToken, accessExpiresAt, err := i.accessToken(userID, familyID, now)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refreshExpiresAt := now.Add(ttlOrDefault(i.RefreshTTL, defaultRefreshTokenTTL))
+	err = i.Store.Save(RefreshToken{
+		ID:        hashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    userID,
+		IssuedAt:  now,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt.Unix(),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt.Unix(),
+	}, nil
+}
+
+func (i *Issuer) accessToken(userID, sessionID string, now time.Time) (string, time.Time, error) {
+	if i.Keys == nil {
+		return "", time.Time{}, fmt.Errorf("no signing keys configured")
+	}
+	key, err := i.Keys.Lookup(i.KeyID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	secret, ok := key.Material.([]byte)
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("signing key '%s' is not an HMAC secret", i.KeyID)
+	}
+	expiresAt := now.Add(ttlOrDefault(i.AccessTTL, defaultAccessTokenTTL))
+	claims := jwt.MapClaims{
+		"userId": userID,
+		"sid":    sessionID,
+		"iat":    now.Unix(),
+		"nbf":    now.Unix(),
+		"exp":    expiresAt.Unix(),
+	}
+	if i.Issuer != "" {
+		claims["iss"] = i.Issuer
+	}
+	if i.Audience != "" {
+		claims["aud"] = i.Audience
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if i.KeyID != "" {
+		token.Header["kid"] = i.KeyID
+	}
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+func ttlOrDefault(ttl, def time.Duration) time.Duration {
+	if ttl <= 0 {
+		return def
+	}
+	return ttl
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
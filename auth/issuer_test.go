@@ -0,0 +1,78 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/kickback-app/api/server/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIssuer() (*auth.Issuer, auth.Validator) {
+	keys := auth.NewKeySet()
+	keys.AddHMACKeys("k1:secret")
+	issuer := &auth.Issuer{
+		Keys:     keys,
+		KeyID:    "k1",
+		Issuer:   "kickback",
+		Audience: "kickback-app",
+		Store:    auth.NewMemoryRefreshStore(),
+	}
+	return issuer, auth.Validator{Keys: keys, Issuer: "kickback", Audience: "kickback-app"}
+}
+
+func TestIssuedAccessTokenValidates(t *testing.T) {
+	issuer, validator := newIssuer()
+	tokens, err := issuer.NewSession("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := validator.Validate(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "mockUserId", claims.UserID)
+	assert.NotEmpty(t, tokens.RefreshToken)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	issuer, validator := newIssuer()
+	first, err := issuer.NewSession("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := issuer.Refresh(first.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	_, err = validator.Validate(second.AccessToken)
+	assert.NoError(t, err)
+	third, err := issuer.Refresh(second.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, third.RefreshToken)
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	issuer, _ := newIssuer()
+	first, _ := issuer.NewSession("mockUserId")
+	second, err := issuer.Refresh(first.RefreshToken)
+	assert.NoError(t, err)
+
+	// replaying the already used token kills the chain...
+	_, err = issuer.Refresh(first.RefreshToken)
+	assert.EqualError(t, err, "refresh token reuse detected")
+	// ...including the token the legitimate client currently holds
+	_, err = issuer.Refresh(second.RefreshToken)
+	assert.EqualError(t, err, "invalid refresh token")
+
+	// other sessions are unaffected
+	other, _ := issuer.NewSession("mockUserId")
+	_, err = issuer.Refresh(other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestRevoke(t *testing.T) {
+	issuer, _ := newIssuer()
+	first, _ := issuer.NewSession("mockUserId")
+	second, _ := issuer.Refresh(first.RefreshToken)
+	assert.NoError(t, issuer.Revoke(second.RefreshToken))
+	_, err := issuer.Refresh(second.RefreshToken)
+	assert.EqualError(t, err, "invalid refresh token")
+	assert.EqualError(t, issuer.Revoke("bogus"), "invalid refresh token")
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const jwksRefreshInterval = 1 * time.Hour
+
+// don't hammer the JWKS endpoint when we see tokens with bogus kids
+const jwksMinRefreshInterval = 1 * time.Minute
+
+type jwksSource struct {
+	location string
+	client   *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]Key
+	fetchedAt time.Time
+}
+
+func newJWKSSource(location string) *jwksSource {
+	return &jwksSource{
+		location: location,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     map[string]Key{},
+	}
+}
+
+func (s *jwksSource) lookup(kid string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[kid]
+	age := time.Since(s.fetchedAt)
+	if ok && age < jwksRefreshInterval {
+		return k, nil
+	}
+	if !ok && age < jwksMinRefreshInterval {
+		return Key{}, UnknownKeyError{KeyID: kid}
+	}
+	keys, err := s.fetch()
+	if err != nil {
+		if ok {
+			// keep serving the last known good key while the endpoint is down
+			return k, nil
+		}
+		return Key{}, fmt.Errorf("unable to load JWKS from %s: %v", s.location, err)
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	if k, ok := s.keys[kid]; ok {
+		return k, nil
+	}
+	return Key{}, UnknownKeyError{KeyID: kid}
+}
+
+func (s *jwksSource) fetch() (map[string]Key, error) {
+	var body io.Reader
+	if strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://") {
+		resp, err := s.client.Get(s.location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		body = resp.Body
+	} else {
+		f, err := os.Open(s.location)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		body = f
+	}
+	return ParseJWKS(body)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS decodes a JSON Web Key Set (RFC 7517) into verification keys.
+// Keys that are not meant for signatures or use unsupported types are skipped.
+func ParseJWKS(r io.Reader) (map[string]Key, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("malformed JWKS document: %v", err)
+	}
+	keys := map[string]Key{}
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		material, err := k.material()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key '%s': %v", k.Kid, err)
+		}
+		if material == nil {
+			continue
+		}
+		keys[k.Kid] = Key{ID: k.Kid, Material: material}
+	}
+	return keys, nil
+}
+
+func (k jwk) material() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
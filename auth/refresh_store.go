@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+var ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+
+// RefreshToken is the server side record of a refresh token. Every token
+// issued from the same login shares a FamilyID so the whole chain can be
+// revoked at once.
+type RefreshToken struct {
+	ID        string // sha256 of the opaque token handed to the client
+	FamilyID  string
+	UserID    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// RefreshStore persists refresh tokens. Implementations must make Consume
+// atomic so two concurrent refreshes with the same token can't both succeed,
+// even on different instances.
+type RefreshStore interface {
+	Save(t RefreshToken) error
+	// Consume marks the token as used and returns it. A token that was already
+	// consumed returns ErrRefreshTokenReused along with the record so the
+	// caller can revoke its family.
+	Consume(id string) (RefreshToken, error)
+	// Lookup returns the token without consuming it
+	Lookup(id string) (RefreshToken, error)
+	RevokeFamily(familyID string) error
+}
+
+// refreshDoc is how a refresh token is stored, Used marks the ones that were
+// exchanged already so their reuse can be detected
+type refreshDoc struct {
+	ID        string    `json:"id"`
+	FamilyID  string    `json:"familyId"`
+	UserID    string    `json:"userId"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Used      bool      `json:"used"`
+}
+
+func (d refreshDoc) Expires() time.Time {
+	return d.ExpiresAt
+}
+
+func (d refreshDoc) token() RefreshToken {
+	return RefreshToken{ID: d.ID, FamilyID: d.FamilyID, UserID: d.UserID, IssuedAt: d.IssuedAt, ExpiresAt: d.ExpiresAt}
+}
+
+// revokedFamily marks a family as revoked for as long as its tokens live
+type revokedFamily struct {
+	RevokedAt time.Time `json:"revokedAt"`
+	Until     time.Time `json:"until"`
+}
+
+func (f revokedFamily) Expires() time.Time {
+	return f.Until
+}
+
+// familyKey is where the revocation of a family is kept, token IDs are hex
+// so they can't collide with it
+func familyKey(familyID string) string {
+	return "family:" + familyID
+}
+
+// DBRefreshStore keeps refresh tokens in the database so every instance can
+// exchange them and a restart doesn't end the sessions
+type DBRefreshStore struct {
+	docs docstore.Collection
+}
+
+func NewDBRefreshStore(docs docstore.Collection) *DBRefreshStore {
+	return &DBRefreshStore{docs: docs}
+}
+
+// NewMemoryRefreshStore keeps refresh tokens in memory, for tests and single
+// instance deployments
+func NewMemoryRefreshStore() *DBRefreshStore {
+	return NewDBRefreshStore(docstore.NewMemory().Collection("refresh_tokens"))
+}
+
+func (d *DBRefreshStore) Save(t RefreshToken) error {
+	if err := d.checkFamily(t.FamilyID); err != nil {
+		return err
+	}
+	_, err := d.docs.Put(t.ID, 0, refreshDoc{
+		ID:        t.ID,
+		FamilyID:  t.FamilyID,
+		UserID:    t.UserID,
+		IssuedAt:  t.IssuedAt,
+		ExpiresAt: t.ExpiresAt,
+	})
+	return err
+}
+
+func (d *DBRefreshStore) Consume(id string) (RefreshToken, error) {
+	var consumed RefreshToken
+	err := docstore.Retry(func() error {
+		doc, version, err := d.lookup(id)
+		if err != nil {
+			return err
+		}
+		consumed = doc.token()
+		if doc.Used {
+			return ErrRefreshTokenReused
+		}
+		doc.Used = true
+		_, err = d.docs.Put(id, version, doc)
+		return err
+	})
+	return consumed, err
+}
+
+func (d *DBRefreshStore) Lookup(id string) (RefreshToken, error) {
+	doc, _, err := d.lookup(id)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	return doc.token(), nil
+}
+
+func (d *DBRefreshStore) RevokeFamily(familyID string) error {
+	var tokens []refreshDoc
+	if err := d.docs.Find(map[string]interface{}{"familyId": familyID}, &tokens); err != nil {
+		return err
+	}
+	now := time.Now()
+	revoked := revokedFamily{RevokedAt: now, Until: now}
+	for _, t := range tokens {
+		if t.ExpiresAt.After(revoked.Until) {
+			revoked.Until = t.ExpiresAt
+		}
+	}
+	if _, err := d.docs.Put(familyKey(familyID), 0, revoked); err != nil && err != docstore.ErrConflict {
+		// a conflict means the family was revoked already
+		return err
+	}
+	for _, t := range tokens {
+		err := docstore.Retry(func() error {
+			var doc refreshDoc
+			version, err := d.docs.Get(t.ID, &doc)
+			if err == docstore.ErrNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			return d.docs.Delete(t.ID, version)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DBRefreshStore) lookup(id string) (refreshDoc, int64, error) {
+	var doc refreshDoc
+	version, err := d.docs.Get(id, &doc)
+	if err == docstore.ErrNotFound {
+		return refreshDoc{}, 0, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return refreshDoc{}, 0, err
+	}
+	if err := d.checkFamily(doc.FamilyID); err != nil {
+		return refreshDoc{}, 0, err
+	}
+	return doc, version, nil
+}
+
+// checkFamily returns ErrRefreshTokenRevoked once the family was revoked
+func (d *DBRefreshStore) checkFamily(familyID string) error {
+	var revoked revokedFamily
+	_, err := d.docs.Get(familyKey(familyID), &revoked)
+	switch err {
+	case nil:
+		return ErrRefreshTokenRevoked
+	case docstore.ErrNotFound:
+		return nil
+	default:
+		return err
+	}
+}
@@ -0,0 +1,38 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBRefreshStore(t *testing.T) {
+	// two instances sharing the database
+	docs := docstore.NewMemory().Collection("refresh_tokens")
+	first, second := auth.NewDBRefreshStore(docs), auth.NewDBRefreshStore(docs)
+	now := time.Now()
+	token := auth.RefreshToken{ID: "a1", FamilyID: "fam", UserID: "USR_1", IssuedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, first.Save(token))
+
+	consumed, err := second.Consume("a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "USR_1", consumed.UserID)
+	reused, err := first.Consume("a1")
+	assert.Equal(t, auth.ErrRefreshTokenReused, err, "the other instance sees the token was used")
+	assert.Equal(t, "fam", reused.FamilyID)
+
+	next := auth.RefreshToken{ID: "b2", FamilyID: "fam", UserID: "USR_1", IssuedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, second.Save(next))
+	assert.NoError(t, first.RevokeFamily("fam"))
+	_, err = second.Lookup("b2")
+	assert.Equal(t, auth.ErrRefreshTokenNotFound, err)
+	assert.Equal(t, auth.ErrRefreshTokenRevoked, second.Save(auth.RefreshToken{ID: "c3", FamilyID: "fam", ExpiresAt: now.Add(time.Hour)}))
+
+	expired := auth.RefreshToken{ID: "d4", FamilyID: "other", IssuedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	assert.NoError(t, first.Save(expired))
+	_, err = first.Consume("d4")
+	assert.Equal(t, auth.ErrRefreshTokenNotFound, err)
+}
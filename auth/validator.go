@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var supportedMethods = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+}
+
+// Claims are the parts of a validated access token the API relies on
+type Claims struct {
+	Issuer   string
+	Audience string
+	UserID   string
+}
+
+// InvalidTokenError is returned for any token that must not be trusted. Reason
+// is safe to return to the caller.
+type InvalidTokenError struct {
+	Reason string
+}
+
+func (e InvalidTokenError) Error() string {
+	return e.Reason
+}
+
+// Validator verifies access tokens against a KeySet and the expected
+// issuer/audience. An empty Issuer or Audience disables that check.
+type Validator struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+}
+
+func (v Validator) Validate(raw string) (Claims, error) {
+	parser := jwt.Parser{ValidMethods: supportedMethods}
+	token, err := parser.Parse(raw, v.keyFunc)
+	if err != nil {
+		return Claims{}, toInvalidTokenError(err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return Claims{}, InvalidTokenError{Reason: "invalid token"}
+	}
+	// jwt-go treats exp as optional, we don't
+	if _, ok := claims["exp"]; !ok {
+		return Claims{}, InvalidTokenError{Reason: "token has no expiry"}
+	}
+	iss, _ := claims["iss"].(string)
+	if v.Issuer != "" && iss != v.Issuer {
+		return Claims{}, InvalidTokenError{Reason: "invalid token issuer"}
+	}
+	aud, ok := matchAudience(claims["aud"], v.Audience)
+	if !ok {
+		return Claims{}, InvalidTokenError{Reason: "invalid token audience"}
+	}
+	userID, _ := claims["userId"].(string)
+	if userID == "" {
+		return Claims{}, InvalidTokenError{Reason: "token is missing userId"}
+	}
+	return Claims{Issuer: iss, Audience: aud, UserID: userID}, nil
+}
+
+func (v Validator) keyFunc(token *jwt.Token) (interface{}, error) {
+	if v.Keys == nil {
+		return nil, fmt.Errorf("no signing keys configured")
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, err := v.Keys.Lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	if !key.allows(token.Method) {
+		return nil, fmt.Errorf("signing method %s not allowed for key '%s'", token.Method.Alg(), kid)
+	}
+	return key.Material, nil
+}
+
+// matchAudience accepts aud as either a single string or a list of strings and
+// returns the audience that matched
+func matchAudience(aud interface{}, want string) (string, bool) {
+	switch a := aud.(type) {
+	case string:
+		return a, want == "" || a == want
+	case []interface{}:
+		for _, v := range a {
+			if s, ok := v.(string); ok && (want == "" || s == want) {
+				return s, true
+			}
+		}
+		return "", want == ""
+	}
+	return "", want == ""
+}
+
+func toInvalidTokenError(err error) error {
+	ve, ok := err.(*jwt.ValidationError)
+	if !ok {
+		return InvalidTokenError{Reason: "invalid token"}
+	}
+	switch {
+	case ve.Errors&jwt.ValidationErrorExpired != 0:
+		return InvalidTokenError{Reason: "token is expired"}
+	case ve.Errors&jwt.ValidationErrorNotValidYet != 0:
+		return InvalidTokenError{Reason: "token is not valid yet"}
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return InvalidTokenError{Reason: "malformed token"}
+	case ve.Errors&jwt.ValidationErrorUnverifiable != 0:
+		// unknown kids are worth surfacing, they usually mean a missed key rotation
+		if _, ok := ve.Inner.(UnknownKeyError); ok {
+			return InvalidTokenError{Reason: ve.Inner.Error()}
+		}
+		return InvalidTokenError{Reason: "unable to verify token"}
+	}
+	return InvalidTokenError{Reason: "invalid token"}
+}
@@ -0,0 +1,131 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":    "kickback",
+		"aud":    "kickback-app",
+		"userId": "mockUserId",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestValidateHMAC(t *testing.T) {
+	keys := auth.NewKeySet(auth.Key{ID: "", Material: []byte("legacy")})
+	if err := keys.AddHMACKeys("k1:old-secret,k2:new-secret"); err != nil {
+		t.Fatal(err)
+	}
+	v := auth.Validator{Keys: keys, Issuer: "kickback", Audience: "kickback-app"}
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	notYet := validClaims()
+	notYet["nbf"] = time.Now().Add(time.Hour).Unix()
+	wrongIss := validClaims()
+	wrongIss["iss"] = "someone-else"
+	audList := validClaims()
+	audList["aud"] = []string{"other", "kickback-app"}
+	noExp := validClaims()
+	delete(noExp, "exp")
+	noUser := validClaims()
+	delete(noUser, "userId")
+	badUser := validClaims()
+	badUser["userId"] = 42
+
+	cases := []struct {
+		Name          string
+		Token         string
+		ExpectedError string
+	}{
+		{Name: "legacy token without kid", Token: sign(t, jwt.SigningMethodHS256, "", []byte("legacy"), validClaims())},
+		{Name: "token signed with rotated out key", Token: sign(t, jwt.SigningMethodHS256, "k1", []byte("old-secret"), validClaims())},
+		{Name: "token signed with new key", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), validClaims())},
+		{Name: "audience list", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), audList)},
+		{Name: "kid does not match secret", Token: sign(t, jwt.SigningMethodHS256, "k1", []byte("new-secret"), validClaims()), ExpectedError: "invalid token"},
+		{Name: "unknown kid", Token: sign(t, jwt.SigningMethodHS256, "k3", []byte("new-secret"), validClaims()), ExpectedError: "unknown signing key 'k3'"},
+		{Name: "expired", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), expired), ExpectedError: "token is expired"},
+		{Name: "not valid yet", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), notYet), ExpectedError: "token is not valid yet"},
+		{Name: "wrong issuer", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), wrongIss), ExpectedError: "invalid token issuer"},
+		{Name: "no expiry", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), noExp), ExpectedError: "token has no expiry"},
+		{Name: "no userId", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), noUser), ExpectedError: "token is missing userId"},
+		{Name: "non string userId", Token: sign(t, jwt.SigningMethodHS256, "k2", []byte("new-secret"), badUser), ExpectedError: "token is missing userId"},
+		{Name: "garbage", Token: "not.a.jwt", ExpectedError: "malformed token"},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		claims, err := v.Validate(c.Token)
+		if c.ExpectedError != "" {
+			assert.EqualError(t, err, c.ExpectedError, c.Name)
+			continue
+		}
+		assert.NoError(t, err, c.Name)
+		assert.Equal(t, "mockUserId", claims.UserID, c.Name)
+		assert.Equal(t, "kickback-app", claims.Audience, c.Name)
+	}
+}
+
+func TestValidateJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	doc := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": "%s", "e": "%s"},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": "%s", "y": "%s"}
+	]}`, b64(rsaKey.N), b64(big.NewInt(int64(rsaKey.E))), b64(ecKey.X), b64(ecKey.Y))
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys := auth.NewKeySet(auth.Key{ID: "", Material: []byte("legacy")})
+	keys.UseJWKS(path)
+	v := auth.Validator{Keys: keys}
+
+	_, err = v.Validate(sign(t, jwt.SigningMethodRS256, "rsa1", rsaKey, validClaims()))
+	assert.NoError(t, err)
+	_, err = v.Validate(sign(t, jwt.SigningMethodES256, "ec1", ecKey, validClaims()))
+	assert.NoError(t, err)
+	// an RSA kid must not be usable to verify an HMAC token signed with the public key
+	_, err = v.Validate(sign(t, jwt.SigningMethodHS256, "rsa1", rsaKey.N.Bytes(), validClaims()))
+	assert.EqualError(t, err, "unable to verify token")
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/handlers"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (s S) RefreshToken(c *gin.Context) {
+	var reqBody refreshTokenRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqBody); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if reqBody.RefreshToken == "" {
+		handlers.EncodeError(c, handlers.MissingBodyFieldError{Field: "refreshToken"})
+		return
+	}
+	tokens, err := s.TokenIssuer.Refresh(reqBody.RefreshToken)
+	if err != nil {
+		handlers.EncodeError(c, toAuthError(err))
+		return
+	}
+	logger.Info(c, "refreshed access token")
+	handlers.EncodeSuccess(c, http.StatusOK, tokens)
+}
+
+func (s S) Logout(c *gin.Context) {
+	var reqBody refreshTokenRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqBody); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if reqBody.RefreshToken == "" {
+		handlers.EncodeError(c, handlers.MissingBodyFieldError{Field: "refreshToken"})
+		return
+	}
+	err := s.TokenIssuer.Revoke(reqBody.RefreshToken)
+	if err != nil {
+		handlers.EncodeError(c, toAuthError(err))
+		return
+	}
+	logger.Info(c, "revoked refresh token family")
+	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+}
+
+// toAuthError turns token problems the client can act on into a 401, anything
+// else is left as an internal error
+func toAuthError(err error) error {
+	if e, ok := err.(auth.InvalidTokenError); ok {
+		return handlers.UnauthorizedError{Reason: e.Reason}
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func newTokenIssuer() *auth.Issuer {
+	keys := auth.NewKeySet()
+	keys.AddHMACKeys("k1:secret")
+	return &auth.Issuer{Keys: keys, KeyID: "k1", Store: auth.NewMemoryRefreshStore()}
+}
+
+func refreshBody(token string) string {
+	return fmt.Sprintf(`{"refreshToken": %q}`, token)
+}
+
+func TestRefreshToken(t *testing.T) {
+	issuer := newTokenIssuer()
+	session, err := issuer.NewSession("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockServer := server.S{TokenIssuer: issuer}
+	// the token handed out by each successful refresh, the next case uses it
+	latest := session.RefreshToken
+	cases := []struct {
+		Name               string
+		Body               func() string
+		ExpectedStatusCode int
+		PathToResult       string
+		ExpectedResult     string
+	}{
+		{
+			Name:               "missing refresh token",
+			Body:               func() string { return `{}` },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.errorCode",
+			ExpectedResult:     `"MISSING_BODY_FIELD"`,
+		},
+		{
+			Name:               "unknown refresh token",
+			Body:               func() string { return refreshBody("not-a-token") },
+			ExpectedStatusCode: http.StatusUnauthorized,
+			PathToResult:       "meta.error.errorMessage",
+			ExpectedResult:     `"not authorized: invalid refresh token"`,
+		},
+		{
+			Name:               "happy path - refreshing rotates the token",
+			Body:               func() string { return refreshBody(session.RefreshToken) },
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "the rotated token can be used once",
+			Body:               func() string { return refreshBody(latest) },
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "reusing a rotated token is rejected",
+			Body:               func() string { return refreshBody(session.RefreshToken) },
+			ExpectedStatusCode: http.StatusUnauthorized,
+			PathToResult:       "meta.error.errorCode",
+			ExpectedResult:     `"UNAUTHORIZED"`,
+		},
+		{
+			Name:               "reuse revoked the tokens issued after it",
+			Body:               func() string { return refreshBody(latest) },
+			ExpectedStatusCode: http.StatusUnauthorized,
+			PathToResult:       "meta.error.errorCode",
+			ExpectedResult:     `"UNAUTHORIZED"`,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		utils.MockRequest(ctx, http.MethodPost, c.Body())
+		mockServer.RefreshToken(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		if c.ExpectedResult != "" {
+			assert.JSONEq(t, c.ExpectedResult, gjson.Get(w.Body.String(), c.PathToResult).Raw, c.Name)
+		}
+		if w.Code == http.StatusOK {
+			refreshed := gjson.Get(w.Body.String(), "result.refreshToken").String()
+			assert.NotEmpty(t, refreshed, c.Name)
+			assert.NotEqual(t, latest, refreshed, c.Name)
+			latest = refreshed
+		}
+	}
+}
+
+func TestLogout(t *testing.T) {
+	issuer := newTokenIssuer()
+	session, err := issuer.NewSession("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockServer := server.S{TokenIssuer: issuer}
+	cases := []struct {
+		Name               string
+		Handler            func(server.S) gin.HandlerFunc
+		Body               string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "unknown refresh token",
+			Handler:            func(s server.S) gin.HandlerFunc { return s.Logout },
+			Body:               refreshBody("not-a-token"),
+			ExpectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			Name:               "happy path - logout revokes the session",
+			Handler:            func(s server.S) gin.HandlerFunc { return s.Logout },
+			Body:               refreshBody(session.RefreshToken),
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "revoked token can't be refreshed",
+			Handler:            func(s server.S) gin.HandlerFunc { return s.RefreshToken },
+			Body:               refreshBody(session.RefreshToken),
+			ExpectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		utils.MockRequest(ctx, http.MethodPost, c.Body)
+		c.Handler(mockServer)(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+	}
+}
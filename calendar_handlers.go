@@ -0,0 +1,274 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/ical"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/twilio"
+	"github.com/kickback-app/api/utils"
+)
+
+const calendarProdID = "-//Kickback//Kickback API//EN"
+
+// how often subscribed calendar apps are asked to poll the feed
+const calendarRefreshInterval = time.Hour
+
+var partStats = map[models.MemberStatus]string{
+	models.MemberStatusInvited:  ical.PartStatNeedsAction,
+	models.MemberStatusGoing:    ical.PartStatAccepted,
+	models.MemberStatusNotGoing: ical.PartStatDeclined,
+	models.MemberStatusMaybe:    ical.PartStatTentative,
+	memberStatusNoResponse:      ical.PartStatNeedsAction,
+}
+
+// calendarMember is what the calendar needs from an entry of EventMembersList
+type calendarMember struct {
+	UserID      string              `json:"userId"`
+	FirstName   string              `json:"first_name"`
+	LastName    string              `json:"last_name"`
+	PhoneNumber string              `json:"phone_number"`
+	Email       string              `json:"email"`
+	Status      models.MemberStatus `json:"status"`
+}
+
+// address identifies the member by email. Phone numbers are only shown to
+// the member themselves, to everyone else the many who only signed up with
+// one are identified by their user ID.
+func (m calendarMember) address(viewerID string) ical.Address {
+	uri := "urn:kickback:user:" + m.UserID
+	switch {
+	case m.Email != "":
+		uri = "mailto:" + m.Email
+	case m.PhoneNumber != "" && m.UserID == viewerID:
+		uri = "tel:" + m.PhoneNumber
+	}
+	return ical.Address{Name: strings.TrimSpace(m.FirstName + " " + m.LastName), URI: uri}
+}
+
+// calendarFeedResponse is where calendar apps subscribe to the feed, the
+// webcal:// url opens the subscription dialog on Apple devices
+type calendarFeedResponse struct {
+	URL       string `json:"url"`
+	WebcalURL string `json:"webcal_url"`
+}
+
+// calendarUID is the UID of the event in calendars, it has to be globally
+// unique so it's qualified with our domain
+func (s S) calendarUID(eventID string) string {
+	domain := "kickback"
+	if u, err := url.Parse(s.config.WebAppURL); err == nil && u.Host != "" {
+		domain = u.Host
+	}
+	return eventID + "@" + domain
+}
+
+// publicBaseURL is where clients reach the API, see config.HTTP.PublicBaseURL
+func (s S) publicBaseURL(c *gin.Context) string {
+	return strings.TrimSuffix(twilio.RequestURL(c.Request, s.config.HTTP.PublicBaseURL), c.Request.URL.RequestURI())
+}
+
+// calendarEvents describes the event as VEVENTs, one for the series and one
+// for every occurrence that was moved, changed or answered for separately.
+// Cancelled occurrences are excluded from the series. viewerID is the user the
+// calendar is for.
+func (s S) calendarEvents(c *gin.Context, event models.Event, series recurrence.Series, viewerID string) []ical.Event {
+	var organizer *ical.Address
+	attendees := []ical.Attendee{}
+	for _, m := range s.EventService.EventMembersList(c, s.withNoResponse(c, event)) {
+		var member calendarMember
+		b, err := json.Marshal(m)
+		if err == nil {
+			err = json.Unmarshal(b, &member)
+		}
+		if err != nil || member.UserID == "" {
+			logger.Warn(c, "skipping unreadable member of event %s in calendar: %v", event.ID, err)
+			continue
+		}
+		address := member.address(viewerID)
+		if member.UserID == event.CreatedBy {
+			organizer = &address
+		}
+		attendees = append(attendees, ical.Attendee{Address: address, UserID: member.UserID, PartStat: partStats[member.Status]})
+	}
+	stamp := time.Unix(event.UpdatedAt, 0)
+	if event.UpdatedAt == 0 {
+		stamp = time.Unix(event.CreatedAt, 0)
+	}
+	loc := series.Schedule.Location()
+	rrule := ""
+	if series.Schedule.RRule != "" {
+		// written out again so a floating UNTIL is in UTC as the TZID of the start requires
+		rrule = series.Schedule.Rule().String()
+	}
+	master := ical.Event{
+		UID:         s.calendarUID(event.ID),
+		Stamp:       stamp,
+		Start:       series.Schedule.Start.In(loc),
+		End:         series.Schedule.End.In(loc),
+		RRule:       rrule,
+		Summary:     event.Name,
+		Description: event.Description,
+		Status:      ical.StatusConfirmed,
+		Organizer:   organizer,
+		Attendees:   attendees,
+	}
+	changed := map[string]bool{}
+	for id := range series.Exceptions {
+		changed[id] = true
+	}
+	for id := range series.RSVPs {
+		changed[id] = true
+	}
+	ids := make([]string, 0, len(changed))
+	for id := range changed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	overrides := []ical.Event{}
+	for _, id := range ids {
+		o, ok := series.Find(id)
+		if !ok {
+			continue
+		}
+		if master.RRule == "" {
+			// a one-off event is its only occurrence
+			applyOccurrence(&master, o, loc)
+			continue
+		}
+		original, _ := time.Parse(recurrence.IDLayout, id)
+		if o.Cancelled {
+			master.ExDates = append(master.ExDates, original.In(loc))
+			continue
+		}
+		override := master
+		override.RRule = ""
+		override.RecurrenceID = original.In(loc)
+		applyOccurrence(&override, o, loc)
+		overrides = append(overrides, override)
+	}
+	return append([]ical.Event{master}, overrides...)
+}
+
+// applyOccurrence sets what was changed for a single occurrence on e
+func applyOccurrence(e *ical.Event, o recurrence.Occurrence, loc *time.Location) {
+	e.Start, e.End = o.Start.In(loc), o.End.In(loc)
+	if o.Cancelled {
+		e.Status = ical.StatusCancelled
+	}
+	if o.Overrides != nil {
+		if o.Overrides.Name != nil {
+			e.Summary = *o.Overrides.Name
+		}
+		if o.Overrides.Description != nil {
+			e.Description = *o.Overrides.Description
+		}
+	}
+	if len(o.RSVPs) > 0 {
+		attendees := make([]ical.Attendee, len(e.Attendees))
+		copy(attendees, e.Attendees)
+		for i, a := range attendees {
+			if status, ok := o.RSVPs[a.UserID]; ok {
+				attendees[i].PartStat = partStats[status]
+			}
+		}
+		e.Attendees = attendees
+	}
+}
+
+// ExportEventCalendar serves the event as an .ics file to add to a calendar
+func (s S) ExportEventCalendar(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("event", err))
+		return
+	}
+	series, err := s.Schedules.Get(eventID)
+	if err != nil {
+		handlers.EncodeError(c, scheduleNotFound(err))
+		return
+	}
+	cal := ical.Calendar{ProdID: calendarProdID, Events: s.calendarEvents(c, event, series, utils.CurrentUser(c).ID)}
+	logger.Info(c, "exported event %s as ics", eventID)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ics"`, eventID))
+	c.Data(http.StatusOK, ical.ContentType, cal.Encode())
+}
+
+// GetCalendarFeed serves every scheduled event of the user the feed token was
+// issued to. Calendar apps poll it, so changes to the events show up on the
+// next poll.
+func (s S) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	userID, err := s.CalendarFeeds.Lookup(token)
+	if err != nil {
+		logger.Warn(c, "rejecting calendar feed token: %v", err)
+		handlers.EncodeError(c, handlers.UnauthorizedError{Reason: "calendar feed token is invalid or has been revoked"})
+		return
+	}
+	userEvents, err := s.EventService.GetUsersEvents(c, userID, &models.GetFilters{})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	cal := ical.Calendar{ProdID: calendarProdID, Name: "Kickback", RefreshInterval: calendarRefreshInterval}
+	for _, event := range userEvents {
+		series, err := s.Schedules.Get(event.ID)
+		if err == recurrence.ErrNotFound {
+			// nothing to put on a calendar until it is scheduled
+			continue
+		}
+		if err != nil {
+			handlers.EncodeError(c, err)
+			return
+		}
+		cal.Events = append(cal.Events, s.calendarEvents(c, event, series, userID)...)
+	}
+	body := cal.Encode()
+	if notModified(c, string(body)) {
+		return
+	}
+	logger.Info(c, "served calendar feed of %s with %d events", userID, len(cal.Events))
+	c.Data(http.StatusOK, ical.ContentType, body)
+}
+
+// CreateCalendarFeed issues the url of the caller's calendar feed, the url
+// issued before stops working
+func (s S) CreateCalendarFeed(c *gin.Context) {
+	userID := utils.CurrentUser(c).ID
+	token, err := s.CalendarFeeds.Issue(userID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	feedURL := s.publicBaseURL(c) + "/calendar/" + token + ".ics"
+	_, hostAndPath, _ := strings.Cut(feedURL, "://")
+	logger.Info(c, "issued calendar feed for %s", userID)
+	handlers.EncodeSuccess(c, http.StatusCreated, calendarFeedResponse{URL: feedURL, WebcalURL: "webcal://" + hostAndPath})
+}
+
+// DeleteCalendarFeed revokes the url of the caller's calendar feed
+func (s S) DeleteCalendarFeed(c *gin.Context) {
+	userID := utils.CurrentUser(c).ID
+	if err := s.CalendarFeeds.Revoke(userID); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	logger.Info(c, "revoked calendar feed of %s", userID)
+	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+}
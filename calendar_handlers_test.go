@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestCalendarFeedHandlers(t *testing.T) {
+	mockServer := server.S{CalendarFeeds: auth.NewMemoryFeedStore()}
+	call := func(handler gin.HandlerFunc, method, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+		ctx.Params = []gin.Param{{Key: "token", Value: token}}
+		utils.MockRequest(ctx, method, "")
+		ctx.Request.Host = "api.kickbackapp.io"
+		handler(ctx)
+		return w
+	}
+
+	fmt.Printf("executing case %d: %v\n", 0, "happy path - issue a feed url")
+	w := call(mockServer.CreateCalendarFeed, http.MethodPost, "")
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	feedURL := gjson.Get(w.Body.String(), "result.url").String()
+	assert.True(t, strings.HasPrefix(feedURL, "https://api.kickbackapp.io/calendar/"), feedURL)
+	assert.True(t, strings.HasSuffix(feedURL, ".ics"), feedURL)
+	assert.Equal(t, "webcal://"+strings.TrimPrefix(feedURL, "https://"), gjson.Get(w.Body.String(), "result.webcal_url").String())
+	token := strings.TrimPrefix(feedURL, "https://api.kickbackapp.io/calendar/")
+
+	fmt.Printf("executing case %d: %v\n", 1, "unknown tokens are rejected")
+	w = call(mockServer.GetCalendarFeed, http.MethodGet, "not-a-token.ics")
+	assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+
+	fmt.Printf("executing case %d: %v\n", 2, "revoked tokens are rejected")
+	w = call(mockServer.DeleteCalendarFeed, http.MethodDelete, "")
+	assert.EqualValues(t, http.StatusNoContent, w.Code)
+	w = call(mockServer.GetCalendarFeed, http.MethodGet, token)
+	assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/kickback-app/api/logger"
 	"github.com/kickback-app/api/pkg/models"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/paging"
 	"github.com/kickback-app/api/utils"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -19,11 +20,17 @@ func (s S) GetChannels(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
+	query, err := paging.Parse(c.Request.URL.Query(), channelListing)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
 	channels, err := s.ChatService.GetChannels(c, kickbackID)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
+	page, nextCursor := query.Apply(normalizeEach(channels))
 	// get kickback info to add isHost data
 	kickback, err := s.EventService.GetEvent(c, kickbackID)
 	if err != nil {
@@ -32,7 +39,8 @@ func (s S) GetChannels(c *gin.Context) {
 		return
 	}
 	channelsWithInfo := []interface{}{}
-	for _, channel := range channels {
+	for _, i := range page {
+		channel := channels[i]
 		channelMap := utils.Normalize(channel)
 		membersWithInfo := []interface{}{}
 		totalMembers := channel.Members
@@ -53,7 +61,7 @@ func (s S) GetChannels(c *gin.Context) {
 		channelsWithInfo = append(channelsWithInfo, channelMap)
 	}
 	logger.Info(c, "retrieved %d chats for kickback %s", len(channelsWithInfo), kickbackID)
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"chats": channelsWithInfo})
+	handlers.EncodePage(c, http.StatusOK, gin.H{"chats": channelsWithInfo}, nextCursor)
 }
 
 func (s S) CreateChannel(c *gin.Context) {
@@ -65,7 +73,7 @@ func (s S) CreateChannel(c *gin.Context) {
 	}
 	var channel models.Channel
 	if err := json.NewDecoder(c.Request.Body).Decode(&channel); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	ID, err := s.ChatService.CreateChannel(c, kickbackID, &channel)
@@ -86,7 +94,7 @@ func (s S) UpdateChannel(c *gin.Context) {
 	}
 	var updates models.ChannelUpdates
 	if err := json.NewDecoder(c.Request.Body).Decode(&updates); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.ChatService.UpdateChannel(c, channelID, &updates)
@@ -97,6 +105,61 @@ func (s S) UpdateChannel(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
 
+// PatchChannel applies a merge patch to a channel, see applyMergePatch
+func (s S) PatchChannel(c *gin.Context) {
+	param := "kickbackId"
+	kickbackID := c.Param(param)
+	if kickbackID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	param = "channelId"
+	channelID := c.Param(param)
+	if channelID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	getChannel := func() (interface{}, error) {
+		channels, err := s.ChatService.GetChannels(c, kickbackID)
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range channels {
+			if channel.ID == channelID {
+				return channel, nil
+			}
+		}
+		return nil, handlers.NotFoundError{Resource: "channel"}
+	}
+	release, err := s.guardWrite(c, "channel", channelID, getChannel)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	current, err := getChannel()
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	var patched models.Channel
+	var updates models.ChannelUpdates
+	if err := applyMergePatch(c, channelPatchable, current, &patched, &updates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if err := validateChannel(patched); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err = s.ChatService.UpdateChannel(c, channelID, &updates)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+}
+
 func (s S) DeleteChannel(c *gin.Context) {
 	param := "channelId"
 	channelID := c.Param(param)
@@ -112,6 +175,11 @@ func (s S) DeleteChannel(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
 
+type channelMembersRequest struct {
+	MembersToAdd    []string `json:"members_to_add"`
+	MembersToRemove []string `json:"members_to_remove"`
+}
+
 func (s S) UpdateChannelMembers(c *gin.Context) {
 	param := "channelId"
 	channelID := c.Param(param)
@@ -119,12 +187,9 @@ func (s S) UpdateChannelMembers(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
-	var updates struct {
-		MembersToAdd    []string `json:"members_to_add"`
-		MembersToRemove []string `json:"members_to_remove"`
-	}
+	var updates channelMembersRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&updates); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.ChatService.UpdateChannelMembers(c, channelID, updates.MembersToAdd, updates.MembersToRemove)
@@ -150,7 +215,7 @@ func (s S) PinChatMessage(c *gin.Context) {
 	}
 	var message models.PinnedMessage
 	if err := json.NewDecoder(c.Request.Body).Decode(&message); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.ChatService.PinMessage(c, kickbackID, channelID, &message)
@@ -170,7 +235,7 @@ func (s S) UnpinChatMessage(c *gin.Context) {
 	}
 	var message models.PinnedMessage
 	if err := json.NewDecoder(c.Request.Body).Decode(&message); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.ChatService.UnpinMessage(c, channelID, &message)
@@ -188,16 +253,23 @@ func (s S) ListPinnedChatMessages(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
+	query, err := paging.Parse(c.Request.URL.Query(), pinnedMessageListing)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
 	msgs, err := s.ChatService.GetPinnedMessages(c, kickbackID, c.Query("channelId"))
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
+	page, nextCursor := query.Apply(normalizeEach(msgs))
 	messages := []interface{}{}
-	for _, msg := range msgs {
+	for _, i := range page {
+		msg := msgs[i]
 		message := utils.Normalize(msg)
 		message["sent_by"] = s.UserService.SummarizeUsers(c, []string{msg.SentBy}).Find(msg.SentBy)
 		messages = append(messages, message)
 	}
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"pinned_messages": messages})
+	handlers.EncodePage(c, http.StatusOK, gin.H{"pinned_messages": messages}, nextCursor)
 }
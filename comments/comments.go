@@ -0,0 +1,72 @@
+// Package comments remembers who wrote each media comment. The media service
+// doesn't answer with the author of a comment, without it only hosts could be
+// trusted to change comments.
+package comments
+
+import (
+	"errors"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+var ErrNotFound = errors.New("comment author not found")
+
+// Author is who wrote the comment with ID on a media item of a kickback
+type Author struct {
+	CommentID  string `json:"commentId"`
+	KickbackID string `json:"kickbackId"`
+	ItemID     string `json:"itemId"`
+	UserID     string `json:"userId"`
+}
+
+// Store persists the authors keyed by comment ID. Comments written before
+// authors were kept have none.
+type Store interface {
+	Get(commentID string) (Author, error)
+	Save(author Author) error
+	Delete(commentID string) error
+}
+
+// DBStore keeps the authors in the database, shared by every instance
+type DBStore struct {
+	docs docstore.Collection
+}
+
+func NewDBStore(docs docstore.Collection) *DBStore {
+	return &DBStore{docs: docs}
+}
+
+// NewMemoryStore keeps the authors in memory, for tests and single instance
+// deployments
+func NewMemoryStore() *DBStore {
+	return NewDBStore(docstore.NewMemory().Collection("comment_authors"))
+}
+
+func (d *DBStore) Get(commentID string) (Author, error) {
+	var author Author
+	_, err := d.docs.Get(commentID, &author)
+	if err == docstore.ErrNotFound {
+		return Author{}, ErrNotFound
+	}
+	return author, err
+}
+
+// Save records the author of a new comment, comments are never reassigned
+func (d *DBStore) Save(author Author) error {
+	_, err := d.docs.Put(author.CommentID, 0, author)
+	return err
+}
+
+func (d *DBStore) Delete(commentID string) error {
+	return docstore.Retry(func() error {
+		var author Author
+		version, err := d.docs.Get(commentID, &author)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.docs.Delete(commentID, version)
+	})
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/etag"
+	"github.com/kickback-app/api/server/handlers"
+)
+
+// lockRetryDelay is how long a write waits before checking again whether the
+// lock of its resource was released
+const lockRetryDelay = 20 * time.Millisecond
+
+// writeLocks serializes writes to the same resource across instances so an
+// If-Match can't pass while another write to the resource is in flight. A
+// lock is a document taken with a put conditional on its version and deleted
+// once the write is done, it expires after ttl should its instance die.
+type writeLocks struct {
+	docs docstore.Collection
+	ttl  time.Duration
+}
+
+type writeLock struct {
+	Until time.Time `json:"until"`
+}
+
+// lock blocks until key is free or c is done and returns the func releasing
+// it, S values that weren't built by New don't lock
+func (w *writeLocks) lock(c *gin.Context, key string) (func(), error) {
+	if w == nil {
+		return func() {}, nil
+	}
+	for {
+		var held writeLock
+		version, err := w.docs.Get(key, &held)
+		if err != nil && err != docstore.ErrNotFound {
+			return nil, err
+		}
+		now := time.Now()
+		if !held.Until.After(now) {
+			version, err = w.docs.Put(key, version, writeLock{Until: now.Add(w.ttl)})
+			if err == nil {
+				return func() {
+					// an expired lock may have been taken over, it isn't ours to delete then
+					if err := w.docs.Delete(key, version); err != nil && err != docstore.ErrConflict {
+						logger.Warn(c, "unable to release the write lock of %s: %v", key, err)
+					}
+				}, nil
+			}
+			if err != docstore.ErrConflict {
+				return nil, err
+			}
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-time.After(lockRetryDelay):
+		}
+	}
+}
+
+// notModified sets the ETag of response, the result the handler is about to
+// encode, and answers 304 when the client's If-None-Match says its copy is up
+// to date
+func notModified(c *gin.Context, response interface{}) bool {
+	tag := etag.Of(response)
+	if tag == "" {
+		return false
+	}
+	c.Header(etag.HeaderETag, tag)
+	if !etag.MatchWeak(c.GetHeader(etag.HeaderIfNoneMatch), tag) {
+		return false
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// guardWrite locks resource id for the rest of a PUT or DELETE and, when the
+// request has an If-Match, checks it against the ETag of current, which
+// renders the resource like its GET does. The returned func must be called
+// once the write is done.
+func (s S) guardWrite(c *gin.Context, resource, id string, current func() (interface{}, error)) (func(), error) {
+	unlock, err := s.locks.lock(c, resource+":"+id)
+	if err != nil {
+		return nil, err
+	}
+	ifMatch := c.GetHeader(etag.HeaderIfMatch)
+	if ifMatch == "" {
+		return unlock, nil
+	}
+	v, err := current()
+	if err != nil {
+		unlock()
+		return nil, handlers.NotFound(resource, err)
+	}
+	tag := etag.Of(v)
+	if !etag.Match(ifMatch, tag) {
+		unlock()
+		logger.Warn(c, "rejecting write to %s %s, If-Match %s is stale", resource, id, ifMatch)
+		c.Header(etag.HeaderETag, tag)
+		return nil, handlers.PreconditionFailedError{Resource: resource}
+	}
+	return unlock, nil
+}
@@ -0,0 +1,211 @@
+package config_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+env: dev
+http:
+  port: 9000
+  write_timeout: 10s
+auth:
+  issuer: from-file
+  audience: from-file
+  signing_token: file-secret
+`)
+	t.Setenv("JWT_ISSUER", "from-env")
+	t.Setenv("JWT_AUDIENCE", "from-env")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "20s")
+
+	cfg, err := config.Load([]string{"--config", path, "--auth.issuer", "from-flag"})
+	assert.NoError(t, err)
+	assert.Equal(t, "from-flag", cfg.Auth.Issuer)
+	assert.Equal(t, "from-env", cfg.Auth.Audience)
+	assert.Equal(t, 20*time.Second, cfg.HTTP.WriteTimeout)
+	assert.Equal(t, 9000, cfg.HTTP.Port)
+	assert.Equal(t, "file-secret", cfg.Auth.SigningToken.Value())
+	// untouched settings keep their defaults
+	assert.Equal(t, "users", cfg.Storage.Collections.Users)
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		Name  string
+		File  string
+		Env   map[string]string
+		Args  []string
+		Error string
+	}{
+		{
+			Name:  "unknown setting in file",
+			File:  "env: dev\nauth:\n  signing_token: x\n  issur: typo\n",
+			Error: "field issur not found",
+		},
+		{
+			Name:  "unparseable env var",
+			File:  "env: dev\nauth:\n  signing_token: x\n",
+			Env:   map[string]string{"PORT": "eighty"},
+			Error: "invalid PORT",
+		},
+		{
+			Name:  "unparseable flag",
+			File:  "env: dev\nauth:\n  signing_token: x\n",
+			Args:  []string{"--clients.timeout", "soon"},
+			Error: "invalid --clients.timeout",
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		t.Run(c.Name, func(t *testing.T) {
+			for k, v := range c.Env {
+				t.Setenv(k, v)
+			}
+			args := append([]string{"--config", writeFile(t, c.File)}, c.Args...)
+			_, err := config.Load(args)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), c.Error)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := config.Default()
+	valid.Auth.SigningKeys = "k1:secret-one"
+	valid.Auth.ActiveKeyID = "k1"
+	valid.Auth.InviteSigningSecret = "invite-secret"
+	valid.Twilio.AuthToken = "twilio-token"
+	valid.Storage.MongoURL = "mongodb://localhost/kickback"
+
+	cases := []struct {
+		Name     string
+		Modify   func(c *config.Config)
+		Problems []string
+	}{
+		{
+			Name:   "valid",
+			Modify: func(c *config.Config) {},
+		},
+		{
+			Name: "no way to verify tokens",
+			Modify: func(c *config.Config) {
+				c.Auth.SigningKeys = ""
+				c.Auth.ActiveKeyID = ""
+			},
+			Problems: []string{
+				"one of auth.signing_token, auth.signing_keys or auth.jwks_url is required to verify tokens",
+				"auth.active_key_id or auth.signing_token is required to issue tokens",
+			},
+		},
+		{
+			Name:     "unknown active key",
+			Modify:   func(c *config.Config) { c.Auth.ActiveKeyID = "k2" },
+			Problems: []string{`auth.active_key_id "k2" is not one of auth.signing_keys`},
+		},
+		{
+			Name:     "twilio account without a sender",
+			Modify:   func(c *config.Config) { c.Twilio.AccountSID = "AC1" },
+			Problems: []string{"twilio.from is required to send SMS with twilio.account_sid"},
+		},
+		{
+			Name: "missing secrets outside of dev",
+			Modify: func(c *config.Config) {
+				c.Auth.InviteSigningSecret = ""
+				c.Twilio.AuthToken = ""
+				c.Storage.MongoURL = ""
+			},
+			Problems: []string{
+				"auth.invite_signing_secret is required, invite links can't be created without it",
+				"twilio.auth_token is required, twilio webhooks can't be verified without it",
+				"storage.mongo_url is required, instances can't share waitlists, deadlines and locks without it",
+			},
+		},
+		{
+			Name: "missing secrets in dev",
+			Modify: func(c *config.Config) {
+				c.Env = "dev"
+				c.Auth.InviteSigningSecret = ""
+				c.Twilio.AuthToken = ""
+				c.Storage.MongoURL = ""
+			},
+		},
+		{
+			Name:   "trusted proxies",
+			Modify: func(c *config.Config) { c.HTTP.TrustedProxies = "10.0.0.0/8, 127.0.0.1" },
+		},
+		{
+			Name:     "bad trusted proxy",
+			Modify:   func(c *config.Config) { c.HTTP.TrustedProxies = "10.0.0.0/8,heroku" },
+			Problems: []string{`http.trusted_proxies must be IPs or CIDRs, got "heroku"`},
+		},
+		{
+			Name:   "metrics disabled",
+			Modify: func(c *config.Config) { c.HTTP.MetricsPort = 0 },
+		},
+		{
+			Name:     "metrics on the public port",
+			Modify:   func(c *config.Config) { c.HTTP.MetricsPort = c.HTTP.Port },
+			Problems: []string{"http.metrics_port must differ from http.port, metrics aren't public"},
+		},
+		{
+			Name: "bad values",
+			Modify: func(c *config.Config) {
+				c.HTTP.Port = 0
+				c.Clients.Timeout = 0
+				c.HTTP.PublicBaseURL = "api.kickbackapp.io"
+				c.Telemetry.TracesExporter = "jaeger"
+			},
+			Problems: []string{
+				"http.port must be between 1 and 65535, got 0",
+				"clients.timeout must be greater than 0",
+				`http.public_base_url must be an absolute url, got "api.kickbackapp.io"`,
+				`telemetry.traces_exporter must be one of none, stdout, otlp, got "jaeger"`,
+			},
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		cfg := valid
+		c.Modify(&cfg)
+		err := cfg.Validate()
+		if len(c.Problems) == 0 {
+			assert.NoError(t, err)
+			continue
+		}
+		if assert.IsType(t, config.ValidationError{}, err) {
+			assert.Equal(t, c.Problems, err.(config.ValidationError).Problems)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.SigningKeys = "k1:super-secret"
+	cfg.Twilio.AuthToken = "twilio-token"
+	cfg.Auth.Issuer = "kickback"
+
+	dump := cfg.Redacted()
+	assert.False(t, strings.Contains(dump, "super-secret"))
+	assert.False(t, strings.Contains(dump, "twilio-token"))
+	assert.Contains(t, dump, "signing_keys: '[redacted]'")
+	assert.Contains(t, dump, "issuer: kickback")
+	assert.Equal(t, "[redacted]", fmt.Sprint(cfg.Twilio.AuthToken))
+}
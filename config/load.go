@@ -0,0 +1,143 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the effective config from the defaults, the config file, the
+// environment and args (usually os.Args[1:]), then validates it
+func Load(args []string) (Config, error) {
+	cfg := Default()
+	fs := flag.NewFlagSet("kickback-api", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+	// flags are applied last so they're collected here while parsing
+	type flagValue struct{ path, raw string }
+	flagValues := []flagValue{}
+	walk(reflect.ValueOf(&cfg).Elem(), "", func(path string, _ reflect.Value, _ reflect.StructField) {
+		fs.Func(path, "overrides "+path, func(raw string) error {
+			flagValues = append(flagValues, flagValue{path, raw})
+			return nil
+		})
+	})
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configFile != "" {
+		f, err := os.Open(*configFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("unable to read config file: %v", err)
+		}
+		defer f.Close()
+		dec := yaml.NewDecoder(f)
+		// a typo in the file should fail loudly rather than silently use the default
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+			return Config{}, fmt.Errorf("unable to parse config file %s: %v", *configFile, err)
+		}
+	}
+
+	var err error
+	walk(reflect.ValueOf(&cfg).Elem(), "", func(path string, field reflect.Value, sf reflect.StructField) {
+		name := sf.Tag.Get("env")
+		if name == "" || err != nil {
+			return
+		}
+		if raw, ok := os.LookupEnv(name); ok {
+			if setErr := set(field, raw); setErr != nil {
+				err = fmt.Errorf("invalid %s: %v", name, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	for _, fv := range flagValues {
+		walk(reflect.ValueOf(&cfg).Elem(), "", func(path string, field reflect.Value, _ reflect.StructField) {
+			if path != fv.path || err != nil {
+				return
+			}
+			if setErr := set(field, fv.raw); setErr != nil {
+				err = fmt.Errorf("invalid --%s: %v", path, setErr)
+			}
+		})
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Redacted renders the effective config as YAML with secrets hidden, for
+// logging at startup
+func (c Config) Redacted() string {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("unable to render config: %v", err)
+	}
+	return string(b)
+}
+
+// walk calls fn with every leaf setting and its dotted yaml path
+func walk(v reflect.Value, prefix string, fn func(path string, field reflect.Value, sf reflect.StructField)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			walk(field, path, fn)
+			continue
+		}
+		fn(path, field, sf)
+	}
+}
+
+func set(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/utils"
+)
+
+var tracesExporters = []string{"none", "stdout", "otlp"}
+
+// ValidationError lists every problem with the config so they can all be
+// fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (c Config) Validate() error {
+	var problems []string
+	problem := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	if c.HTTP.Port < 1 || c.HTTP.Port > 65535 {
+		problem("http.port must be between 1 and 65535, got %d", c.HTTP.Port)
+	}
+	if c.HTTP.MetricsPort < 0 || c.HTTP.MetricsPort > 65535 {
+		problem("http.metrics_port must be between 0 and 65535, got %d", c.HTTP.MetricsPort)
+	}
+	if c.HTTP.MetricsPort == c.HTTP.Port {
+		problem("http.metrics_port must differ from http.port, metrics aren't public")
+	}
+	if c.HTTP.MaxBodyBytes < 1 {
+		problem("http.max_body_bytes must be greater than 0")
+	}
+	for _, proxy := range c.HTTP.TrustedProxyList() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problem("http.trusted_proxies must be IPs or CIDRs, got %q", proxy)
+		}
+	}
+	positive := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"http.read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"http.idempotency_key_ttl", c.HTTP.IdempotencyKeyTTL},
+		{"auth.access_token_ttl", c.Auth.AccessTokenTTL},
+		{"auth.refresh_token_ttl", c.Auth.RefreshTokenTTL},
+		{"auth.invite_ttl", c.Auth.InviteTTL},
+		{"storage.user_cache_ttl", c.Storage.UserCacheTTL},
+		{"storage.user_cache_cleanup", c.Storage.UserCacheCleanup},
+		{"clients.timeout", c.Clients.Timeout},
+		{"twilio.delivery_retention", c.Twilio.DeliveryRetention},
+	}
+	for _, d := range positive {
+		if d.value <= 0 {
+			problem("%s must be greater than 0", d.name)
+		}
+	}
+	if c.Reminders.Interval < 0 {
+		problem("reminders.interval must be 0 or greater")
+	}
+	absoluteURLs := []struct {
+		name  string
+		value string
+	}{
+		{"web_app_url", c.WebAppURL},
+		{"http.public_base_url", c.HTTP.PublicBaseURL},
+	}
+	if c.WebAppURL == "" {
+		problem("web_app_url is required to build invite links")
+	}
+	for _, u := range absoluteURLs {
+		if u.value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(u.value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			problem("%s must be an absolute url, got %q", u.name, u.value)
+		}
+	}
+
+	if c.Auth.SigningToken == "" && c.Auth.SigningKeys == "" && c.Auth.JWKSURL == "" {
+		problem("one of auth.signing_token, auth.signing_keys or auth.jwks_url is required to verify tokens")
+	}
+	keys := auth.NewKeySet()
+	if err := keys.AddHMACKeys(c.Auth.SigningKeys.Value()); err != nil {
+		// the error may quote the secret
+		problem("auth.signing_keys must be comma separated kid:secret pairs")
+	} else if c.Auth.ActiveKeyID != "" {
+		if _, err := keys.Lookup(c.Auth.ActiveKeyID); err != nil {
+			problem("auth.active_key_id %q is not one of auth.signing_keys", c.Auth.ActiveKeyID)
+		}
+	} else if c.Auth.SigningToken == "" {
+		problem("auth.active_key_id or auth.signing_token is required to issue tokens")
+	}
+
+	if c.Twilio.AccountSID != "" && c.Twilio.From == "" {
+		problem("twilio.from is required to send SMS with twilio.account_sid")
+	}
+
+	if !c.IsDev() {
+		if c.Auth.InviteSigningSecret == "" {
+			problem("auth.invite_signing_secret is required, invite links can't be created without it")
+		}
+		if c.Twilio.AuthToken == "" {
+			problem("twilio.auth_token is required, twilio webhooks can't be verified without it")
+		}
+		if c.Storage.MongoURL == "" {
+			problem("storage.mongo_url is required, instances can't share waitlists, deadlines and locks without it")
+		}
+	}
+
+	if !utils.ContainsString(tracesExporters, c.Telemetry.TracesExporter) {
+		problem("telemetry.traces_exporter must be one of %s, got %q", strings.Join(tracesExporters, ", "), c.Telemetry.TracesExporter)
+	}
+	if c.Telemetry.ServiceName == "" {
+		problem("telemetry.service_name is required")
+	}
+
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
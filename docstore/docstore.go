@@ -0,0 +1,78 @@
+// Package docstore keeps the documents the API owns, e.g. waitlists and RSVP
+// deadlines, in the database next to the collections of the services.
+//
+// Every document has a version that each write moves forward. Writes name the
+// version they read and only apply while it is still current, so instances
+// sharing the database can't overwrite each other's changes: the loser gets
+// ErrConflict and reads the document again, see Retry.
+//
+// Documents that implement Expiring are gone once they expire, the databases
+// delete them on their own so collections of e.g. refresh tokens don't grow
+// without bound.
+package docstore
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("document not found")
+	ErrConflict = errors.New("document was changed by another write")
+)
+
+// maxAttempts bounds how often Retry reads and writes a document again
+const maxAttempts = 5
+
+// Collection holds documents keyed by ID. Documents are stored by their JSON
+// encoding, filters name their JSON fields.
+type Collection interface {
+	// Get decodes the document into out and returns its version. A missing
+	// document is ErrNotFound with version 0.
+	Get(id string, out interface{}) (int64, error)
+	// Put stores doc if the stored version is still version, 0 when the
+	// document must not exist yet, and returns the new version
+	Put(id string, version int64, doc interface{}) (int64, error)
+	// Delete removes the document if the stored version is still version,
+	// deleting a missing document with version 0 is a no-op
+	Delete(id string, version int64) error
+	// Find decodes the documents whose top level fields equal those of
+	// filter into out, a pointer to a slice, ordered by ID
+	Find(filter map[string]interface{}, out interface{}) error
+}
+
+// Expiring is implemented by documents that are only kept until Expires, the
+// zero time keeps them for good
+type Expiring interface {
+	Expires() time.Time
+}
+
+// expiry is when doc expires, the zero time when it doesn't
+func expiry(doc interface{}) time.Time {
+	if e, ok := doc.(Expiring); ok {
+		return e.Expires()
+	}
+	return time.Time{}
+}
+
+// expired reports whether a document expiring at expires is gone at now
+func expired(expires, now time.Time) bool {
+	return !expires.IsZero() && !now.Before(expires)
+}
+
+// DB is implemented by database clients that can hold the API's documents
+type DB interface {
+	Collection(name string) Collection
+}
+
+// Retry runs fn, which reads, changes and writes back documents, again for as
+// long as the write conflicts with another one
+func Retry(fn func() error) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if err = fn(); err != ErrConflict {
+			return err
+		}
+	}
+	return err
+}
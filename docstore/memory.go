@@ -0,0 +1,141 @@
+package docstore
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a DB for tests and single instance deployments, like the database
+// it only hands out copies of what it stores
+type Memory struct {
+	mu          sync.Mutex
+	collections map[string]*memoryCollection
+}
+
+func NewMemory() *Memory {
+	return &Memory{collections: map[string]*memoryCollection{}}
+}
+
+func (m *Memory) Collection(name string) Collection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	coll, ok := m.collections[name]
+	if !ok {
+		coll = &memoryCollection{docs: map[string]memoryDoc{}}
+		m.collections[name] = coll
+	}
+	return coll
+}
+
+type memoryCollection struct {
+	mu   sync.Mutex
+	docs map[string]memoryDoc
+}
+
+type memoryDoc struct {
+	version int64
+	body    []byte
+	expires time.Time
+}
+
+// live returns the document stored at id, deleting it when it expired. m.mu
+// must be held.
+func (m *memoryCollection) live(id string, now time.Time) (memoryDoc, bool) {
+	doc, ok := m.docs[id]
+	if ok && expired(doc.expires, now) {
+		delete(m.docs, id)
+		return memoryDoc{}, false
+	}
+	return doc, ok
+}
+
+func (m *memoryCollection) Get(id string, out interface{}) (int64, error) {
+	m.mu.Lock()
+	doc, ok := m.live(id, time.Now())
+	m.mu.Unlock()
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return doc.version, json.Unmarshal(doc.body, out)
+}
+
+func (m *memoryCollection) Put(id string, version int64, doc interface{}) (int64, error) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	// expired documents are dropped as they are written over, like the
+	// database does in the background
+	for other := range m.docs {
+		m.live(other, now)
+	}
+	if m.docs[id].version != version {
+		return 0, ErrConflict
+	}
+	m.docs[id] = memoryDoc{version: version + 1, body: body, expires: expiry(doc)}
+	return version + 1, nil
+}
+
+func (m *memoryCollection) Delete(id string, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if doc, _ := m.live(id, time.Now()); doc.version != version {
+		return ErrConflict
+	}
+	delete(m.docs, id)
+	return nil
+}
+
+func (m *memoryCollection) Find(filter map[string]interface{}, out interface{}) error {
+	want := map[string]interface{}{}
+	if err := roundTrip(filter, &want); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	now := time.Now()
+	ids := make([]string, 0, len(m.docs))
+	for id := range m.docs {
+		if _, ok := m.live(id, now); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	matched := []json.RawMessage{}
+	for _, id := range ids {
+		body := m.docs[id].body
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		if matches(fields, want) {
+			matched = append(matched, body)
+		}
+	}
+	m.mu.Unlock()
+	return roundTrip(matched, out)
+}
+
+func matches(fields, want map[string]interface{}) bool {
+	for k, v := range want {
+		if !reflect.DeepEqual(fields[k], v) {
+			return false
+		}
+	}
+	return true
+}
+
+// roundTrip decodes the JSON encoding of v into out
+func roundTrip(v, out interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,120 @@
+package docstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type doc struct {
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"`
+}
+
+func TestMemoryVersions(t *testing.T) {
+	testVersions(t, docstore.NewMemory().Collection("docs"))
+}
+
+func TestMemoryFind(t *testing.T) {
+	testFind(t, docstore.NewMemory().Collection("docs"))
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	testExpiry(t, docstore.NewMemory().Collection("docs"))
+}
+
+type expiringDoc struct {
+	Name  string    `json:"name"`
+	Until time.Time `json:"until"`
+}
+
+func (d expiringDoc) Expires() time.Time {
+	return d.Until
+}
+
+// testExpiry checks that expired documents are gone from coll
+func testExpiry(t *testing.T, coll docstore.Collection) {
+	_, err := coll.Put("gone", 0, expiringDoc{Name: "gone", Until: time.Now().Add(-time.Second)})
+	assert.NoError(t, err)
+	_, err = coll.Put("kept", 0, expiringDoc{Name: "kept", Until: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+
+	var got expiringDoc
+	_, err = coll.Get("gone", &got)
+	assert.Equal(t, docstore.ErrNotFound, err)
+	var all []expiringDoc
+	assert.NoError(t, coll.Find(nil, &all))
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "kept", all[0].Name)
+	}
+	_, err = coll.Put("gone", 0, expiringDoc{Name: "again", Until: time.Now().Add(time.Hour)})
+	assert.NoError(t, err, "an expired document can be created again")
+}
+
+// testVersions checks that writes to coll only apply to the version they read
+func testVersions(t *testing.T, coll docstore.Collection) {
+	var got doc
+	version, err := coll.Get("a", &got)
+	assert.Equal(t, docstore.ErrNotFound, err)
+	assert.EqualValues(t, 0, version)
+
+	version, err = coll.Put("a", 0, doc{Name: "first"})
+	assert.NoError(t, err)
+	_, err = coll.Put("a", 0, doc{Name: "created twice"})
+	assert.Equal(t, docstore.ErrConflict, err)
+
+	read, err := coll.Get("a", &got)
+	assert.NoError(t, err)
+	assert.Equal(t, version, read)
+	assert.Equal(t, "first", got.Name)
+
+	// a write based on what was read before the latest one is rejected
+	_, err = coll.Put("a", read, doc{Name: "second"})
+	assert.NoError(t, err)
+	_, err = coll.Put("a", read, doc{Name: "stale"})
+	assert.Equal(t, docstore.ErrConflict, err)
+	assert.Equal(t, docstore.ErrConflict, coll.Delete("a", read))
+
+	read, _ = coll.Get("a", &got)
+	assert.Equal(t, "second", got.Name)
+	assert.NoError(t, coll.Delete("a", read))
+	assert.NoError(t, coll.Delete("a", 0), "deleting a missing document is a no-op")
+}
+
+// testFind checks that coll finds documents by their fields, ordered by ID
+func testFind(t *testing.T, coll docstore.Collection) {
+	coll.Put("c", 0, doc{Name: "c"})
+	coll.Put("a", 0, doc{Name: "a"})
+	coll.Put("b", 0, doc{Name: "b", Closed: true})
+
+	var open []doc
+	assert.NoError(t, coll.Find(map[string]interface{}{"closed": false}, &open))
+	assert.Equal(t, []doc{{Name: "a"}, {Name: "c"}}, open)
+
+	var all []doc
+	assert.NoError(t, coll.Find(nil, &all))
+	assert.Len(t, all, 3)
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := docstore.Retry(func() error {
+		calls++
+		if calls < 3 {
+			return docstore.ErrConflict
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = docstore.Retry(func() error {
+		calls++
+		return docstore.ErrConflict
+	})
+	assert.Equal(t, docstore.ErrConflict, err)
+	assert.Equal(t, 5, calls, "retries are bounded")
+}
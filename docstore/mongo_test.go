@@ -0,0 +1,37 @@
+package docstore_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kickback-app/api/server/docstore"
+)
+
+// mongoCollection connects to the database in DOCSTORE_TEST_MONGO_URL and
+// returns a collection of its own, the tests are skipped without one
+func mongoCollection(t *testing.T) docstore.Collection {
+	url := os.Getenv("DOCSTORE_TEST_MONGO_URL")
+	if url == "" {
+		t.Skip("DOCSTORE_TEST_MONGO_URL isn't set")
+	}
+	db, err := docstore.DialMongo(url, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	return db.Collection("docstore_test_" + uuid.NewString())
+}
+
+func TestMongoVersions(t *testing.T) {
+	testVersions(t, mongoCollection(t))
+}
+
+func TestMongoFind(t *testing.T) {
+	testFind(t, mongoCollection(t))
+}
+
+func TestMongoExpiry(t *testing.T) {
+	testExpiry(t, mongoCollection(t))
+}
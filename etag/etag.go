@@ -0,0 +1,62 @@
+// Package etag versions API resources by their content so clients can make
+// conditional requests without the stored documents carrying a version field.
+package etag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// Of returns the strong ETag of v's JSON encoding, empty when v can't be
+// encoded
+func Of(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Match implements If-Match: it reports whether tag is one of the strong
+// ETags listed in header, or header is "*"
+func Match(header, tag string) bool {
+	return match(header, tag, false)
+}
+
+// MatchWeak implements If-None-Match, which ignores the W/ prefix of weak
+// ETags
+func MatchWeak(header, tag string) bool {
+	return match(header, tag, true)
+}
+
+func match(header, tag string, weak bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || tag == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
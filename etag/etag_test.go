@@ -0,0 +1,37 @@
+package etag_test
+
+import (
+	"testing"
+
+	"github.com/kickback-app/api/server/etag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	task := map[string]interface{}{"_id": "TSK_mock", "name": "chips"}
+	tag := etag.Of(task)
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, tag)
+	assert.Equal(t, tag, etag.Of(map[string]interface{}{"name": "chips", "_id": "TSK_mock"}))
+	assert.NotEqual(t, tag, etag.Of(map[string]interface{}{"_id": "TSK_mock", "name": "dip"}))
+	assert.Empty(t, etag.Of(func() {}))
+}
+
+func TestMatch(t *testing.T) {
+	tag := `"abc"`
+	cases := []struct {
+		Header string
+		Strong bool
+		Weak   bool
+	}{
+		{Header: "", Strong: false, Weak: false},
+		{Header: "*", Strong: true, Weak: true},
+		{Header: `"abc"`, Strong: true, Weak: true},
+		{Header: `"xyz", "abc"`, Strong: true, Weak: true},
+		{Header: `W/"abc"`, Strong: false, Weak: true},
+		{Header: `"xyz"`, Strong: false, Weak: false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Strong, etag.Match(c.Header, tag), "If-Match: %s", c.Header)
+		assert.Equal(t, c.Weak, etag.MatchWeak(c.Header, tag), "If-None-Match: %s", c.Header)
+	}
+}
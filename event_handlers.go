@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,8 +13,18 @@ import (
 	"github.com/kickback-app/api/internal/services"
 	"github.com/kickback-app/api/logger"
 	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/etag"
+	"github.com/kickback-app/api/server/guests"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/metrics"
+	"github.com/kickback-app/api/server/paging"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/telemetry"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/kickback-app/api/server/waitlist"
 	"github.com/kickback-app/api/utils"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -28,13 +39,47 @@ func (s S) GetUsersEvents(c *gin.Context) {
 	if a, err := strconv.Atoi(after); err == nil {
 		filters.After = a
 	}
+	query, err := paging.Parse(c.Request.URL.Query(), eventListing)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	from, to, expand, err := occurrenceWindow(c)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
 	userEvents, err := s.EventService.GetUsersEvents(c, userID, &filters)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
+	// with a window, recurring events are listed with their occurrences in it
+	// and left out when they have none
+	occurrences := map[string][]recurrence.Occurrence{}
+	if expand {
+		inWindow := userEvents[:0]
+		for _, event := range userEvents {
+			series, err := s.Schedules.Get(event.ID)
+			if err == recurrence.ErrNotFound {
+				inWindow = append(inWindow, event)
+				continue
+			}
+			if err != nil {
+				handlers.EncodeError(c, err)
+				return
+			}
+			if o := series.Between(from, to); len(o) > 0 {
+				occurrences[event.ID] = o
+				inWindow = append(inWindow, event)
+			}
+		}
+		userEvents = inWindow
+	}
+	page, nextCursor := query.Apply(normalizeEach(userEvents))
 	userEventsWithInfo := []models.M{}
-	for _, event := range userEvents {
+	for _, i := range page {
+		event := userEvents[i]
 		mediaID := event.BackgroundImg
 		var backgroundImgInfo models.Media
 		var err error
@@ -44,12 +89,16 @@ func (s S) GetUsersEvents(c *gin.Context) {
 				logger.Warn(c, "unable to get additional background image for %v-%v: %v", event.ID, mediaID, err)
 			}
 		}
-		userEventsWithInfo = append(userEventsWithInfo, event.ToMap(models.M{
+		info := models.M{
 			"background_img_info": backgroundImgInfo,
-		}))
+		}
+		if o, ok := occurrences[event.ID]; ok {
+			info["occurrences"] = o
+		}
+		userEventsWithInfo = append(userEventsWithInfo, event.ToMap(info))
 	}
 	logger.Info(c, "retrieved %d events %s", len(userEvents), userID)
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"events": userEventsWithInfo})
+	handlers.EncodePage(c, http.StatusOK, gin.H{"events": userEventsWithInfo}, nextCursor)
 }
 
 func (s S) GetEvent(c *gin.Context) {
@@ -61,10 +110,21 @@ func (s S) GetEvent(c *gin.Context) {
 	}
 	event, err := s.EventService.GetEvent(c, eventID)
 	if err != nil {
-		handlers.EncodeError(c, err)
+		handlers.EncodeError(c, handlers.NotFound("event", err))
+		return
+	}
+	resolvedEvent := s.eventResponse(c, event)
+	if notModified(c, resolvedEvent) {
 		return
 	}
-	resolvedEvent := s.EventService.ResolveLinks(c, event)
+	logger.Info(c, "retrieved event %s", eventID)
+	handlers.EncodeSuccess(c, http.StatusOK, resolvedEvent)
+}
+
+// eventResponse is the event as GetEvent returns it, with its links resolved
+// and the details of its background image
+func (s S) eventResponse(c *gin.Context, event models.Event) models.M {
+	resolvedEvent := s.EventService.ResolveLinks(c, s.withNoResponse(c, event))
 	mediaID := event.BackgroundImg
 	if strings.HasPrefix(mediaID, "MDA_") {
 		backgroundImgInfo, err := s.MediaService.GetItem(c, event.ID, mediaID)
@@ -73,14 +133,48 @@ func (s S) GetEvent(c *gin.Context) {
 		}
 		resolvedEvent["background_img_info"] = backgroundImgInfo
 	}
-	logger.Info(c, "retrieved event %s", eventID)
-	handlers.EncodeSuccess(c, http.StatusOK, resolvedEvent)
+	return resolvedEvent
+}
+
+// encodeEvent answers a write with the event as GetEvent renders it, tagged
+// with the ETag the If-Match of the next write has to carry
+func (s S) encodeEvent(c *gin.Context, event models.Event) {
+	response := s.eventResponse(c, event)
+	c.Header(etag.HeaderETag, etag.Of(response))
+	handlers.EncodeSuccess(c, http.StatusOK, response)
+}
+
+// currentEvent renders the event for the If-Match of a write, see guardWrite
+func (s S) currentEvent(c *gin.Context, eventID string) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		event, err := s.EventService.GetEvent(c, eventID)
+		if err != nil {
+			return nil, err
+		}
+		return s.eventResponse(c, event), nil
+	}
 }
 
 func (s S) CreateEvent(c *gin.Context) {
-	var event models.Event
-	if err := json.NewDecoder(c.Request.Body).Decode(&event); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+	var body createEventRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	event := body.Event
+	if err := validateEvent(event); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	var v validate.Validator
+	if body.Schedule != nil {
+		body.Schedule.Check(&v, "schedule.")
+	}
+	if body.Capacity != nil {
+		v.Min("capacity", float64(*body.Capacity), 1)
+	}
+	if err := v.Err(); err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
 	createdEvent, err := s.EventService.CreateEvent(c, &event)
@@ -88,19 +182,77 @@ func (s S) CreateEvent(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
-	err = s.UserService.AddEventToUser(c, utils.CurrentUser(c).ID, createdEvent.ID)
+	resolvedEvent, err := s.setUpCreatedEvent(c, createdEvent, body)
 	if err != nil {
+		s.discardEvent(c, createdEvent.ID)
 		handlers.EncodeError(c, err)
 		return
 	}
+	metrics.EventsCreated.Inc()
+	logger.Info(c, "created new event with id: %s", createdEvent.ID)
+	handlers.EncodeSuccess(c, http.StatusOK, resolvedEvent)
+}
+
+// setUpCreatedEvent stores the schedule and capacity of a new event before
+// setting it up for the creator and returns the event as CreateEvent answers
+// it
+func (s S) setUpCreatedEvent(c *gin.Context, createdEvent models.Event, body createEventRequest) (models.M, error) {
+	if body.Schedule != nil {
+		if err := s.Schedules.Save(recurrence.NewSeries(createdEvent.ID, *body.Schedule)); err != nil {
+			logger.Error(c, "unable to save the schedule of the event: %v", err)
+			return nil, err
+		}
+	}
+	if body.Capacity != nil {
+		if err := s.Waitlists.Save(newWaitlist(createdEvent, guests.New(createdEvent.ID), *body.Capacity)); err != nil {
+			logger.Error(c, "unable to save the capacity of the event: %v", err)
+			return nil, err
+		}
+	}
+	if err := s.setUpEvent(c, createdEvent, []string{utils.CurrentUser(c).ID}); err != nil {
+		return nil, err
+	}
+	resolvedEvent := s.EventService.ResolveLinks(c, createdEvent)
+	if body.Schedule != nil {
+		resolvedEvent["schedule"] = body.Schedule
+	}
+	if body.Capacity != nil {
+		resolvedEvent["capacity"] = *body.Capacity
+	}
+	return resolvedEvent, nil
+}
+
+// discardEvent removes an event whose creation failed part way along with the
+// documents stored for it. The request failed already, so errors are only
+// logged.
+func (s S) discardEvent(c *gin.Context, eventID string) {
+	if err := s.EventService.DeleteEvent(c, eventID); err != nil {
+		logger.Error(c, "unable to discard event %s: %v", eventID, err)
+	}
+	for _, remove := range []func(string) error{s.Schedules.Delete, s.Waitlists.Delete, s.Guests.Delete, s.Deadlines.Delete} {
+		if err := remove(eventID); err != nil {
+			logger.Error(c, "unable to discard the documents of event %s: %v", eventID, err)
+		}
+	}
+	logger.Warn(c, "discarded event %s", eventID)
+}
+
+// setUpEvent adds a newly created event to the users and creates its note and
+// main chat channel
+func (s S) setUpEvent(c *gin.Context, event models.Event, userIDs []string) error {
+	for _, userID := range userIDs {
+		if err := s.UserService.AddEventToUser(c, userID, event.ID); err != nil {
+			return err
+		}
+	}
 	noteID, err := s.NoteService.CreateNote(c, &models.Note{ParentID: event.ID})
 	if err != nil {
 		logger.Error(c, "unable to create a new note for the event: %v", err)
-		handlers.EncodeError(c, err)
+		return err
 	}
 	logger.Info(c, "created new note '%s'", noteID)
 	memberIDs := []string{}
-	for _, member := range createdEvent.Members {
+	for _, member := range event.Members {
 		memberIDs = append(memberIDs, member.UserID)
 	}
 	mainChannelID, err := s.ChatService.CreateChannel(c, event.ID, &models.Channel{
@@ -110,25 +262,85 @@ func (s S) CreateEvent(c *gin.Context) {
 	})
 	if err != nil {
 		logger.Error(c, "unable to create main channel for the event: %v", err)
-		handlers.EncodeError(c, err)
+		return err
 	}
 	logger.Info(c, "created main event channel with id: %s", mainChannelID)
-	logger.Info(c, "created new event with id: %s", createdEvent.ID)
-	handlers.EncodeSuccess(c, http.StatusOK, s.EventService.ResolveLinks(c, createdEvent))
+	return nil
 }
 
 func (s S) UpdateEvent(c *gin.Context) {
-	eventID := c.Param("eventId")
+	param := "eventId"
+	eventID := c.Param(param)
 	if eventID == "" {
-		handlers.EncodeError(c, handlers.MissingBodyFieldError{Field: "eventId"})
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
 	var eventUpdates models.EventUpdates
 	if err := json.NewDecoder(c.Request.Body).Decode(&eventUpdates); err != nil {
 		logger.Error(c, err.Error())
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if err := validateEventUpdates(eventUpdates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	scope, occurrenceID, err := editScope(c)
+	if err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
+	release, err := s.guardWrite(c, "event", eventID, s.currentEvent(c, eventID))
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	switch scope {
+	case scopeThis:
+		s.updateOccurrenceDetails(c, eventID, occurrenceID, eventUpdates)
+	case scopeFollowing:
+		s.updateFollowing(c, eventID, occurrenceID, eventUpdates)
+	default:
+		s.saveEventUpdates(c, eventID, eventUpdates)
+	}
+}
+
+// PatchEvent applies a merge patch to the event, see applyMergePatch
+func (s S) PatchEvent(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	release, err := s.guardWrite(c, "event", eventID, s.currentEvent(c, eventID))
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	current, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("event", err))
+		return
+	}
+	var patched models.Event
+	var eventUpdates models.EventUpdates
+	if err := applyMergePatch(c, eventPatchable, current, &patched, &eventUpdates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if err := validateEvent(patched); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	s.saveEventUpdates(c, eventID, eventUpdates)
+}
+
+// saveEventUpdates persists validated updates, notifies the other members and
+// responds with the updated event
+func (s S) saveEventUpdates(c *gin.Context, eventID string, eventUpdates models.EventUpdates) {
 	err := s.EventService.UpdateEvent(c, eventID, &eventUpdates)
 	if err != nil {
 		handlers.EncodeError(c, err)
@@ -152,7 +364,7 @@ func (s S) UpdateEvent(c *gin.Context) {
 					usersToNotify = append(usersToNotify, userID)
 				}
 			}
-			s.doSendNotification(c, models.Notification{
+			s.sendInBackground(c, models.Notification{
 				Type:     models.EventUpdated,
 				Channels: []string{"push"},
 				To:       usersToNotify,
@@ -165,7 +377,7 @@ func (s S) UpdateEvent(c *gin.Context) {
 		}
 	}
 	logger.Info(c, "successfully updated event %s", eventID)
-	handlers.EncodeSuccess(c, http.StatusOK, s.EventService.ResolveLinks(c, updatedEvent))
+	s.encodeEvent(c, updatedEvent)
 }
 
 func (s S) DeleteEvent(c *gin.Context) {
@@ -175,7 +387,44 @@ func (s S) DeleteEvent(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
-	err := s.EventService.DeleteEvent(c, eventID)
+	scope, occurrenceID, err := editScope(c)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	release, err := s.guardWrite(c, "event", eventID, s.currentEvent(c, eventID))
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	switch scope {
+	case scopeThis:
+		o, err := s.changeOccurrence(eventID, occurrenceID, func(e *recurrence.Exception, _ recurrence.Occurrence) error {
+			e.Cancelled = true
+			return nil
+		})
+		if err != nil {
+			handlers.EncodeError(c, err)
+			return
+		}
+		s.notifyOccurrenceChange(c, eventID, o)
+		logger.Info(c, "cancelled occurrence %s of event %s", o.ID, eventID)
+		handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+		return
+	case scopeFollowing:
+		whole, err := s.endSeriesBefore(eventID, occurrenceID)
+		if err != nil {
+			handlers.EncodeError(c, err)
+			return
+		}
+		if !whole {
+			logger.Info(c, "ended event %s before occurrence %s", eventID, occurrenceID)
+			handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+			return
+		}
+	}
+	err = s.EventService.DeleteEvent(c, eventID)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
@@ -195,6 +444,26 @@ func (s S) DeleteEvent(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
+	err = s.Schedules.Delete(eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err = s.Waitlists.Delete(eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err = s.Guests.Delete(eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err = s.Deadlines.Delete(eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
 	logger.Info(c, "successfully deleted event %s", eventID)
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
@@ -206,13 +475,46 @@ func (s S) UpdateEventSettings(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
+	release, err := s.guardWrite(c, "settings", eventID, s.currentSettings(c, eventID))
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
 	var settingUpdates models.M
 	if err := json.NewDecoder(c.Request.Body).Decode(&settingUpdates); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	policy, err := takeGuestPolicy(settingUpdates)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	rsvpPolicy, err := takeRSVPPolicy(settingUpdates)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if len(settingUpdates) > 0 {
+		if err := s.checkSettingUpdates(c, eventID, settingUpdates); err != nil {
+			handlers.EncodeError(c, err)
+			return
+		}
+	}
+	putBack, err := s.savePolicies(c, eventID, policy, rsvpPolicy)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if len(settingUpdates) == 0 && (policy != nil || rsvpPolicy != nil) {
+		logger.Info(c, "successfully updated the guest policy and rsvp deadline of event %s", eventID)
+		handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 		return
 	}
-	err := s.EventService.UpdateEventSettings(c, eventID, settingUpdates)
+	err = s.EventService.UpdateEventSettings(c, eventID, settingUpdates)
 	if err != nil {
+		putBack()
 		handlers.EncodeError(c, err)
 		return
 	}
@@ -220,6 +522,199 @@ func (s S) UpdateEventSettings(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
 
+// currentSettings renders the settings of the event like GetEventSettings,
+// for guardWrite
+func (s S) currentSettings(c *gin.Context, eventID string) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		settings, err := s.getEventSettings(c, eventID)
+		if err != nil {
+			return nil, err
+		}
+		channels, err := s.ChatService.GetChannels(c, eventID)
+		if err != nil {
+			return nil, err
+		}
+		return settingsResponse(settings, channels), nil
+	}
+}
+
+// checkSettingUpdates validates the settings a PUT replaces, other than the
+// guest policy and RSVP deadline, as they would be stored
+func (s S) checkSettingUpdates(c *gin.Context, eventID string, updates models.M) error {
+	current, err := s.EventService.GetEventSettings(c, eventID)
+	if err != nil {
+		return err
+	}
+	merged := utils.Normalize(current)
+	for k, v := range updates {
+		merged[k] = v
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	var settings models.EventSettings
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		return handlers.MalformedBody(err)
+	}
+	channels, err := s.ChatService.GetChannels(c, eventID)
+	if err != nil {
+		return fmt.Errorf("unable to list chat channels: %v", err)
+	}
+	return validateEventSettings(settings, channels)
+}
+
+// savePolicies saves the guest policy and RSVP deadline of the event, nil
+// keeps one as it is. They are kept in documents of their own that can't be
+// written together, so when the deadline can't be saved the guest policy is
+// put back. The returned func puts both back, for when a later write of the
+// settings fails.
+func (s S) savePolicies(c *gin.Context, eventID string, guestPolicy *guests.Policy, rsvpPolicy *reminders.Policy) (func(), error) {
+	var undo []func() error
+	rollback := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				logger.Error(c, "unable to put back the settings of event %s: %v", eventID, err)
+			}
+		}
+	}
+	if guestPolicy != nil {
+		guestList, err := s.Guests.Get(eventID)
+		if err != nil {
+			return nil, err
+		}
+		previous := guestList.Policy
+		if err := s.saveGuestPolicy(eventID, *guestPolicy); err != nil {
+			return nil, err
+		}
+		undo = append(undo, func() error { return s.saveGuestPolicy(eventID, previous) })
+	}
+	if rsvpPolicy != nil {
+		previous, err := s.rsvpPolicy(eventID)
+		if err == nil {
+			err = s.saveRSVPPolicy(eventID, *rsvpPolicy)
+		}
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		undo = append(undo, func() error { return s.saveRSVPPolicy(eventID, previous) })
+	}
+	return rollback, nil
+}
+
+// settingsResponse is the settings as GetEventSettings returns them, along
+// with the chat channels of the event
+func settingsResponse(settings eventSettings, channels []models.Channel) gin.H {
+	totalChannels := []models.M{}
+	for _, channel := range channels {
+		totalChannels = append(totalChannels, models.M{
+			"channelId": channel.ID,
+			"name":      channel.Name,
+			"is_open":   utils.ContainsString(settings.Chat.OpenChannels, channel.ID),
+			"is_muted":  utils.ContainsString(settings.Chat.MutedChannels, channel.ID),
+		})
+	}
+	settingsAsMap := utils.Normalize(settings.EventSettings)
+	settingsAsMap["guests"] = settings.Guests
+	settingsAsMap["rsvp"] = settings.RSVP
+	chatSettings := settings.Chat
+	chatSettingsAsMap := utils.Normalize(chatSettings)
+	chatSettingsAsMap["channels"] = totalChannels
+	settingsAsMap["chats"] = chatSettingsAsMap
+	return gin.H{"settings": settingsAsMap}
+}
+
+// getEventSettings reads the settings of the event along with its guest policy
+// and RSVP deadline
+func (s S) getEventSettings(c *gin.Context, eventID string) (eventSettings, error) {
+	settings, err := s.EventService.GetEventSettings(c, eventID)
+	if err != nil {
+		return eventSettings{}, err
+	}
+	guestList, err := s.Guests.Get(eventID)
+	if err != nil {
+		return eventSettings{}, err
+	}
+	rsvpPolicy, err := s.rsvpPolicy(eventID)
+	if err != nil {
+		return eventSettings{}, err
+	}
+	return eventSettings{EventSettings: settings, Guests: guestList.Policy, RSVP: rsvpPolicy}, nil
+}
+
+// PatchEventSettings applies a merge patch to the event settings. Unlike the
+// PUT, only whitelisted settings are accepted.
+func (s S) PatchEventSettings(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	release, err := s.guardWrite(c, "settings", eventID, s.currentSettings(c, eventID))
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	current, err := s.getEventSettings(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	var patched eventSettings
+	settingUpdates := models.M{}
+	if err := applyMergePatch(c, eventSettingsPatchable, current, &patched, &settingUpdates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	channels, err := s.ChatService.GetChannels(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, fmt.Errorf("unable to list chat channels: %v", err))
+		return
+	}
+	if err := validateEventSettings(patched.EventSettings, channels); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	var guestPolicy *guests.Policy
+	var rsvpPolicy *reminders.Policy
+	if _, ok := settingUpdates["guests"]; ok {
+		if err := checkGuestPolicy(patched.Guests); err != nil {
+			handlers.EncodeError(c, err)
+			return
+		}
+		guestPolicy = &patched.Guests
+		delete(settingUpdates, "guests")
+	}
+	if _, ok := settingUpdates["rsvp"]; ok {
+		if err := checkRSVPPolicy(patched.RSVP, time.Now()); err != nil {
+			handlers.EncodeError(c, err)
+			return
+		}
+		rsvpPolicy = &patched.RSVP
+		delete(settingUpdates, "rsvp")
+	}
+	putBack, err := s.savePolicies(c, eventID, guestPolicy, rsvpPolicy)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if len(settingUpdates) > 0 {
+		err = s.EventService.UpdateEventSettings(c, eventID, settingUpdates)
+		if err != nil {
+			putBack()
+			handlers.EncodeError(c, err)
+			return
+		}
+	}
+	logger.Info(c, "successfully patched event settings for event %s", eventID)
+	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+}
+
 func (s S) GetEventMembers(c *gin.Context) {
 	param := "eventId"
 	eventID := c.Param(param)
@@ -232,8 +727,42 @@ func (s S) GetEventMembers(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
+	guestList, err := s.Guests.Get(eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	list, err := s.Waitlists.Get(eventID)
+	if err != nil && err != waitlist.ErrNotFound {
+		handlers.EncodeError(c, err)
+		return
+	}
+	event = s.withNoResponse(c, event)
+	members := s.EventService.EventMembersList(c, event)
+	for _, m := range members {
+		if userID, ok := m["userId"].(string); ok {
+			party := guestList.Party(userID)
+			m["guests"] = party.Count
+			m["guest_names"] = append([]string{}, party.Names...)
+		}
+	}
 	logger.Info(c, "retrieved %d members for event %s", len(event.Members), eventID)
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"members": s.EventService.EventMembersList(c, event)})
+	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"members": members, "headcounts": headcounts(event, guestList, list)})
+}
+
+// inviteMembersRequest adds existing users and people who aren't on kickback
+// yet, who are invited by SMS
+type inviteMembersRequest struct {
+	Users []struct {
+		IsHost bool   `json:"is_host"`
+		UserID string `json:"userId"`
+	} `json:"users"`
+	NewUsers []struct {
+		IsHost      bool   `json:"is_host"`
+		PhoneNumber string `json:"phone_number"`
+		FirstName   string `json:"first_name"`
+		LastName    string `json:"last_name"`
+	} `json:"new_users"`
 }
 
 func (s S) InviteEventMembers(c *gin.Context) {
@@ -243,20 +772,9 @@ func (s S) InviteEventMembers(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
-	var body struct {
-		Users []struct {
-			IsHost bool   `json:"is_host"`
-			UserID string `json:"userId"`
-		} `json:"users"`
-		NewUsers []struct {
-			IsHost      bool   `json:"is_host"`
-			PhoneNumber string `json:"phone_number"`
-			FirstName   string `json:"first_name"`
-			LastName    string `json:"last_name"`
-		} `json:"new_users"`
-	}
+	var body inviteMembersRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	// set invited by to the user making the request
@@ -265,6 +783,7 @@ func (s S) InviteEventMembers(c *gin.Context) {
 	failures := models.M{}
 	hosts := []string{}
 
+	endSpan := telemetry.StartSpan(c, "UserService.CreateUser", attribute.Int("count", len(body.NewUsers)))
 	for _, newUser := range body.NewUsers {
 		newUserID, err := s.UserService.CreateUser(c, &models.User{
 			PhoneNumber: newUser.PhoneNumber,
@@ -295,6 +814,7 @@ func (s S) InviteEventMembers(c *gin.Context) {
 			hosts = append(hosts, newUserID)
 		}
 	}
+	endSpan(nil)
 
 	for _, user := range body.Users {
 		invitesToSend = append(invitesToSend, models.Member{
@@ -306,12 +826,16 @@ func (s S) InviteEventMembers(c *gin.Context) {
 			hosts = append(hosts, user.UserID)
 		}
 	}
+	endSpan = telemetry.StartSpan(c, "EventService.Invite", attribute.Int("count", len(invitesToSend)))
 	membersAdded, err := s.EventService.Invite(c, eventID, invitesToSend)
+	endSpan(err)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
+	endSpan = telemetry.StartSpan(c, "EventService.AddEventHosts")
 	err = s.EventService.AddEventHosts(c, eventID, hosts)
+	endSpan(err)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
@@ -328,7 +852,7 @@ func (s S) InviteEventMembers(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
-	s.doSendNotification(c, models.Notification{
+	s.sendInBackground(c, models.Notification{
 		Type:     models.EventRSVP,
 		Channels: []string{"push", "sms"}, // this only triggers a push because SMSMsg attribute is empty
 		To:       memberListToUserIDs(membersAdded),
@@ -338,7 +862,9 @@ func (s S) InviteEventMembers(c *gin.Context) {
 			"eventId": eventID,
 		},
 	})
+	endSpan = telemetry.StartSpan(c, "UserService.GetUsers")
 	invitedUsers, err := s.UserService.GetUsers(c, bson.M{"_id": bson.M{"$in": memberListToUserIDs(membersAdded)}})
+	endSpan(err)
 	if err != nil {
 		logger.Error(c, "unable to get invited users info: %v", err)
 		handlers.EncodeError(c, err)
@@ -346,19 +872,33 @@ func (s S) InviteEventMembers(c *gin.Context) {
 	}
 	// need to use sms method directly because we need to make each invite personalized by user phone number in the embedded link
 	for _, invited := range invitedUsers {
+		if invited.PhoneNumber == "" {
+			continue
+		}
+		link, err := s.rsvpLink(eventID, invited.ID)
+		if err != nil {
+			logger.Error(c, "unable to create rsvp link for %s: %v", invited.ID, err)
+			continue
+		}
 		msg := fmt.Sprintf("%v has invited you to %v\n\n"+
 			"Description: %v\n\n"+
 			"See event details and RSVP: [Link to app]\n\n"+
-			"https://kickbackapp.io/invited?eventId=%v&userId=%v",
-			host.Name(), event.Name, event.Description, eventID, invited.ID)
-		if invited.PhoneNumber != "" {
-			s.NotificationService.SendSMS(c, msg, invited.PhoneNumber)
+			"%v",
+			host.Name(), event.Name, event.Description, link)
+		endSpan = telemetry.StartSpan(c, "NotificationService.SendSMS", attribute.String("userId", invited.ID))
+		_, err = s.sendSMS(c, msg, invited.PhoneNumber)
+		endSpan(err)
+		if err == nil {
+			metrics.SMSSent.WithLabelValues("invite").Inc()
 		}
 	}
+	endSpan = telemetry.StartSpan(c, "UserService.Connect")
 	err = s.UserService.Connect(c, event.MemberUserIDs())
+	endSpan(err)
 	if err != nil {
 		logger.Error(c, "unable to add user connections: %v", err)
 		handlers.EncodeError(c, err)
+		return
 	}
 	logger.Info(c, "successfully added %d members to event %s", len(membersAdded), eventID)
 	handlers.EncodeSuccess(c, http.StatusOK, gin.H{
@@ -367,6 +907,26 @@ func (s S) InviteEventMembers(c *gin.Context) {
 	})
 }
 
+// rsvpRequest is the answer of a member, without guests they keep bringing
+// the guests they brought before
+type rsvpRequest struct {
+	Status     models.MemberStatus `json:"status"`
+	Guests     *int                `json:"guests"`
+	GuestNames []string            `json:"guest_names"`
+}
+
+// party is who the member brings, nil when the request doesn't change it
+func (r rsvpRequest) party() *guests.Party {
+	if r.Guests == nil && r.GuestNames == nil {
+		return nil
+	}
+	p := guests.Party{Count: len(r.GuestNames), Names: r.GuestNames}
+	if r.Guests != nil {
+		p.Count = *r.Guests
+	}
+	return &p
+}
+
 func (s S) RSVP(c *gin.Context) {
 	param := "eventId"
 	eventID := c.Param(param)
@@ -375,30 +935,46 @@ func (s S) RSVP(c *gin.Context) {
 		return
 	}
 	source := c.Query("source")
-	var rsvp struct {
-		// if empty, it will be the authenticated user
-		// this allows the front-end web app to make unathenticated calls to rsvp a user
-		UserID string              `json:"userId"`
-		Status models.MemberStatus `json:"status"`
-	}
+	var rsvp rsvpRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&rsvp); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
-	currentUserID := utils.CurrentUser(c).ID
-	if currentUserID == "" && rsvp.UserID == "" {
-		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
-		return
+	// the web app rsvps on behalf of the invitee identified by the invite link token
+	userID := c.GetString(inviteUserIDKey)
+	if userID == "" {
+		userID = utils.CurrentUser(c).ID
 	}
-	userID := rsvp.UserID
 	if userID == "" {
-		userID = currentUserID
+		handlers.EncodeError(c, handlers.UnauthorizedError{})
+		return
+	}
+	// members of a recurring event answer for a single occurrence
+	occurrenceID := c.Query("occurrence")
+	var (
+		waiting *waitlist.Entry
+		err     error
+	)
+	switch {
+	case rsvp.Status == memberStatusNoResponse:
+		err = handlers.InvalidBodyFieldError{Field: "status", Reason: "is set once the rsvp deadline passes"}
+	case occurrenceID != "" && rsvp.party() != nil:
+		err = handlers.InvalidBodyFieldError{Field: "guests", Reason: "are set for the whole event, not a single occurrence"}
+	case occurrenceID != "":
+		err = s.rsvpOccurrence(c, eventID, occurrenceID, userID, rsvp.Status)
+	default:
+		waiting, err = s.setRSVP(c, eventID, userID, rsvp.Status, rsvp.party())
 	}
-	err := s.EventService.RSVP(c, eventID, userID, rsvp.Status)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
+	if waiting != nil {
+		metrics.RSVP("waitlisted", source)
+		logger.Info(c, "put member %s on the waitlist of event %s at position %d", userID, eventID, waiting.Position)
+		handlers.EncodeSuccess(c, http.StatusAccepted, waiting)
+		return
+	}
 	event, err := s.EventService.GetEvent(c, eventID)
 	if err != nil {
 		logger.Error(c, "unable to get event details: %v", err)
@@ -411,13 +987,26 @@ func (s S) RSVP(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
-	if rsvp.Status == models.MemberStatusGoing {
+	s.announceRSVP(c, event, user, rsvp.Status, source)
+	logger.Info(c, "set member %s status to %s for event %s (occurrence: '%s')", userID, rsvp.Status, eventID, occurrenceID)
+	handlers.EncodeSuccess(c, http.StatusOK, gin.H{userID: rsvp.Status})
+}
+
+// announceRSVP sends the notifications that follow a member changing their
+// RSVP status, source is where the RSVP came from (app, web or sms)
+func (s S) announceRSVP(c *gin.Context, event models.Event, user models.User, status models.MemberStatus, source string) {
+	if source == "" {
+		source = "app"
+	}
+	metrics.RSVP(string(status), source)
+	eventID := event.ID
+	if status == models.MemberStatusGoing {
 		// if rsvp'ing from web, trigger text
 		if source == "web" {
 			msg := fmt.Sprintf("Thank you for RSVPing %s."+
 				" The other attendees have been added to your Kickback contacts and you can invite them to events going forward."+
 				" To host your own event, get our app", event.Name)
-			s.doSendNotification(c, models.Notification{
+			s.sendInBackground(c, models.Notification{
 				Type:     models.EventMemberAttending,
 				Channels: []string{"sms"},
 				To:       []string{user.ID},
@@ -431,7 +1020,7 @@ func (s S) RSVP(c *gin.Context) {
 		}
 		// if is host, send push notification
 		if utils.ContainsString(event.Hosts, user.ID) {
-			s.doSendNotification(c, models.Notification{
+			s.sendInBackground(c, models.Notification{
 				Type:     models.EventCoHost,
 				Channels: []string{"push"},
 				To:       []string{user.ID},
@@ -444,18 +1033,16 @@ func (s S) RSVP(c *gin.Context) {
 		}
 	}
 	// let creator of event know someone rsvp'ed
-	s.doSendNotification(c, models.Notification{
+	s.sendInBackground(c, models.Notification{
 		Type:     models.EventRSVP,
 		Channels: []string{"push"},
 		To:       []string{event.CreatedBy},
 		Title:    "Someone RSVPed to your Kickback event",
-		Body:     fmt.Sprintf("%v set their status as %v for your event %v", user.Name(), rsvp.Status, event.Name),
+		Body:     fmt.Sprintf("%v set their status as %v for your event %v", user.Name(), status, event.Name),
 		Data: map[string]string{
 			"eventId": eventID,
 		},
 	})
-	logger.Info(c, "set member %s status to %s for event %s", userID, rsvp.Status, eventID)
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{userID: rsvp.Status})
 }
 
 func (s S) GetEventSettings(c *gin.Context) {
@@ -465,7 +1052,7 @@ func (s S) GetEventSettings(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
-	settings, err := s.EventService.GetEventSettings(c, eventID)
+	settings, err := s.getEventSettings(c, eventID)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
@@ -475,22 +1062,12 @@ func (s S) GetEventSettings(c *gin.Context) {
 		handlers.EncodeError(c, fmt.Errorf("unable to list chat channels: %v", err))
 		return
 	}
-	totalChannels := []models.M{}
-	for _, channel := range channels {
-		totalChannels = append(totalChannels, models.M{
-			"channelId": channel.ID,
-			"name":      channel.Name,
-			"is_open":   utils.ContainsString(settings.Chat.OpenChannels, channel.ID),
-			"is_muted":  utils.ContainsString(settings.Chat.MutedChannels, channel.ID),
-		})
+	response := settingsResponse(settings, channels)
+	if notModified(c, response) {
+		return
 	}
-	settingsAsMap := utils.Normalize(settings)
-	chatSettings := settings.Chat
-	chatSettingsAsMap := utils.Normalize(chatSettings)
-	chatSettingsAsMap["channels"] = totalChannels
-	settingsAsMap["chats"] = chatSettingsAsMap
 	logger.Info(c, "retrieved settings for event %s", eventID)
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"settings": settingsAsMap})
+	handlers.EncodeSuccess(c, http.StatusOK, response)
 }
 
 func memberListToUserIDs(members []models.Member) []string {
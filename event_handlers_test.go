@@ -0,0 +1,138 @@
+package server_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/internal/services"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventConditionalRequests(t *testing.T) {
+	storedEvent := `{"_id": "mockEventId", "name": "Game night", "createdBy": "mockUserId", "created_at": 666}`
+	renamedEvent := `{"_id": "mockEventId", "name": "Board game night", "createdBy": "mockUserId", "created_at": 666}`
+	newServer := func(responses ...interface{}) server.S {
+		return server.S{
+			EventService: services.EventService{
+				DBClient: utils.MockDBClient{
+					CallCount: new(int),
+					Responses: responses,
+				},
+			},
+			NotificationService: services.NotificationService{
+				DBClient: utils.MockDBClient{
+					CallCount:       new(int),
+					DefaultResponse: "NTF_Mock",
+				},
+			},
+		}
+	}
+	request := func(method, body string, header http.Header) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: header}
+		ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}}
+		utils.MockRequest(ctx, method, body)
+		return ctx, w
+	}
+
+	ctx, w := request(http.MethodGet, "", http.Header{})
+	newServer(storedEvent).GetEvent(ctx)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	tag := w.Header().Get("ETag")
+	assert.NotEmpty(t, tag)
+
+	// the event to check the If-Match against, the update and the updated event
+	ctx, w = request(http.MethodPut, `{"name": "Board game night"}`, http.Header{"If-Match": {tag}})
+	newServer(storedEvent, int64(1), renamedEvent).UpdateEvent(ctx)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	renamedTag := w.Header().Get("ETag")
+	assert.NotEqual(t, tag, renamedTag)
+
+	// the ETag of the write is the one its GET answers with
+	ctx, w = request(http.MethodGet, "", http.Header{"If-None-Match": {renamedTag}})
+	newServer(renamedEvent).GetEvent(ctx)
+	assert.EqualValues(t, http.StatusNotModified, w.Code)
+
+	ctx, w = request(http.MethodPut, `{"name": "Game night"}`, http.Header{"If-Match": {renamedTag}})
+	newServer(renamedEvent, int64(1), storedEvent).UpdateEvent(ctx)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+}
+
+func TestUpdateEventSettingsWritesAllOrNothing(t *testing.T) {
+	settings := `{"chat": {"open_channels": [], "muted_channels": []}}`
+	newServer := func(guestList guests.Store, responses ...interface{}) server.S {
+		return server.S{
+			EventService: services.EventService{
+				DBClient: utils.MockDBClient{CallCount: new(int), Responses: responses, DefaultResponse: settings},
+			},
+			ChatService: services.ChatService{
+				DBClient: utils.MockDBClient{CallCount: new(int), DefaultResponse: `[{"_id": "CHN_1", "name": "general"}]`},
+			},
+			Guests:    guestList,
+			Deadlines: reminders.NewMemoryStore(),
+		}
+	}
+	cases := []struct {
+		Name               string
+		Body               string
+		IfMatch            string
+		Responses          []interface{}
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "settings are checked before the guest policy is saved",
+			Body:               `{"guests": {"max_per_member": 3}, "chat": {"open_channels": ["CHN_9"]}}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "unknown settings are rejected",
+			Body:               `{"guests": {"max_per_member": 3}, "color": "red"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "the guest policy is put back when the settings can't be saved",
+			Body:               `{"guests": {"max_per_member": 3}, "chat": {"open_channels": ["CHN_1"]}}`,
+			Responses:          []interface{}{settings, errors.New("database unavailable")},
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			Name:               "stale If-Match",
+			Body:               `{"guests": {"max_per_member": 3}}`,
+			IfMatch:            `"stale"`,
+			ExpectedStatusCode: http.StatusPreconditionFailed,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		store := guests.NewMemoryStore()
+		list := guests.New("mockEventId")
+		list.Policy = guests.Policy{MaxPerMember: 1}
+		if err := store.Save(list); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}}
+		utils.MockRequest(ctx, http.MethodPut, c.Body)
+		if c.IfMatch != "" {
+			ctx.Request.Header.Set("If-Match", c.IfMatch)
+		}
+		newServer(store, c.Responses...).UpdateEventSettings(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		list, err := store.Get("mockEventId")
+		assert.NoError(t, err)
+		assert.Equal(t, guests.Policy{MaxPerMember: 1}, list.Policy, c.Name)
+	}
+}
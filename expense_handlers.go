@@ -8,8 +8,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/logger"
 	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/etag"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/paging"
+	"github.com/kickback-app/api/server/telemetry"
 	"github.com/kickback-app/api/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func (s S) GetExpenses(c *gin.Context) {
@@ -19,18 +23,23 @@ func (s S) GetExpenses(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
+	query, err := paging.Parse(c.Request.URL.Query(), expenseListing)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	endSpan := telemetry.StartSpan(c, "ExpenseService.GetExpenses")
 	res, err := s.ExpenseService.GetExpenses(c, kickbackID)
+	endSpan(err)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
 	currUser := utils.CurrentUser(c).ID
-	totalExpenses, expensesOwed, expensesOwes := []models.M{}, []models.M{}, []models.M{}
+	// totals are the user's balance in the kickback, they cover every expense
+	// no matter which page is requested
 	totalOwed, totalCollected, totalOwes, totalPaid := 0.0, 0.0, 0.0, 0.0
 	for _, exp := range res {
-		associatedUsers := s.ExpenseService.AssociatedUserIDs(exp)
-		userObjs := s.UserService.SummarizeUsers(c, associatedUsers)
-		totalExpenses = append(totalExpenses, s.ExpenseService.ResolveLinks(c, exp))
 		relation, assignees := s.ExpenseService.Contextualize(exp, currUser)
 		for _, a := range assignees {
 			if relation == models.ExpenseOwed {
@@ -38,6 +47,29 @@ func (s S) GetExpenses(c *gin.Context) {
 				if a.IsCompleted {
 					totalCollected += a.Amount
 				}
+			}
+			if relation == models.ExpenseOwes {
+				totalOwes += a.Amount
+				if a.IsCompleted {
+					totalPaid += a.Amount
+				}
+			}
+		}
+	}
+	page, nextCursor := query.Apply(normalizeEach(res))
+	totalExpenses, expensesOwed, expensesOwes := []models.M{}, []models.M{}, []models.M{}
+	for _, i := range page {
+		exp := res[i]
+		endSpan = telemetry.StartSpan(c, "UserService.SummarizeUsers", attribute.String("expenseId", exp.ID))
+		associatedUsers := s.ExpenseService.AssociatedUserIDs(exp)
+		userObjs := s.UserService.SummarizeUsers(c, associatedUsers)
+		endSpan(nil)
+		endSpan = telemetry.StartSpan(c, "ExpenseService.ResolveLinks", attribute.String("expenseId", exp.ID))
+		totalExpenses = append(totalExpenses, s.ExpenseService.ResolveLinks(c, exp))
+		endSpan(nil)
+		relation, assignees := s.ExpenseService.Contextualize(exp, currUser)
+		for _, a := range assignees {
+			if relation == models.ExpenseOwed {
 				expensesOwed = append(expensesOwed, a.ToMap(models.M{
 					"_id":        exp.ID,
 					"name":       exp.Name,
@@ -46,10 +78,6 @@ func (s S) GetExpenses(c *gin.Context) {
 				}))
 			}
 			if relation == models.ExpenseOwes {
-				totalOwes += a.Amount
-				if a.IsCompleted {
-					totalPaid += a.Amount
-				}
 				expensesOwes = append(expensesOwes, a.ToMap(models.M{
 					"_id":        exp.ID,
 					"name":       exp.Name,
@@ -59,7 +87,7 @@ func (s S) GetExpenses(c *gin.Context) {
 			}
 		}
 	}
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{
+	handlers.EncodePage(c, http.StatusOK, gin.H{
 		"total_collected": totalCollected,
 		"total_owed":      totalOwed,
 		"total_paid":      totalPaid,
@@ -68,7 +96,7 @@ func (s S) GetExpenses(c *gin.Context) {
 		"expenses_owes":   expensesOwes,
 		"total_expenses":  totalExpenses,
 		"user":            s.UserService.SummarizeUsers(c, []string{currUser}).Find(currUser),
-	})
+	}, nextCursor)
 }
 
 func (s S) CreateExpense(c *gin.Context) {
@@ -80,7 +108,11 @@ func (s S) CreateExpense(c *gin.Context) {
 	}
 	var expense models.Expense
 	if err := json.NewDecoder(c.Request.Body).Decode(&expense); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if err := validateExpense(expense, s.config.Expenses.StrictValidation); err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
 	expense.ParentID = kickbackID
@@ -95,7 +127,7 @@ func (s S) CreateExpense(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
-	s.doSendNotification(c, models.Notification{
+	s.sendInBackground(c, models.Notification{
 		Type:     models.ExpenseCreated,
 		Channels: []string{"push"},
 		To:       assigneesToUserIDs(expense.Assignees, false),
@@ -117,7 +149,10 @@ func (s S) GetExpense(c *gin.Context) {
 	}
 	expense, err := s.ExpenseService.GetExpense(c, expenseID)
 	if err != nil {
-		handlers.EncodeError(c, err)
+		handlers.EncodeError(c, handlers.NotFound("expense", err))
+		return
+	}
+	if notModified(c, expense) {
 		return
 	}
 	logger.Info(c, "retrieved expense %s", expenseID)
@@ -132,9 +167,61 @@ func (s S) UpdateExpense(c *gin.Context) {
 	}
 	var input models.ExpenseUpdates
 	if err := json.NewDecoder(c.Request.Body).Decode(&input); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
+	if err := validateExpenseUpdates(input, s.config.Expenses.StrictValidation); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	release, err := s.guardWrite(c, "expense", expenseID, func() (interface{}, error) {
+		return s.ExpenseService.GetExpense(c, expenseID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	s.saveExpenseUpdates(c, expenseID, input)
+}
+
+// PatchExpense applies a merge patch to the expense, see applyMergePatch
+func (s S) PatchExpense(c *gin.Context) {
+	param := "expenseId"
+	expenseID := c.Param(param)
+	if expenseID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	release, err := s.guardWrite(c, "expense", expenseID, func() (interface{}, error) {
+		return s.ExpenseService.GetExpense(c, expenseID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	current, err := s.ExpenseService.GetExpense(c, expenseID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("expense", err))
+		return
+	}
+	var patched models.Expense
+	var input models.ExpenseUpdates
+	if err := applyMergePatch(c, expensePatchable, current, &patched, &input); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if err := validateExpense(patched, s.config.Expenses.StrictValidation); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	s.saveExpenseUpdates(c, expenseID, input)
+}
+
+// saveExpenseUpdates persists validated updates, notifies whoever is on the
+// other side of the expense and responds with the updated expense
+func (s S) saveExpenseUpdates(c *gin.Context, expenseID string, input models.ExpenseUpdates) {
 	err := s.ExpenseService.UpdateExpense(c, expenseID, &input)
 	if err != nil {
 		handlers.EncodeError(c, err)
@@ -159,7 +246,7 @@ func (s S) UpdateExpense(c *gin.Context) {
 	}
 	if expense.CreatedBy == utils.CurrentUser(c).ID && input.Assignees != nil {
 		// the charger is marking as paid so notify chargee
-		s.doSendNotification(c, models.Notification{
+		s.sendInBackground(c, models.Notification{
 			Type:     models.ExpenseUpdated,
 			Channels: []string{"push"},
 			To:       assigneesToUserIDs(*input.Assignees, true),
@@ -179,7 +266,7 @@ func (s S) UpdateExpense(c *gin.Context) {
 			handlers.EncodeError(c, err)
 			return
 		}
-		s.doSendNotification(c, models.Notification{
+		s.sendInBackground(c, models.Notification{
 			Type:     models.ExpenseUpdated,
 			Channels: []string{"push"},
 			To:       []string{expense.CreatedBy},
@@ -191,6 +278,7 @@ func (s S) UpdateExpense(c *gin.Context) {
 		})
 	}
 	logger.Info(c, "successfully updated expense %s", expenseID)
+	c.Header(etag.HeaderETag, etag.Of(expense))
 	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"expense": expense})
 }
 
@@ -200,6 +288,14 @@ func (s S) DeleteExpense(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: "expenseId"})
 		return
 	}
+	release, err := s.guardWrite(c, "expense", expenseID, func() (interface{}, error) {
+		return s.ExpenseService.GetExpense(c, expenseID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
 	// get updated expense for notification data
 	expense, err := s.ExpenseService.GetExpense(c, expenseID)
 	if err != nil {
@@ -221,7 +317,7 @@ func (s S) DeleteExpense(c *gin.Context) {
 	}
 	if expense.CreatedBy == utils.CurrentUser(c).ID {
 		// the charger is marking as paid so notify chargee
-		s.doSendNotification(c, models.Notification{
+		s.sendInBackground(c, models.Notification{
 			Type:     models.ExpenseDeleted,
 			Channels: []string{"push"},
 			To:       assigneesToUserIDs(expense.Assignees, false),
@@ -241,7 +337,7 @@ func (s S) DeleteExpense(c *gin.Context) {
 			handlers.EncodeError(c, err)
 			return
 		}
-		s.doSendNotification(c, models.Notification{
+		s.sendInBackground(c, models.Notification{
 			Type:     models.ExpenseDeleted,
 			Channels: []string{"push"},
 			To:       []string{expense.CreatedBy},
@@ -261,21 +357,31 @@ func (s S) DeleteExpense(c *gin.Context) {
  * offers a more tailored route
  *
  */
+type assigneeUpdateRequest struct {
+	IsComepleted bool   `json:"is_completed"`
+	Assignee     string `json:"assignee"`
+}
+
 func (s S) UpdateExpenseAssignee(c *gin.Context) {
 	expenseID := c.Param("expenseId")
 	if expenseID == "" {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: "expenseId"})
 		return
 	}
-	var input struct {
-		IsComepleted bool   `json:"is_completed"`
-		Assignee     string `json:"assignee"`
-	}
+	var input assigneeUpdateRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&input); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	release, err := s.guardWrite(c, "expense", expenseID, func() (interface{}, error) {
+		return s.ExpenseService.GetExpense(c, expenseID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
-	err := s.ExpenseService.UpdateAssignee(c, expenseID, input.Assignee, input.IsComepleted)
+	defer release()
+	err = s.ExpenseService.UpdateAssignee(c, expenseID, input.Assignee, input.IsComepleted)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
@@ -294,7 +400,7 @@ func (s S) UpdateExpenseAssignee(c *gin.Context) {
 	}
 	if expense.CreatedBy == utils.CurrentUser(c).ID && input.IsComepleted {
 		// the charger is marking as paid so notify chargee
-		s.doSendNotification(c, models.Notification{
+		s.sendInBackground(c, models.Notification{
 			Type:     models.ExpenseUpdated,
 			Channels: []string{"push"},
 			To:       []string{input.Assignee},
@@ -314,7 +420,7 @@ func (s S) UpdateExpenseAssignee(c *gin.Context) {
 			handlers.EncodeError(c, err)
 			return
 		}
-		s.doSendNotification(c, models.Notification{
+		s.sendInBackground(c, models.Notification{
 			Type:     models.ExpenseUpdated,
 			Channels: []string{"push"},
 			To:       []string{expense.CreatedBy},
@@ -326,6 +432,7 @@ func (s S) UpdateExpenseAssignee(c *gin.Context) {
 		})
 	}
 	logger.Info(c, "successfully updated users expenses status to %v", input.IsComepleted)
+	c.Header(etag.HeaderETag, etag.Of(expense))
 	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"expense": expense})
 }
 
@@ -0,0 +1,5 @@
+package server
+
+// ProcessDeadline lets the tests run the reminders of a single event without
+// waiting for runReminders
+var ProcessDeadline = S.processDeadline
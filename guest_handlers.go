@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/kickback-app/api/server/waitlist"
+)
+
+// the most guests a host can allow each member to bring
+const maxGuestsPerMember = 20
+
+// headcount is how many people answered with a status, members and the
+// guests they bring
+type headcount struct {
+	Members int `json:"members"`
+	Guests  int `json:"guests"`
+	Total   int `json:"total"`
+}
+
+// eventSettings are the settings of an event as the settings routes show
+// them, the guest policy and the RSVP deadline are kept apart from the rest
+type eventSettings struct {
+	models.EventSettings
+	Guests guests.Policy    `json:"guests"`
+	RSVP   reminders.Policy `json:"rsvp"`
+}
+
+func checkParty(policy guests.Policy, p guests.Party) error {
+	var v validate.Validator
+	v.Min("guests", float64(p.Count), 0)
+	switch {
+	case policy.MaxPerMember == 0:
+		v.Check(p.Count == 0, "guests", validate.RuleMax, "the host doesn't allow guests")
+	default:
+		v.Max("guests", float64(p.Count), float64(policy.MaxPerMember))
+	}
+	v.Check(len(p.Names) <= p.Count, "guest_names", validate.RuleMax, "can't name more guests than are coming")
+	if policy.RequireNames {
+		v.Check(len(p.Names) >= p.Count, "guest_names", validate.RuleRequired, "the host asks for the names of all guests")
+	}
+	for i, name := range p.Names {
+		field := fmt.Sprintf("guest_names[%d]", i)
+		v.Required(field, name)
+		v.MaxLength(field, name, maxNameLength)
+	}
+	return v.Err()
+}
+
+func checkGuestPolicy(policy guests.Policy) error {
+	var v validate.Validator
+	v.Min("guests.max_per_member", float64(policy.MaxPerMember), 0)
+	v.Max("guests.max_per_member", float64(policy.MaxPerMember), maxGuestsPerMember)
+	return v.Err()
+}
+
+// headcountWaitlisted is the headcount of the members waiting for a spot,
+// whatever their status
+const headcountWaitlisted models.MemberStatus = "waitlisted"
+
+// headcounts breaks the members of the event down by status, counting the
+// guests they bring. Members on the waitlist are counted apart.
+func headcounts(event models.Event, guestList guests.List, list waitlist.List) map[models.MemberStatus]headcount {
+	counts := map[models.MemberStatus]headcount{
+		models.MemberStatusInvited:  {},
+		models.MemberStatusGoing:    {},
+		models.MemberStatusNotGoing: {},
+		models.MemberStatusMaybe:    {},
+		memberStatusNoResponse:      {},
+		headcountWaitlisted:         {},
+	}
+	for _, m := range event.Members {
+		status := m.Status
+		if list.Position(m.UserID) != 0 {
+			status = headcountWaitlisted
+		}
+		count := counts[status]
+		guestCount := guestList.Party(m.UserID).Count
+		count.Members++
+		count.Guests += guestCount
+		count.Total += 1 + guestCount
+		counts[status] = count
+	}
+	return counts
+}
+
+// takeSetting removes key from the settings of a PUT and decodes it into v,
+// for the settings the server keeps apart from models.EventSettings. It
+// reports whether the settings had key.
+func takeSetting(settings models.M, key string, v interface{}) (bool, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return false, nil
+	}
+	delete(settings, key)
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return true, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return true, handlers.MalformedBody(err)
+	}
+	return true, nil
+}
+
+// takeGuestPolicy removes the guest policy from the settings of a PUT and
+// returns it, nil when the settings don't change it
+func takeGuestPolicy(settings models.M) (*guests.Policy, error) {
+	var policy guests.Policy
+	ok, err := takeSetting(settings, "guests", &policy)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return &policy, checkGuestPolicy(policy)
+}
+
+// saveGuestPolicy sets the guest policy of the event, members keep the guests
+// they already bring
+func (s S) saveGuestPolicy(eventID string, policy guests.Policy) error {
+	err := docstore.Retry(func() error {
+		guestList, err := s.Guests.Get(eventID)
+		if err != nil {
+			return err
+		}
+		guestList.Policy = policy
+		return s.Guests.Save(guestList)
+	})
+	return guestsConflict(err)
+}
+
+// saveParty records who userID brings to the event
+func (s S) saveParty(eventID, userID string, p guests.Party) error {
+	err := docstore.Retry(func() error {
+		guestList, err := s.Guests.Get(eventID)
+		if err != nil {
+			return err
+		}
+		guestList.SetParty(userID, p)
+		return s.Guests.Save(guestList)
+	})
+	return guestsConflict(err)
+}
+
+// guestsConflict asks the client to retry a change to the guests of an event
+// that kept losing to other changes
+func guestsConflict(err error) error {
+	if err == docstore.ErrConflict {
+		return handlers.ConflictError{Resource: "guest list"}
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package server_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestGuestHandlers(t *testing.T) {
+	store := guests.NewMemoryStore()
+	mockServer := server.S{Guests: store}
+	cases := []struct {
+		Name               string
+		Method             string
+		Query              string
+		Body               string
+		Handler            func(server.S) gin.HandlerFunc
+		ExpectedStatusCode int
+		PathToResult       string
+		ExpectedResult     string
+	}{
+		{
+			Name:               "guest limit is checked",
+			Method:             http.MethodPut,
+			Body:               `{"guests": {"max_per_member": 50}}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventSettings },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.0.field",
+			ExpectedResult:     `"guests.max_per_member"`,
+		},
+		{
+			Name:               "happy path - allow plus-ones",
+			Method:             http.MethodPut,
+			Body:               `{"guests": {"max_per_member": 1, "require_names": true}}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventSettings },
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "guests can't be brought to a single occurrence",
+			Method:             http.MethodPut,
+			Query:              "occurrence=20261017T000000Z",
+			Body:               `{"status": "going", "guests": 1}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.RSVP },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.0.field",
+			ExpectedResult:     `"guests"`,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}}
+		utils.MockRequest(ctx, c.Method, c.Body)
+		ctx.Request.URL.RawQuery = c.Query
+		c.Handler(mockServer)(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		if c.ExpectedResult != "" {
+			assert.JSONEq(t, c.ExpectedResult, gjson.Get(w.Body.String(), c.PathToResult).Raw, c.Name)
+		}
+	}
+	list, err := store.Get("mockEventId")
+	assert.NoError(t, err)
+	assert.Equal(t, guests.Policy{MaxPerMember: 1, RequireNames: true}, list.Policy)
+}
+
+// unsavableDeadlines fails every write of an RSVP deadline
+type unsavableDeadlines struct {
+	reminders.Store
+}
+
+func (unsavableDeadlines) Save(reminders.Schedule) error {
+	return errors.New("database unavailable")
+}
+
+func TestSettingsArePutBackWhenAWriteFails(t *testing.T) {
+	store := guests.NewMemoryStore()
+	list := guests.New("mockEventId")
+	list.Policy = guests.Policy{MaxPerMember: 1}
+	if err := store.Save(list); err != nil {
+		t.Fatal(err)
+	}
+	mockServer := server.S{Guests: store, Deadlines: unsavableDeadlines{reminders.NewMemoryStore()}}
+	deadline := time.Now().Add(72 * time.Hour).UTC()
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Set("userId", "mockUserId")
+	ctx.Request = &http.Request{Header: make(http.Header)}
+	ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}}
+	utils.MockRequest(ctx, http.MethodPut, fmt.Sprintf(`{"guests": {"max_per_member": 3}, "rsvp": {"deadline": %q}}`, deadline.Format(time.RFC3339)))
+	mockServer.UpdateEventSettings(ctx)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	list, err := store.Get("mockEventId")
+	assert.NoError(t, err)
+	assert.Equal(t, guests.Policy{MaxPerMember: 1}, list.Policy, "the guest policy is put back")
+}
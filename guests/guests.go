@@ -0,0 +1,47 @@
+package guests
+
+// Policy is how many guests each member may bring, set by hosts in the event
+// settings. Kickbacks don't allow guests until a host sets a limit.
+type Policy struct {
+	MaxPerMember int  `json:"max_per_member"`
+	RequireNames bool `json:"require_names"`
+}
+
+// Party is who a member brings along, Names may name fewer guests than Count
+type Party struct {
+	Count int      `json:"count"`
+	Names []string `json:"names"`
+}
+
+// Size is the number of people in the party including the member
+func (p Party) Size() int {
+	return 1 + p.Count
+}
+
+// List is the guest policy of a kickback and the party of every member who
+// brings guests
+type List struct {
+	EventID string           `json:"eventId"`
+	Policy  Policy           `json:"policy"`
+	Parties map[string]Party `json:"parties"`
+	// Version is what the list was read at, see Store
+	Version int64 `json:"-"`
+}
+
+func New(eventID string) List {
+	return List{EventID: eventID, Parties: map[string]Party{}}
+}
+
+// Party is the party of userID, members without guests come alone
+func (l List) Party(userID string) Party {
+	return l.Parties[userID]
+}
+
+// SetParty records who userID brings, a party without guests is removed
+func (l *List) SetParty(userID string, p Party) {
+	if p.Count == 0 {
+		delete(l.Parties, userID)
+		return
+	}
+	l.Parties[userID] = p
+}
@@ -0,0 +1,41 @@
+package guests_test
+
+import (
+	"testing"
+
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	store := guests.NewMemoryStore()
+	list, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	assert.Equal(t, guests.Policy{}, list.Policy, "guests aren't allowed until a host sets a limit")
+	assert.Equal(t, 1, list.Party("USR_1").Size())
+
+	list.Policy.MaxPerMember = 2
+	list.SetParty("USR_1", guests.Party{Count: 2, Names: []string{"Sam"}})
+	assert.NoError(t, store.Save(list))
+	list.Parties["USR_1"].Names[0] = "Alex"
+
+	saved, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	assert.Equal(t, guests.Party{Count: 2, Names: []string{"Sam"}}, saved.Party("USR_1"))
+	assert.Equal(t, 3, saved.Party("USR_1").Size())
+
+	// list is still at the version it had before it was saved
+	list.Policy.RequireNames = true
+	assert.ErrorIs(t, store.Save(list), docstore.ErrConflict)
+	assert.ErrorIs(t, store.Save(guests.New("EVT_1")), docstore.ErrConflict, "a new list can't replace a stored one")
+
+	saved.SetParty("USR_1", guests.Party{})
+	assert.NotContains(t, saved.Parties, "USR_1")
+	assert.NoError(t, store.Save(saved))
+
+	assert.NoError(t, store.Delete("EVT_1"))
+	deleted, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	assert.Empty(t, deleted.Parties)
+}
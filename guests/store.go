@@ -0,0 +1,65 @@
+package guests
+
+import (
+	"github.com/kickback-app/api/server/docstore"
+)
+
+// Store persists the guest lists of kickbacks keyed by event ID, Get returns
+// an empty list for kickbacks that have none yet. Save only applies while the
+// stored list is still at the Version it was read at, and a new list only
+// while there is none, otherwise it returns docstore.ErrConflict.
+type Store interface {
+	Get(eventID string) (List, error)
+	Save(list List) error
+	Delete(eventID string) error
+}
+
+// DBStore keeps the guest lists in the database, shared by every instance
+type DBStore struct {
+	docs docstore.Collection
+}
+
+func NewDBStore(docs docstore.Collection) *DBStore {
+	return &DBStore{docs: docs}
+}
+
+// NewMemoryStore keeps the guest lists in memory, for tests and single
+// instance deployments
+func NewMemoryStore() *DBStore {
+	return NewDBStore(docstore.NewMemory().Collection("guests"))
+}
+
+func (d *DBStore) Get(eventID string) (List, error) {
+	var list List
+	version, err := d.docs.Get(eventID, &list)
+	if err == docstore.ErrNotFound {
+		return New(eventID), nil
+	}
+	if err != nil {
+		return List{}, err
+	}
+	list.Version = version
+	if list.Parties == nil {
+		list.Parties = map[string]Party{}
+	}
+	return list, nil
+}
+
+func (d *DBStore) Save(list List) error {
+	_, err := d.docs.Put(list.EventID, list.Version, list)
+	return err
+}
+
+func (d *DBStore) Delete(eventID string) error {
+	return docstore.Retry(func() error {
+		var list List
+		version, err := d.docs.Get(eventID, &list)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.docs.Delete(eventID, version)
+	})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Error codes are part of the API contract: clients branch on them, so an
+// existing code must never change meaning
+const (
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeMissingPathParam   = "MISSING_PATH_PARAM"
+	CodeMissingBodyField   = "MISSING_BODY_FIELD"
+	CodeMalformedBody      = "MALFORMED_BODY"
+	CodeValidationFailed   = "VALIDATION_FAILED"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeEventNotFound      = "EVENT_NOT_FOUND"
+	CodeTaskNotFound       = "TASK_NOT_FOUND"
+	CodeExpenseNotFound    = "EXPENSE_NOT_FOUND"
+	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
+	CodeConflict           = "CONFLICT"
+	CodeEventFull          = "EVENT_FULL"
+	CodeRSVPClosed         = "RSVP_CLOSED"
+	CodeUnsupportedMedia   = "UNSUPPORTED_MEDIA_TYPE"
+	CodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	CodePreconditionFailed = "PRECONDITION_FAILED"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeRequestInProgress  = "REQUEST_IN_PROGRESS"
+	CodeIdempotencyKeyUsed = "IDEMPOTENCY_KEY_REUSED"
+	CodeInternal           = "INTERNAL_ERROR"
+	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeUnknown            = "UNKNOWN_ERROR"
+)
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMedia,
+	http.StatusPreconditionFailed:    CodePreconditionFailed,
+	http.StatusUnprocessableEntity:   CodeValidationFailed,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeServiceUnavailable,
+}
+
+// ErrorCoder is implemented by APIErrors that have a more specific code than
+// the one derived from their status
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// ErrorDetailer is implemented by APIErrors that carry structured details,
+// e.g. which body fields were invalid
+type ErrorDetailer interface {
+	ErrorDetails() interface{}
+}
+
+// ErrorCode returns the code reported for err with the given status
+func ErrorCode(err error, status int) string {
+	if e, ok := err.(ErrorCoder); ok {
+		return e.ErrorCode()
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return CodeUnknown
+}
+
+func (e MissingPathParamError) ErrorCode() string { return CodeMissingPathParam }
+
+func (e MissingBodyFieldError) ErrorCode() string { return CodeMissingBodyField }
+
+func (e MissingBodyFieldError) ErrorDetails() interface{} {
+	return []FieldViolation{{Field: e.Field, Rule: "required", Message: "is required"}}
+}
+
+func (e MalformedBodyError) ErrorCode() string { return CodeMalformedBody }
+
+func (e MalformedBodyError) ErrorDetails() interface{} {
+	if e.Field == "" {
+		return nil
+	}
+	return []FieldViolation{{Field: e.Field, Rule: "type", Message: "has the wrong type"}}
+}
+
+// MalformedBody describes a body that couldn't be decoded, naming the field
+// when it's a type mismatch
+func MalformedBody(err error) MalformedBodyError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return MalformedBodyError{Field: typeErr.Field}
+	}
+	return MalformedBodyError{}
+}
+
+func (e UnauthorizedError) ErrorCode() string { return CodeUnauthorized }
+
+func (e ForbiddenError) ErrorCode() string { return CodeForbidden }
+
+func (e InvalidBodyFieldError) ErrorCode() string { return CodeValidationFailed }
+
+func (e InvalidBodyFieldError) ErrorDetails() interface{} {
+	return []FieldViolation{{Field: e.Field, Rule: "invalid", Message: e.Reason}}
+}
+
+func (e TooManyRequestsError) ErrorCode() string { return CodeRateLimited }
+
+func (e TooManyRequestsError) ErrorDetails() interface{} {
+	return map[string]int{"retryAfterSeconds": int(math.Ceil(e.RetryAfter.Seconds()))}
+}
+
+func (e RequestInProgressError) ErrorCode() string { return CodeRequestInProgress }
+
+func (e IdempotencyKeyReusedError) ErrorCode() string { return CodeIdempotencyKeyUsed }
+
+func (e EventFullError) ErrorCode() string { return CodeEventFull }
+
+func (e RSVPClosedError) ErrorCode() string { return CodeRSVPClosed }
+
+// NotFoundError is a 404 for a specific kind of resource, its code is
+// <RESOURCE>_NOT_FOUND, e.g. EVENT_NOT_FOUND
+type NotFoundError struct {
+	Resource string
+	Err      error
+}
+
+func (e NotFoundError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s not found", e.Resource)
+}
+
+func (e NotFoundError) Code() int { return http.StatusNotFound }
+
+func (e NotFoundError) ErrorCode() string {
+	return strings.ToUpper(e.Resource) + "_NOT_FOUND"
+}
+
+func (e NotFoundError) Unwrap() error { return e.Err }
+
+// NotFound tags a service's 404 with the resource that wasn't found, any
+// other error is returned as is
+func NotFound(resource string, err error) error {
+	if e, ok := err.(APIError); ok && e.Code() == http.StatusNotFound {
+		return NotFoundError{Resource: resource, Err: err}
+	}
+	return err
+}
+
+// FieldViolation is a single problem with a request body field
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports every invalid field of a request body at once
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		fields = append(fields, fmt.Sprintf("'%s' %s", v.Field, v.Message))
+	}
+	return "invalid request body: " + strings.Join(fields, ", ")
+}
+
+func (e ValidationError) Code() int { return http.StatusBadRequest }
+
+func (e ValidationError) ErrorCode() string { return CodeValidationFailed }
+
+func (e ValidationError) ErrorDetails() interface{} { return e.Violations }
@@ -1,12 +1,17 @@
 package handlers
 
 import (
-	"bytes"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/requestid"
+	"github.com/kickback-app/api/server/twilio"
 )
 
 // todo: turn into internal package?
@@ -20,77 +25,113 @@ type meta struct {
 	RequestID  string      `json:"requestId"`
 	HTTPStatus string      `json:"httpStatus"`
 	Error      *errorState `json:"error,omitempty"`
+	// NextCursor is set on paginated listings that have more items
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type errorState struct {
-	Message string `json:"errorMessage"`
-	Code    string `json:"errorCode"`
+	Message string      `json:"errorMessage"`
+	Code    string      `json:"errorCode"`
+	Details interface{} `json:"details,omitempty"`
 }
 
+// APIError is an error that maps to a specific HTTP status. It may also
+// implement ErrorCoder and ErrorDetailer to refine what's reported.
 type APIError interface {
 	error
 	Code() int
 }
 
 func EncodeSuccess(c *gin.Context, statusCode int, result interface{}) {
-	resp := APIResponse{
-		Result: result,
-		Meta: meta{
-			RequestID:  c.Writer.Header().Get("X-Request-ID"),
-			HTTPStatus: http.StatusText(statusCode),
-			Error:      nil,
-		},
-	}
-	c.JSON(statusCode, resp)
+	encode(c, statusCode, result, meta{})
+}
+
+// EncodePage writes one page of a listing, nextCursor is empty on the last page
+func EncodePage(c *gin.Context, statusCode int, result interface{}, nextCursor string) {
+	encode(c, statusCode, result, meta{NextCursor: nextCursor})
 }
 
+// EncodeError writes err in the APIResponse envelope. Only the first response
+// of a request is written, later calls are logged and dropped.
 func EncodeError(c *gin.Context, err error) {
+	if e, ok := err.(TooManyRequestsError); ok && !c.Writer.Written() {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
 	if e, ok := err.(APIError); ok {
 		logger.Error(c, "returning handled error: %s (code: %d)", err.Error(), e.Code())
-		resp := APIResponse{
-			Result: nil,
-			Meta: meta{
-				RequestID:  c.Writer.Header().Get("X-Request-ID"),
-				HTTPStatus: http.StatusText(e.Code()),
-				Error: &errorState{
-					Message: e.Error(),
-				},
-			},
+		state := &errorState{
+			Message: e.Error(),
+			Code:    ErrorCode(err, e.Code()),
 		}
-		c.JSON(e.Code(), resp)
+		if d, ok := err.(ErrorDetailer); ok {
+			state.Details = d.ErrorDetails()
+		}
+		encode(c, e.Code(), nil, meta{Error: state})
 		return
 	}
 	logger.Error(c, "unhandled error: %s", err.Error())
-	resp := APIResponse{
-		Result: nil,
-		Meta: meta{
-			RequestID:  c.Writer.Header().Get("X-Request-ID"),
-			HTTPStatus: http.StatusText(http.StatusInternalServerError),
-			Error: &errorState{
-				Message: "internal service error",
-			},
-		},
+	encode(c, http.StatusInternalServerError, nil, meta{Error: &errorState{
+		Message: "internal service error",
+		Code:    CodeInternal,
+	}})
+}
+
+func encode(c *gin.Context, statusCode int, result interface{}, m meta) {
+	if c.Writer.Written() {
+		logger.Error(c, "dropping %d response, one was already written for this request", statusCode)
+		return
+	}
+	m.RequestID = requestID(c)
+	m.HTTPStatus = http.StatusText(statusCode)
+	c.JSON(statusCode, APIResponse{Result: result, Meta: m})
+}
+
+// requestID prefers the ID set by middlewares.AttachRequestIDs, the header is
+// kept as a fallback for engines that set it some other way
+func requestID(c *gin.Context) string {
+	if id := c.GetString(requestid.KeyRequestID); id != "" {
+		return id
 	}
-	c.JSON(http.StatusInternalServerError, resp)
+	return c.Writer.Header().Get(requestid.HeaderRequestID)
 }
 
+// NoRoute answers requests that don't match any route
+func NoRoute(c *gin.Context) {
+	EncodeError(c, RouteNotFoundError{Method: c.Request.Method, Path: c.Request.URL.Path})
+}
+
+// NoMethod answers requests to a known path with a method it doesn't support,
+// the engine must have HandleMethodNotAllowed enabled
+func NoMethod(c *gin.Context) {
+	EncodeError(c, MethodNotAllowedError{Method: c.Request.Method, Path: c.Request.URL.Path})
+}
+
+// Healthcheck is the liveness probe, it only reports that the process is able
+// to serve requests. Dependencies are checked by the readiness probe.
 func Healthcheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
 // https://www.twilio.com/docs/sms/send-messages
-// this is the StatusCallbackURL
-func SentSMSStatus(c *gin.Context) {
-	bodyReader, err := c.Request.GetBody()
-	if err != nil {
-		logger.Error(c, "unable to read twilio message status: %v", err)
-		EncodeError(c, err)
-		return
+// this is the StatusCallbackURL, the request must already be verified with
+// middlewares.VerifyTwilioSignature
+func SentSMSStatus(store twilio.DeliveryStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := twilio.ParseStatusCallback(c.Request.PostForm)
+		if err != nil {
+			logger.Error(c, "unable to read twilio message status: %v", err)
+			EncodeError(c, MalformedBodyError{})
+			return
+		}
+		saved, err := store.Save(status)
+		if err != nil {
+			logger.Error(c, "unable to save twilio message status: %v", err)
+			EncodeError(c, err)
+			return
+		}
+		logger.Info(c, "twilio sms %s status %s (error code: '%s', notification: '%s')", saved.MessageSID, saved.Status, saved.ErrorCode, saved.NotificationID)
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	}
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(bodyReader)
-	logger.Info(c, "twilio sms status response: %v", buf.String())
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
 type MissingPathParamError struct {
@@ -122,9 +163,198 @@ type MalformedBodyError struct {
 }
 
 func (e MalformedBodyError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("malformed request body: field '%s' has the wrong type", e.Field)
+	}
 	return "malformed request body"
 }
 
 func (e MalformedBodyError) Code() int {
 	return http.StatusBadRequest
 }
+
+type UnauthorizedError struct {
+	Reason string
+}
+
+func (e UnauthorizedError) Error() string {
+	if e.Reason == "" {
+		return "not authorized"
+	}
+	return fmt.Sprintf("not authorized: %s", e.Reason)
+}
+
+func (e UnauthorizedError) Code() int {
+	return http.StatusUnauthorized
+}
+
+type ForbiddenError struct {
+	Action string
+}
+
+func (e ForbiddenError) Error() string {
+	if e.Action == "" {
+		return "forbidden"
+	}
+	return fmt.Sprintf("not allowed to %s", e.Action)
+}
+
+func (e ForbiddenError) Code() int {
+	return http.StatusForbidden
+}
+
+type InvalidBodyFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e InvalidBodyFieldError) Error() string {
+	return fmt.Sprintf("invalid body field '%s': %s", e.Field, e.Reason)
+}
+
+func (e InvalidBodyFieldError) Code() int {
+	return http.StatusBadRequest
+}
+
+type TooManyRequestsError struct {
+	RetryAfter time.Duration
+}
+
+func (e TooManyRequestsError) Error() string {
+	return "too many requests, try again later"
+}
+
+func (e TooManyRequestsError) Code() int {
+	return http.StatusTooManyRequests
+}
+
+type RouteNotFoundError struct {
+	Method string
+	Path   string
+}
+
+func (e RouteNotFoundError) Error() string {
+	return fmt.Sprintf("no route for %s %s", e.Method, e.Path)
+}
+
+func (e RouteNotFoundError) Code() int {
+	return http.StatusNotFound
+}
+
+type MethodNotAllowedError struct {
+	Method string
+	Path   string
+}
+
+func (e MethodNotAllowedError) Error() string {
+	return fmt.Sprintf("method %s is not allowed on %s", e.Method, e.Path)
+}
+
+func (e MethodNotAllowedError) Code() int {
+	return http.StatusMethodNotAllowed
+}
+
+// RequestInProgressError is returned to a retry that arrives while the first
+// request with the same Idempotency-Key is still being handled
+type RequestInProgressError struct{}
+
+func (e RequestInProgressError) Error() string {
+	return "a request with this Idempotency-Key is still in progress, retry later"
+}
+
+func (e RequestInProgressError) Code() int {
+	return http.StatusConflict
+}
+
+// IdempotencyKeyReusedError is returned when a key is sent again with a
+// different request body
+type IdempotencyKeyReusedError struct{}
+
+func (e IdempotencyKeyReusedError) Error() string {
+	return "this Idempotency-Key was already used for a different request"
+}
+
+func (e IdempotencyKeyReusedError) Code() int {
+	return http.StatusUnprocessableEntity
+}
+
+// PreconditionFailedError is returned when the If-Match of a write no longer
+// matches the resource, i.e. someone else changed it in the meantime
+type PreconditionFailedError struct {
+	Resource string
+}
+
+func (e PreconditionFailedError) Error() string {
+	return fmt.Sprintf("%s was modified since it was retrieved, fetch it again and retry", e.Resource)
+}
+
+func (e PreconditionFailedError) Code() int {
+	return http.StatusPreconditionFailed
+}
+
+// EventFullError is returned when a member can't be going because the event
+// has reached its capacity and there is no waitlist to put them on
+type EventFullError struct {
+	Capacity int
+}
+
+func (e EventFullError) Error() string {
+	return fmt.Sprintf("the event is full, it has a capacity of %d", e.Capacity)
+}
+
+func (e EventFullError) Code() int {
+	return http.StatusConflict
+}
+
+// RSVPClosedError is returned for RSVPs after the deadline set by the hosts
+type RSVPClosedError struct {
+	Deadline time.Time
+}
+
+func (e RSVPClosedError) Error() string {
+	return fmt.Sprintf("RSVPs closed at %s", e.Deadline.Format(time.RFC3339))
+}
+
+func (e RSVPClosedError) Code() int {
+	return http.StatusConflict
+}
+
+// ConflictError is returned when a write kept losing to other writes of the
+// same resource, the client should retry it
+type ConflictError struct {
+	Resource string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("the %s was changed by another request, retry", e.Resource)
+}
+
+func (e ConflictError) Code() int {
+	return http.StatusConflict
+}
+
+// PayloadTooLargeError is returned for request bodies over the configured limit
+type PayloadTooLargeError struct {
+	Limit int
+}
+
+func (e PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("request body is larger than %d bytes", e.Limit)
+}
+
+func (e PayloadTooLargeError) Code() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+type UnsupportedMediaTypeError struct {
+	ContentType string
+	Supported   []string
+}
+
+func (e UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported Content-Type '%s', use one of %s", e.ContentType, strings.Join(e.Supported, ", "))
+}
+
+func (e UnsupportedMediaTypeError) Code() int {
+	return http.StatusUnsupportedMediaType
+}
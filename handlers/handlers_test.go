@@ -2,11 +2,14 @@ package handlers_test
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/twilio"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,6 +28,140 @@ func TestHealthcheck(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestSentSMSStatus(t *testing.T) {
+	store := twilio.NewMemoryDeliveryStore()
+	store.Track("SM_Mock", "NTF_Mock", "mockUserId")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/sms-status", nil)
+	c.Request.PostForm = url.Values{
+		"MessageSid":    {"SM_Mock"},
+		"To":            {"+15005550006"},
+		"MessageStatus": {"delivered"},
+	}
+	handlers.SentSMSStatus(store)(c)
+	assert.Equal(t, 200, w.Code)
+	status, err := store.Get("SM_Mock")
+	assert.NoError(t, err)
+	assert.Equal(t, "NTF_Mock", status.NotificationID)
+	assert.Equal(t, "delivered", status.Status)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/sms-status", nil)
+	c.Request.PostForm = url.Values{"To": {"+15005550006"}}
+	handlers.SentSMSStatus(store)(c)
+	assert.Equal(t, 400, w.Code)
+}
+
 func TestGetEvent(t *testing.T) {
 	t.Skip()
 }
+
+func TestEncodeErrorCodes(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Err     error
+		Status  int
+		Code    string
+		Details string
+	}{
+		{
+			Name:   "typed error has its own code",
+			Err:    handlers.MissingPathParamError{Param: "eventId"},
+			Status: http.StatusBadRequest,
+			Code:   handlers.CodeMissingPathParam,
+		},
+		{
+			Name:   "not found is scoped to the resource",
+			Err:    handlers.NotFound("event", handlers.NotFoundError{}),
+			Status: http.StatusNotFound,
+			Code:   "EVENT_NOT_FOUND",
+		},
+		{
+			Name: "validation errors list every field",
+			Err: handlers.ValidationError{Violations: []handlers.FieldViolation{
+				{Field: "name", Rule: "required", Message: "is required"},
+			}},
+			Status:  http.StatusBadRequest,
+			Code:    handlers.CodeValidationFailed,
+			Details: `[{"field": "name", "rule": "required", "message": "is required"}]`,
+		},
+		{
+			Name:   "uncaught errors are internal",
+			Err:    assert.AnError,
+			Status: http.StatusInternalServerError,
+			Code:   handlers.CodeInternal,
+		},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		handlers.EncodeError(c, tc.Err)
+		assert.Equal(t, tc.Status, w.Code, tc.Name)
+		var got struct {
+			Meta struct {
+				Error struct {
+					Code    string          `json:"errorCode"`
+					Details json.RawMessage `json:"details"`
+				} `json:"error"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, tc.Code, got.Meta.Error.Code, tc.Name)
+		if tc.Details != "" {
+			assert.JSONEq(t, tc.Details, string(got.Meta.Error.Details), tc.Name)
+		} else {
+			assert.Empty(t, got.Meta.Error.Details, tc.Name)
+		}
+	}
+}
+
+func TestUnmatchedRoutes(t *testing.T) {
+	app := gin.New()
+	app.HandleMethodNotAllowed = true
+	app.NoRoute(handlers.NoRoute)
+	app.NoMethod(handlers.NoMethod)
+	app.GET("/v1/events", func(c *gin.Context) {})
+
+	cases := []struct {
+		Method string
+		Path   string
+		Status int
+		Code   string
+	}{
+		{Method: http.MethodGet, Path: "/v1/nope", Status: http.StatusNotFound, Code: handlers.CodeNotFound},
+		{Method: http.MethodPatch, Path: "/v1/events", Status: http.StatusMethodNotAllowed, Code: handlers.CodeMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+		assert.Equal(t, tc.Status, w.Code)
+		var got handlers.APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, tc.Code, got.Meta.Error.Code)
+	}
+}
+
+func TestEncodeWritesOnce(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Set("requestId", "REQ_mock")
+	handlers.EncodeError(c, handlers.MissingBodyFieldError{Field: "name"})
+	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"id": "EVT_mock"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var got handlers.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "REQ_mock", got.Meta.RequestID)
+	assert.Nil(t, got.Result)
+	assert.Equal(t, handlers.CodeMissingBodyField, got.Meta.Error.Code)
+}
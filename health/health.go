@@ -0,0 +1,94 @@
+// Package health runs dependency probes for the readiness endpoint.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Check probes a single dependency. The service can't serve traffic without
+// a critical dependency, other failures only degrade it.
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    func(ctx context.Context) error
+}
+
+type Result struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Run probes every dependency concurrently, each bounded by timeout, and
+// returns the overall status along with each dependency's result
+func Run(ctx context.Context, timeout time.Duration, checks []Check) (string, map[string]Result) {
+	results := make(map[string]Result, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := check.Probe(probeCtx)
+			result := Result{
+				Status:    StatusOK,
+				Critical:  check.Critical,
+				LatencyMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = StatusUnavailable
+				result.Error = err.Error()
+			}
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	status := StatusOK
+	for _, result := range results {
+		if result.Status == StatusOK {
+			continue
+		}
+		if result.Critical {
+			return StatusUnavailable, results
+		}
+		status = StatusDegraded
+	}
+	return status, results
+}
+
+// HTTPProbe checks that url answers, any response below 500 means the
+// provider is reachable even if it wants credentials we didn't send
+func HTTPProbe(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s returned %d", url, res.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func probe(err error) func(context.Context) error {
+	return func(context.Context) error { return err }
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Checks []health.Check
+		Status string
+	}{
+		{
+			Name: "all dependencies up",
+			Checks: []health.Check{
+				{Name: "database", Critical: true, Probe: probe(nil)},
+				{Name: "twilio", Probe: probe(nil)},
+			},
+			Status: health.StatusOK,
+		},
+		{
+			Name: "optional dependency down",
+			Checks: []health.Check{
+				{Name: "database", Critical: true, Probe: probe(nil)},
+				{Name: "twilio", Probe: probe(errors.New("timeout"))},
+			},
+			Status: health.StatusDegraded,
+		},
+		{
+			Name: "critical dependency down",
+			Checks: []health.Check{
+				{Name: "database", Critical: true, Probe: probe(errors.New("no reachable servers"))},
+				{Name: "twilio", Probe: probe(errors.New("timeout"))},
+			},
+			Status: health.StatusUnavailable,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		status, results := health.Run(context.Background(), time.Second, c.Checks)
+		assert.Equal(t, c.Status, status)
+		assert.Len(t, results, len(c.Checks))
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	status, results := health.Run(context.Background(), 10*time.Millisecond, []health.Check{
+		{Name: "s3", Critical: true, Probe: slow},
+	})
+	assert.Equal(t, health.StatusUnavailable, status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), results["s3"].Error)
+}
+
+func TestHTTPProbe(t *testing.T) {
+	status := http.StatusUnauthorized
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	check := health.HTTPProbe(srv.Client(), srv.URL)
+	assert.NoError(t, check(context.Background()))
+	status = http.StatusServiceUnavailable
+	assert.Error(t, check(context.Background()))
+}
@@ -0,0 +1,244 @@
+// Package ical writes iCalendar (RFC 5545) documents so kickbacks can be
+// added to, or subscribed to from, Google, Apple and Outlook calendars.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const ContentType = "text/calendar; charset=utf-8"
+
+// participation statuses of attendees
+const (
+	PartStatNeedsAction = "NEEDS-ACTION"
+	PartStatAccepted    = "ACCEPTED"
+	PartStatDeclined    = "DECLINED"
+	PartStatTentative   = "TENTATIVE"
+)
+
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// lines are folded after this many octets, not counting the CRLF
+const maxLineOctets = 75
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+)
+
+type Calendar struct {
+	ProdID string
+	// Name is shown by calendar apps for subscribed calendars
+	Name string
+	// RefreshInterval suggests how often subscribers poll for changes
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Address is an organizer or attendee, URI is a mailto:, tel: or other URI
+// identifying them
+type Address struct {
+	Name string
+	URI  string
+}
+
+// Attendee is an event member, UserID identifies them to the caller and isn't
+// written to the calendar
+type Attendee struct {
+	Address
+	UserID   string
+	PartStat string
+}
+
+// Event is a VEVENT. Times outside of UTC are written in their location,
+// along with a VTIMEZONE describing it, so recurring events keep their wall
+// clock time across daylight saving changes. An event with a RecurrenceID
+// overrides a single occurrence of the recurring event with the same UID.
+type Event struct {
+	UID          string
+	Stamp        time.Time
+	Start        time.Time
+	End          time.Time
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time
+	Summary      string
+	Description  string
+	Status       string
+	Organizer    *Address
+	Attendees    []Attendee
+}
+
+// Encode writes the calendar, lines are folded and end in CRLF
+func (cal Calendar) Encode() []byte {
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.prop("PRODID", cal.ProdID)
+	w.line("CALSCALE:GREGORIAN")
+	if cal.Name != "" {
+		w.prop("X-WR-CALNAME", cal.Name)
+	}
+	if cal.RefreshInterval > 0 {
+		w.line("REFRESH-INTERVAL;VALUE=DURATION:" + duration(cal.RefreshInterval))
+		w.line("X-PUBLISHED-TTL:" + duration(cal.RefreshInterval))
+	}
+	for _, tz := range cal.timeZones() {
+		tz.encode(w)
+	}
+	for _, e := range cal.Events {
+		e.encode(w)
+	}
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+// timeZones describes every location the events are in, over the years they
+// span
+func (cal Calendar) timeZones() []timeZone {
+	spans := map[string]*timeZone{}
+	for _, e := range cal.Events {
+		for _, t := range []time.Time{e.Start, e.End, e.RecurrenceID} {
+			if t.IsZero() || isUTC(t.Location()) {
+				continue
+			}
+			name := t.Location().String()
+			tz, ok := spans[name]
+			if !ok {
+				tz = &timeZone{loc: t.Location(), from: t, to: t}
+				spans[name] = tz
+			}
+			if t.Before(tz.from) {
+				tz.from = t
+			}
+			if t.After(tz.to) {
+				tz.to = t
+			}
+			if e.RRule != "" && e.Start.Location() == t.Location() {
+				// recurring events usually run for a while, cover the next years too
+				if later := t.AddDate(recurringYears, 0, 0); later.After(tz.to) {
+					tz.to = later
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(spans))
+	for name := range spans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	zones := make([]timeZone, 0, len(names))
+	for _, name := range names {
+		zones = append(zones, *spans[name])
+	}
+	return zones
+}
+
+func (e Event) encode(w *writer) {
+	w.line("BEGIN:VEVENT")
+	w.prop("UID", e.UID)
+	w.line("DTSTAMP:" + e.Stamp.UTC().Format(utcLayout))
+	if !e.RecurrenceID.IsZero() {
+		w.line(dateTime("RECURRENCE-ID", e.RecurrenceID))
+	}
+	w.line(dateTime("DTSTART", e.Start))
+	w.line(dateTime("DTEND", e.End))
+	if e.RRule != "" {
+		w.line("RRULE:" + strings.TrimPrefix(e.RRule, "RRULE:"))
+	}
+	for _, exDate := range e.ExDates {
+		w.line(dateTime("EXDATE", exDate))
+	}
+	w.prop("SUMMARY", e.Summary)
+	if e.Description != "" {
+		w.prop("DESCRIPTION", e.Description)
+	}
+	if e.Status != "" {
+		w.line("STATUS:" + e.Status)
+	}
+	if e.Organizer != nil {
+		w.line("ORGANIZER" + cn(e.Organizer.Name) + ":" + e.Organizer.URI)
+	}
+	for _, a := range e.Attendees {
+		partStat := a.PartStat
+		if partStat == "" {
+			partStat = PartStatNeedsAction
+		}
+		w.line("ATTENDEE" + cn(a.Name) + ";ROLE=REQ-PARTICIPANT;PARTSTAT=" + partStat + ":" + a.URI)
+	}
+	w.line("END:VEVENT")
+}
+
+func isUTC(loc *time.Location) bool {
+	return loc == time.UTC || loc.String() == "UTC"
+}
+
+// dateTime formats a DATE-TIME property, in UTC or with the TZID of t
+func dateTime(name string, t time.Time) string {
+	if isUTC(t.Location()) {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	return name + ";TZID=" + t.Location().String() + ":" + t.Format(localLayout)
+}
+
+// duration formats d as a DURATION value, e.g. PT1H
+func duration(d time.Duration) string {
+	s := "PT"
+	if h := int(d.Hours()); h > 0 {
+		s += fmt.Sprintf("%dH", h)
+	}
+	if m := int(d.Minutes()) % 60; m > 0 {
+		s += fmt.Sprintf("%dM", m)
+	}
+	if s == "PT" {
+		s += fmt.Sprintf("%dS", int(d.Seconds()))
+	}
+	return s
+}
+
+// cn is the common name parameter, quoted since names may contain : ; or ,
+func cn(name string) string {
+	name = strings.NewReplacer(`"`, "", "\r", "", "\n", " ").Replace(name)
+	if strings.TrimSpace(name) == "" {
+		return ""
+	}
+	return `;CN="` + name + `"`
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+// prop writes a property with a TEXT value
+func (w *writer) prop(name, value string) {
+	w.line(name + ":" + textEscaper.Replace(value))
+}
+
+// line writes a content line, folded so no line is longer than 75 octets
+// without splitting UTF-8 characters
+func (w *writer) line(s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut])
+		w.buf.WriteString("\r\n ")
+		s = s[cut:]
+		// the leading space of continuation lines counts towards the limit
+		limit = maxLineOctets - 1
+	}
+	w.buf.WriteString(s)
+	w.buf.WriteString("\r\n")
+}
@@ -0,0 +1,104 @@
+package ical_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, time.October, 16, 20, 0, 0, 0, ny)
+	cal := ical.Calendar{
+		ProdID:          "-//Kickback//API//EN",
+		Name:            "Kickbacks",
+		RefreshInterval: time.Hour,
+		Events: []ical.Event{
+			{
+				UID:         "EVT_1@kickbackapp.io",
+				Stamp:       time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC),
+				Start:       start,
+				End:         start.Add(3 * time.Hour),
+				RRule:       "FREQ=WEEKLY;COUNT=4",
+				ExDates:     []time.Time{start.AddDate(0, 0, 7)},
+				Summary:     "Game night; bring snacks, drinks",
+				Description: "line one\nline two",
+				Organizer:   &ical.Address{Name: "Ada \"The Host\" Lovelace", URI: "mailto:ada@example.com"},
+				Attendees: []ical.Attendee{
+					{Address: ical.Address{Name: "Grace Hopper", URI: "tel:+15555550100"}, PartStat: ical.PartStatAccepted},
+					{Address: ical.Address{URI: "tel:+15555550101"}},
+				},
+			},
+			{
+				UID:          "EVT_1@kickbackapp.io",
+				Stamp:        time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC),
+				RecurrenceID: start.AddDate(0, 0, 14),
+				Start:        start.AddDate(0, 0, 15),
+				End:          start.AddDate(0, 0, 15).Add(3 * time.Hour),
+				Summary:      "Game night, moved to saturday",
+			},
+		},
+	}
+	expected := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Kickback//API//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:Kickbacks",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+		"BEGIN:VTIMEZONE",
+		"TZID:America/New_York",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20261015T200000",
+		"TZOFFSETFROM:-0400",
+		"TZOFFSETTO:-0400",
+		"TZNAME:EDT",
+		"END:DAYLIGHT",
+	}, "\r\n") + "\r\n"
+	out := string(cal.Encode())
+	assert.True(t, strings.HasPrefix(out, expected), out)
+	// the first change to standard time, and the ones in the following years
+	assert.Contains(t, out, "BEGIN:STANDARD\r\nDTSTART:20261101T020000\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nTZNAME:EST\r\nEND:STANDARD\r\n")
+	assert.Contains(t, out, "BEGIN:DAYLIGHT\r\nDTSTART:20310309T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\nEND:DAYLIGHT\r\n")
+	assert.Contains(t, out, strings.Join([]string{
+		"BEGIN:VEVENT",
+		"UID:EVT_1@kickbackapp.io",
+		"DTSTAMP:20261001T120000Z",
+		"DTSTART;TZID=America/New_York:20261016T200000",
+		"DTEND;TZID=America/New_York:20261016T230000",
+		"RRULE:FREQ=WEEKLY;COUNT=4",
+		"EXDATE;TZID=America/New_York:20261023T200000",
+		`SUMMARY:Game night\; bring snacks\, drinks`,
+		`DESCRIPTION:line one\nline two`,
+		`ORGANIZER;CN="Ada The Host Lovelace":mailto:ada@example.com`,
+		`ATTENDEE;CN="Grace Hopper";ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED:tel:+1555`,
+		" 5550100",
+		"ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION:tel:+15555550101",
+		"END:VEVENT",
+	}, "\r\n"))
+	assert.Contains(t, out, "RECURRENCE-ID;TZID=America/New_York:20261030T200000\r\nDTSTART;TZID=America/New_York:20261031T200000\r\n")
+	assert.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+}
+
+func TestFolding(t *testing.T) {
+	cal := ical.Calendar{ProdID: "-//Kickback//API//EN", Events: []ical.Event{{
+		UID:     "EVT_1",
+		Start:   time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC),
+		End:     time.Date(2026, time.October, 16, 23, 0, 0, 0, time.UTC),
+		Summary: strings.Repeat("é", 100),
+	}}}
+	out := string(cal.Encode())
+	assert.NotContains(t, out, "VTIMEZONE", "utc times don't need one")
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+		assert.True(t, strings.ToValidUTF8(line, "?") == line, "characters aren't split")
+	}
+	assert.Contains(t, strings.ReplaceAll(out, "\r\n ", ""), "SUMMARY:"+strings.Repeat("é", 100)+"\r\n")
+}
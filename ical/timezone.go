@@ -0,0 +1,94 @@
+package ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// years of daylight saving changes described for the location of a recurring
+// event, calendar apps that know the TZID use their own rules anyway
+const recurringYears = 5
+
+// timeZone is the VTIMEZONE of loc, covering the changes in offset between
+// from and to
+type timeZone struct {
+	loc  *time.Location
+	from time.Time
+	to   time.Time
+}
+
+type transition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+}
+
+// transitions finds the changes in offset by checking the offset every day
+// and narrowing down to the second it changed at
+func (tz timeZone) transitions() []transition {
+	var found []transition
+	prev := tz.from.AddDate(0, 0, -1).In(tz.loc)
+	_, prevOffset := prev.Zone()
+	for t := prev.Add(24 * time.Hour); !t.After(tz.to.AddDate(0, 0, 1)); t = t.Add(24 * time.Hour) {
+		_, offset := t.Zone()
+		if offset != prevOffset {
+			lo, hi := t.Add(-24*time.Hour), t
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.Zone(); o == prevOffset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			found = append(found, transition{at: hi, offsetFrom: prevOffset, offsetTo: offset})
+			prevOffset = offset
+		}
+	}
+	return found
+}
+
+func (tz timeZone) encode(w *writer) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + tz.loc.String())
+	// the offset in effect from before the first event, then every change
+	start := tz.from.AddDate(0, 0, -1).In(tz.loc)
+	name, offset := start.Zone()
+	observance(w, kind(start), name, start, offset, offset)
+	for _, t := range tz.transitions() {
+		local := t.at.In(tz.loc)
+		name, _ := local.Zone()
+		observance(w, kind(local), name, t.at, t.offsetFrom, t.offsetTo)
+	}
+	w.line("END:VTIMEZONE")
+}
+
+func kind(t time.Time) string {
+	if t.IsDST() {
+		return "DAYLIGHT"
+	}
+	return "STANDARD"
+}
+
+// observance writes a STANDARD or DAYLIGHT component starting at the instant
+// at, its DTSTART is the wall clock time before the change
+func observance(w *writer, kind, name string, at time.Time, offsetFrom, offsetTo int) {
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + at.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(localLayout))
+	w.line("TZOFFSETFROM:" + utcOffset(offsetFrom))
+	w.line("TZOFFSETTO:" + utcOffset(offsetTo))
+	if name != "" {
+		w.line("TZNAME:" + name)
+	}
+	w.line("END:" + kind)
+}
+
+// utcOffset formats seconds east of UTC as +hhmm
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
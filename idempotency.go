@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/idempotency"
+	"github.com/kickback-app/api/utils"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotent replays the first response to POST requests sent with an
+// Idempotency-Key, so a client retrying after a dropped connection doesn't
+// create the event, expense or invite SMS twice. Keys are scoped to the user
+// and the path they were sent to. Requests without the header are untouched.
+func (s S) Idempotent(c *gin.Context) {
+	key := c.GetHeader(idempotency.Header)
+	if key == "" || c.Request.Method != http.MethodPost || s.Idempotency == nil {
+		c.Next()
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		handlers.EncodeError(c, handlers.InvalidBodyFieldError{Field: idempotency.Header, Reason: "must be at most 255 characters"})
+		c.Abort()
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		c.Abort()
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	storeKey := strings.Join([]string{utils.CurrentUser(c).ID, c.Request.URL.Path, key}, " ")
+	record, reserved, err := s.Idempotency.Begin(storeKey, idempotency.Fingerprint(body), s.config.HTTP.IdempotencyKeyTTL)
+	if err != nil {
+		// same as the rate limiter, an outage of the store shouldn't take the API down with it
+		logger.Error(c, "idempotency store unavailable: %v", err)
+		c.Next()
+		return
+	}
+	if !reserved {
+		replayIdempotent(c, record, idempotency.Fingerprint(body))
+		c.Abort()
+		return
+	}
+
+	// the headers set before, e.g. the request ID, belong to this request
+	before := c.Writer.Header().Clone()
+	rec := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = rec
+	completed := false
+	defer func() {
+		// the handler panicked, let the client retry once the panic is answered
+		if !completed {
+			s.releaseIdempotencyKey(c, storeKey)
+		}
+	}()
+	c.Next()
+	completed = true
+	status := c.Writer.Status()
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		s.releaseIdempotencyKey(c, storeKey)
+		return
+	}
+	if err := s.Idempotency.Complete(storeKey, status, headersSet(before, c.Writer.Header()), rec.body.Bytes()); err != nil {
+		logger.Error(c, "unable to save response for idempotency key: %v", err)
+	}
+}
+
+func replayIdempotent(c *gin.Context, record idempotency.Record, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		handlers.EncodeError(c, handlers.IdempotencyKeyReusedError{})
+		return
+	}
+	if record.Pending {
+		handlers.EncodeError(c, handlers.RequestInProgressError{})
+		return
+	}
+	logger.Info(c, "replaying %d response for idempotency key", record.Status)
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(idempotency.HeaderReplayed, "true")
+	c.Data(record.Status, record.Header.Get("Content-Type"), record.Body)
+}
+
+// headersSet returns the headers of after that weren't in before
+func headersSet(before, after http.Header) http.Header {
+	set := http.Header{}
+	for name, values := range after {
+		if !reflect.DeepEqual(before[name], values) {
+			set[name] = values
+		}
+	}
+	return set
+}
+
+func (s S) releaseIdempotencyKey(c *gin.Context, key string) {
+	if err := s.Idempotency.Release(key); err != nil {
+		logger.Error(c, "unable to release idempotency key: %v", err)
+	}
+}
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,46 @@
+// Package idempotency remembers the response to requests sent with an
+// Idempotency-Key so that client retries replay it instead of creating
+// duplicates.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+const Header = "Idempotency-Key"
+
+// HeaderReplayed is set on responses that were replayed from the store
+const HeaderReplayed = "Idempotent-Replayed"
+
+// Record is what's kept for a key. It is pending while the first request with
+// the key is being handled.
+type Record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Pending     bool        `json:"pending"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// Store keeps idempotency records. DBStore shares them between instances, so
+// a retry that lands on another instance is replayed too.
+type Store interface {
+	// Begin reserves key for a request whose body has fingerprint. When the
+	// key is already in use it returns the existing record and false.
+	Begin(key, fingerprint string, ttl time.Duration) (Record, bool, error)
+	// Complete saves the response to the request that reserved key, header
+	// holds the headers the handler set, e.g. ETag and Location
+	Complete(key string, status int, header http.Header, body []byte) error
+	// Release forgets key so the request can be retried, e.g. after it failed
+	Release(key string) error
+}
+
+// Fingerprint identifies a request body, reusing a key with a different body
+// is a client bug
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+package idempotency
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+// record is how a Record is stored, until the key can be used again
+type record struct {
+	Record
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (r record) Expires() time.Time {
+	return r.ExpiresAt
+}
+
+// DBStore keeps the records in the database, shared by every instance
+type DBStore struct {
+	docs docstore.Collection
+	now  func() time.Time
+}
+
+func NewDBStore(docs docstore.Collection) *DBStore {
+	return &DBStore{docs: docs, now: time.Now}
+}
+
+// NewMemoryStore keeps the records in memory, for tests and single instance
+// deployments
+func NewMemoryStore() *DBStore {
+	return NewDBStore(docstore.NewMemory().Collection("idempotency_keys"))
+}
+
+func (d *DBStore) Begin(key, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	var existing Record
+	var reserved bool
+	err := docstore.Retry(func() error {
+		now := d.now()
+		var stored record
+		version, err := d.docs.Get(key, &stored)
+		if err != nil && err != docstore.ErrNotFound {
+			return err
+		}
+		if err == nil && now.Before(stored.ExpiresAt) {
+			existing, reserved = stored.Record, false
+			return nil
+		}
+		existing, reserved = Record{Fingerprint: fingerprint, Pending: true}, true
+		_, err = d.docs.Put(key, version, record{Record: existing, ExpiresAt: now.Add(ttl)})
+		return err
+	})
+	if err != nil {
+		return Record{}, false, err
+	}
+	return existing, reserved, nil
+}
+
+func (d *DBStore) Complete(key string, status int, header http.Header, body []byte) error {
+	return docstore.Retry(func() error {
+		var stored record
+		version, err := d.docs.Get(key, &stored)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		stored.Pending = false
+		stored.Status = status
+		stored.Header = header
+		stored.Body = body
+		_, err = d.docs.Put(key, version, stored)
+		return err
+	})
+}
+
+func (d *DBStore) Release(key string) error {
+	return docstore.Retry(func() error {
+		var stored record
+		version, err := d.docs.Get(key, &stored)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.docs.Delete(key, version)
+	})
+}
@@ -0,0 +1,77 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+// newTestStore starts the clock at the wall time, the records expire by it
+func newTestStore() (*DBStore, *fakeClock) {
+	clock := &fakeClock{t: time.Now()}
+	store := NewMemoryStore()
+	store.now = clock.now
+	return store, clock
+}
+
+func TestBeginAndComplete(t *testing.T) {
+	store, clock := newTestStore()
+	fingerprint := Fingerprint([]byte(`{"name": "mock event"}`))
+
+	record, reserved, err := store.Begin("key", fingerprint, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.True(t, record.Pending)
+
+	// a retry while the first request is running sees it pending
+	record, reserved, _ = store.Begin("key", fingerprint, time.Hour)
+	assert.False(t, reserved)
+	assert.True(t, record.Pending)
+
+	header := http.Header{"Content-Type": {"application/json"}, "Etag": {`"abc"`}}
+	assert.NoError(t, store.Complete("key", 200, header, []byte(`{"result": {}}`)))
+	record, reserved, _ = store.Begin("key", Fingerprint([]byte("other body")), time.Hour)
+	assert.False(t, reserved)
+	assert.False(t, record.Pending)
+	assert.Equal(t, fingerprint, record.Fingerprint)
+	assert.Equal(t, 200, record.Status)
+	assert.Equal(t, header, record.Header)
+	assert.Equal(t, `{"result": {}}`, string(record.Body))
+
+	// keys can be used again once they expire
+	clock.t = clock.t.Add(time.Hour)
+	_, reserved, _ = store.Begin("key", fingerprint, time.Hour)
+	assert.True(t, reserved)
+}
+
+func TestRelease(t *testing.T) {
+	store, _ := newTestStore()
+	_, reserved, _ := store.Begin("key", "abc", time.Hour)
+	assert.True(t, reserved)
+	assert.NoError(t, store.Release("key"))
+	_, reserved, _ = store.Begin("key", "abc", time.Hour)
+	assert.True(t, reserved)
+}
+
+func TestInstancesShareKeys(t *testing.T) {
+	docs := docstore.NewMemory().Collection("idempotency_keys")
+	first, second := NewDBStore(docs), NewDBStore(docs)
+	_, reserved, _ := first.Begin("key", "abc", time.Hour)
+	assert.True(t, reserved)
+	first.Complete("key", 201, http.Header{"Location": {"/v1/events/EVT_1"}}, []byte(`{}`))
+
+	record, reserved, err := second.Begin("key", "abc", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, 201, record.Status)
+	assert.Equal(t, "/v1/events/EVT_1", record.Header.Get("Location"))
+}
@@ -0,0 +1,52 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/config"
+	"github.com/kickback-app/api/server/idempotency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotentReplaysHeaders(t *testing.T) {
+	s, err := server.New(config.Default(), gin.New(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := 0
+	app := gin.New()
+	app.POST("/v1/events", func(c *gin.Context) {
+		c.Set("userId", "mockUserId")
+		c.Header("X-Request-ID", "request-"+c.GetHeader("X-Attempt"))
+	}, s.Idempotent, func(c *gin.Context) {
+		created++
+		c.Header("Location", "/v1/events/EVT_1")
+		c.Header("ETag", `"v1"`)
+		c.JSON(http.StatusCreated, gin.H{"result": gin.H{"_id": "EVT_1"}})
+	})
+
+	send := func(attempt string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(`{"name": "mock event"}`))
+		r.Header.Set(idempotency.Header, "key-1")
+		r.Header.Set("X-Attempt", attempt)
+		app.ServeHTTP(w, r)
+		return w
+	}
+	first := send("1")
+	retry := send("2")
+
+	assert.Equal(t, 1, created, "the retry is replayed instead of creating the event again")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(idempotency.HeaderReplayed))
+	for _, header := range []string{"Location", "ETag", "Content-Type"} {
+		assert.Equal(t, first.Header().Get(header), retry.Header().Get(header), header)
+	}
+	assert.Equal(t, "request-2", retry.Header().Get("X-Request-ID"), "the retry keeps its own request ID")
+}
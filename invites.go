@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/handlers"
+)
+
+// set on the context when the request was authenticated with an invite link
+const inviteUserIDKey = "inviteUserId"
+
+// RequireInviteToken authenticates the public web app routes with the signed
+// token embedded in the invite SMS. The token only grants access to the event
+// it was issued for and stops working once the invitee is no longer a member.
+func (s S) RequireInviteToken(c *gin.Context) {
+	eventID := c.Param("eventId")
+	token := c.Query("token")
+	if token == "" {
+		handlers.EncodeError(c, handlers.UnauthorizedError{Reason: "missing invite token"})
+		c.Abort()
+		return
+	}
+	claims, err := s.InviteSigner.Verify(token, eventID, auth.PurposeRSVP)
+	if err != nil {
+		logger.Warn(c, "rejecting invite token for event %s: %v", eventID, err)
+		handlers.EncodeError(c, toAuthError(err))
+		c.Abort()
+		return
+	}
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("event", err))
+		c.Abort()
+		return
+	}
+	isMember := false
+	for _, m := range event.Members {
+		if m.UserID == claims.UserID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		logger.Warn(c, "invite token for %s used after they were removed from event %s", claims.UserID, eventID)
+		handlers.EncodeError(c, handlers.UnauthorizedError{Reason: "invite token has been revoked"})
+		c.Abort()
+		return
+	}
+	c.Set(inviteUserIDKey, claims.UserID)
+	c.Next()
+}
+
+// rsvpLink builds the personalized link sent to an invitee
+func (s S) rsvpLink(eventID, userID string) (string, error) {
+	token, err := s.InviteSigner.Sign(eventID, userID, auth.PurposeRSVP)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{"eventId": {eventID}, "token": {token}}
+	return fmt.Sprintf("%s/invited?%s", strings.TrimSuffix(s.config.WebAppURL, "/"), query.Encode()), nil
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/health"
+	"github.com/kickback-app/api/server/metrics"
+)
+
+const (
+	twilioHealthURL = "https://api.twilio.com/"
+	expoHealthURL   = "https://exp.host/"
+	probeTimeout    = 2 * time.Second
+)
+
+// lifecycle is shared by every copy of S
+type lifecycle struct {
+	mu       sync.Mutex
+	tasks    sync.WaitGroup
+	draining bool
+	closed   bool
+}
+
+// goBackground runs fn after the request completes, on a copy of c since
+// the original is reused once the handler returns. Tasks started once
+// shutdown has begun, or on an S that wasn't built by New, run inline.
+func (l *lifecycle) goBackground(c *gin.Context, fn func(c *gin.Context)) {
+	if l == nil {
+		fn(c)
+		return
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		fn(c)
+		return
+	}
+	l.tasks.Add(1)
+	l.mu.Unlock()
+	cp := c.Copy()
+	go func() {
+		defer l.tasks.Done()
+		fn(cp)
+	}()
+}
+
+func (l *lifecycle) isDraining() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.draining
+}
+
+func (l *lifecycle) drain() {
+	l.mu.Lock()
+	l.draining = true
+	l.mu.Unlock()
+}
+
+// wait blocks until the running background tasks finish or ctx is done
+func (l *lifecycle) wait(ctx context.Context) error {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		l.tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendInBackground sends a notification nobody waits on the result of
+func (s S) sendInBackground(c *gin.Context, notification models.Notification) {
+	s.lifecycle.goBackground(c, func(c *gin.Context) {
+		if _, _, err := s.doSendNotification(c, notification); err != nil {
+			logger.Error(c, "unable to send %s notification: %v", notification.Type, err)
+		}
+	})
+}
+
+// backgroundContext is the context of work no request started, the services
+// and the logger expect a gin.Context. It isn't cancelled on shutdown so the
+// work can finish while draining.
+func backgroundContext() *gin.Context {
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/", nil)
+	return &gin.Context{Request: req}
+}
+
+// Run serves the API and sends RSVP reminders until ctx is cancelled or the
+// process gets SIGINT or SIGTERM, then stops accepting connections and waits
+// for in-flight requests and background sends to finish.
+func (s S) Run(ctx context.Context) error {
+	cfg := s.config.HTTP
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           s.app,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+	metricsSrv := s.serveMetrics()
+	go s.runReminders(ctx)
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, draining for up to %s", cfg.ShutdownTimeout)
+	s.lifecycle.drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("in-flight requests did not finish before shutdown: %v", err)
+	}
+	if metricsSrv != nil {
+		metricsSrv.Close()
+	}
+	if waitErr := s.lifecycle.wait(shutdownCtx); waitErr != nil {
+		log.Printf("background tasks did not finish before shutdown: %v", waitErr)
+		if err == nil {
+			err = waitErr
+		}
+	}
+	if flushErr := s.Shutdown(shutdownCtx); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	if listenErr := <-serveErr; listenErr != http.ErrServerClosed && err == nil {
+		err = listenErr
+	}
+	return err
+}
+
+// serveMetrics serves /metrics on the internal metrics port so scrapes never
+// go through the public router, it returns nil when metrics are off
+func (s S) serveMetrics() *http.Server {
+	cfg := s.config.HTTP
+	if cfg.MetricsPort == 0 {
+		return nil
+	}
+	app := gin.New()
+	app.GET("/metrics", metrics.Handler())
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.MetricsPort),
+		Handler:           app,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+	}
+	go func() {
+		log.Printf("serving metrics on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Printf("metrics listener stopped: %v", err)
+		}
+	}()
+	return srv
+}
+
+// Readiness reports whether this instance should receive traffic: it isn't
+// shutting down and its critical dependencies answer
+func (s S) Readiness(c *gin.Context) {
+	if s.lifecycle.isDraining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": health.StatusDraining})
+		return
+	}
+	status, checks := health.Run(c.Request.Context(), probeTimeout, s.healthChecks())
+	code := http.StatusOK
+	if status == health.StatusUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// pinger is implemented by databases the readiness check can probe,
+// database.Manager doesn't require it
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// healthChecks probes the dependencies of the instance. A database client
+// that can't be pinged isn't checked, a check that can never pass would keep
+// the instance from ever becoming ready.
+func (s S) healthChecks() []health.Check {
+	checks := []health.Check{
+		{Name: "twilio", Probe: health.HTTPProbe(s.probeClient, twilioHealthURL)},
+		{Name: "expo", Probe: health.HTTPProbe(s.probeClient, expoHealthURL)},
+	}
+	if db, ok := s.db.(pinger); ok {
+		checks = append(checks, health.Check{Name: "database", Critical: true, Probe: db.Ping})
+	}
+	// waitlists, deadlines and write locks live there
+	if docs, ok := s.docs.(pinger); ok {
+		checks = append(checks, health.Check{Name: "documents", Critical: true, Probe: docs.Ping})
+	}
+	bucket := s.config.Storage.S3Bucket
+	if s.s3Client != nil && bucket != "" {
+		checks = append(checks, health.Check{Name: "s3", Critical: true, Probe: func(ctx context.Context) error {
+			_, err := s.s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+			return err
+		}})
+	}
+	return checks
+}
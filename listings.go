@@ -0,0 +1,71 @@
+package server
+
+import (
+	"reflect"
+
+	"github.com/kickback-app/api/server/paging"
+	"github.com/kickback-app/api/utils"
+)
+
+// what each list endpoint can be sorted and filtered by, see paging.Parse
+var (
+	eventListing = paging.Spec{
+		Sorts:       []string{"created_at", "updated_at", "name"},
+		DefaultSort: "-created_at",
+		Filters:     []paging.Filter{paging.Equal("created_by")},
+	}
+	taskListing = paging.Spec{
+		Sorts:       []string{"created_at", "updated_at", "due_by", "name"},
+		DefaultSort: "created_at",
+		Filters: []paging.Filter{
+			paging.Bool("is_completed"),
+			paging.Contains("assignee", "assignees"),
+			paging.Equal("created_by"),
+		},
+	}
+	expenseListing = paging.Spec{
+		Sorts:       []string{"created_at", "updated_at", "name"},
+		DefaultSort: "-created_at",
+		Filters: []paging.Filter{
+			paging.Bool("is_private"),
+			paging.ContainsWhere("assignee", "assignees", "userId"),
+			paging.Equal("created_by"),
+		},
+	}
+	mediaListing = paging.Spec{
+		Sorts:       []string{"created_at"},
+		DefaultSort: "-created_at",
+		Filters:     []paging.Filter{paging.Equal("created_by")},
+	}
+	channelListing = paging.Spec{
+		Sorts:       []string{"created_at", "name"},
+		DefaultSort: "created_at",
+		Filters: []paging.Filter{
+			paging.Bool("is_public"),
+			paging.Contains("member", "members"),
+			paging.Equal("created_by"),
+		},
+	}
+	pinnedMessageListing = paging.Spec{
+		Sorts:       []string{"created_at"},
+		DefaultSort: "-created_at",
+		Filters:     []paging.Filter{paging.Equal("sent_by")},
+	}
+	notificationListing = paging.Spec{
+		Sorts:       []string{"created_at"},
+		DefaultSort: "-created_at",
+		Filters:     []paging.Filter{paging.Equal("type")},
+	}
+)
+
+// normalizeEach converts every element of the slice items for paging.Query.Apply,
+// the list endpoints load whole listings from the services and page them in
+// memory
+func normalizeEach(items interface{}) []map[string]interface{} {
+	v := reflect.ValueOf(items)
+	docs := make([]map[string]interface{}, v.Len())
+	for i := range docs {
+		docs[i] = utils.Normalize(v.Index(i).Interface())
+	}
+	return docs
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/logger"
 	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/comments"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/paging"
 	"github.com/kickback-app/api/utils"
 )
 
@@ -18,17 +20,23 @@ func (s S) GetMediaItemsMetadata(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
+	query, err := paging.Parse(c.Request.URL.Query(), mediaListing)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
 	items, err := s.MediaService.GetItemsMetadata(c, kickbackID)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
+	page, nextCursor := query.Apply(normalizeEach(items))
 	result := []models.M{}
-	for _, item := range items {
-		result = append(result, s.MediaService.ResolveLinks(c, item))
+	for _, i := range page {
+		result = append(result, s.MediaService.ResolveLinks(c, items[i]))
 	}
 	logger.Info(c, "retrieved %d media objects for kickback %s", len(result), kickbackID)
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"mediaItems": result})
+	handlers.EncodePage(c, http.StatusOK, gin.H{"mediaItems": result}, nextCursor)
 }
 
 func (s S) GetMediaItem(c *gin.Context) {
@@ -62,7 +70,7 @@ func (s S) CreateMediaItem(c *gin.Context) {
 	}
 	var item models.Media
 	if err := json.NewDecoder(c.Request.Body).Decode(&item); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	itemCreated, err := s.MediaService.CreateItem(c, kickbackID, item)
@@ -83,7 +91,7 @@ func (s S) UpdateMediaItemMetadata(c *gin.Context) {
 	}
 	var updates models.MediaUpdates
 	if err := json.NewDecoder(c.Request.Body).Decode(&updates); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.MediaService.UpdateItemMetada(c, itemID, &updates)
@@ -94,6 +102,48 @@ func (s S) UpdateMediaItemMetadata(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
 
+// PatchMediaItemMetadata applies a merge patch to a media item's metadata,
+// see applyMergePatch
+func (s S) PatchMediaItemMetadata(c *gin.Context) {
+	param := "kickbackId"
+	kickbackID := c.Param(param)
+	if kickbackID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	param = "itemId"
+	itemID := c.Param(param)
+	if itemID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	release, err := s.guardWrite(c, "media", itemID, func() (interface{}, error) {
+		return s.MediaService.GetItem(c, kickbackID, itemID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	current, err := s.MediaService.GetItem(c, kickbackID, itemID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("media", err))
+		return
+	}
+	var patched models.Media
+	var updates models.MediaUpdates
+	if err := applyMergePatch(c, mediaPatchable, current, &patched, &updates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err = s.MediaService.UpdateItemMetada(c, itemID, &updates)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+}
+
 func (s S) DeleteMediaItem(c *gin.Context) {
 	param := "kickbackId"
 	kickbackID := c.Param(param)
@@ -115,6 +165,10 @@ func (s S) DeleteMediaItem(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
 
+type commentRequest struct {
+	Message string `json:"message"`
+}
+
 func (s S) AddMediaItemComment(c *gin.Context) {
 	param := "itemId"
 	itemID := c.Param(param)
@@ -122,11 +176,9 @@ func (s S) AddMediaItemComment(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
-	var comment struct {
-		Message string `json:"message"`
-	}
+	var comment commentRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&comment); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	commentID, err := s.MediaService.AddComment(c, itemID, models.MediaComment{
@@ -137,6 +189,11 @@ func (s S) AddMediaItemComment(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
+	author := comments.Author{CommentID: commentID, KickbackID: c.Param("kickbackId"), ItemID: itemID, UserID: utils.CurrentUser(c).ID}
+	if err := s.Comments.Save(author); err != nil {
+		// the comment stands, only hosts can change it without its author
+		logger.Error(c, "unable to record %s as the author of comment %s: %v", author.UserID, commentID, err)
+	}
 	logger.Info(c, "created new comment %v assocaited with media %v", commentID, itemID)
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
@@ -154,11 +211,9 @@ func (s S) UpdateMediaItemComment(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
-	var comment struct {
-		Message string `json:"message"`
-	}
+	var comment commentRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&comment); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.MediaService.UpdateComment(c, itemID, commentID, comment.Message)
@@ -188,6 +243,9 @@ func (s S) DeleteMediaItemComment(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
+	if err := s.Comments.Delete(commentID); err != nil {
+		logger.Error(c, "unable to forget the author of comment %s: %v", commentID, err)
+	}
 	logger.Info(c, "deleted comment %v assocaited with media %v", commentID, itemID)
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
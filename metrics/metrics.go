@@ -0,0 +1,148 @@
+// Package metrics exposes prometheus metrics for the API: RED metrics for
+// every route plus counters for what users do with the app.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kickback"
+
+// Registry holds every kickback metric along with the go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route template and status code.",
+	}, []string{"method", "route", "status"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	requestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being handled.",
+	})
+
+	EventsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_created_total",
+		Help:      "Events created.",
+	})
+	rsvps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rsvps_total",
+		Help:      "RSVPs recorded, by member status and where they came from (app, web, sms, waitlist or deadline).",
+	}, []string{"status", "source"})
+	Notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notifications delivered to recipients, by channel and result (sent or failed).",
+	}, []string{"channel", "result"})
+	SMSSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sms_sent_total",
+		Help:      "SMS handed to twilio, by kind (invite or notification).",
+	}, []string{"kind"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requests,
+		requestDuration,
+		requestsInFlight,
+		EventsCreated,
+		rsvps,
+		Notifications,
+		SMSSent,
+	)
+}
+
+// methods are the method labels, anything else is counted as "other" so
+// made up methods on unmatched routes don't blow up cardinality
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Middleware records RED metrics for every request. Routes are labeled by
+// their template (e.g. /v1/tasks/:taskId) so ids don't blow up cardinality.
+// Requests that panic are counted as 500s before the panic moves on to the
+// recovery middleware.
+func Middleware(c *gin.Context) {
+	requestsInFlight.Inc()
+	start := time.Now()
+	defer func() {
+		requestsInFlight.Dec()
+		status := c.Writer.Status()
+		p := recover()
+		if p != nil {
+			status = http.StatusInternalServerError
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		if !methods[method] {
+			method = "other"
+		}
+		requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		requestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		if p != nil {
+			panic(p)
+		}
+	}()
+	c.Next()
+}
+
+// rsvpSources are the source labels of RSVPs
+var rsvpSources = map[string]bool{
+	"app":      true,
+	"web":      true,
+	"sms":      true,
+	"waitlist": true,
+	"deadline": true,
+}
+
+// RSVP counts an RSVP, sources other than the known ones are counted as
+// "other" since the source comes from the request
+func RSVP(status, source string) {
+	if !rsvpSources[source] {
+		source = "other"
+	}
+	rsvps.WithLabelValues(status, source).Inc()
+}
+
+// Handler serves the metrics in the prometheus text format, it belongs on the
+// internal metrics listener rather than the public API
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// Result is the result label for a counter that tracks sent/failed outcomes
+func Result(err error) string {
+	if err != nil {
+		return "failed"
+	}
+	return "sent"
+}
@@ -0,0 +1,59 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.Use(metrics.Middleware)
+	app.GET("/v1/tasks/:taskId", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	app.GET("/metrics", metrics.Handler())
+
+	for _, path := range []string{"/v1/tasks/1", "/v1/tasks/2", "/nope"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	assert.Contains(t, body, `kickback_http_requests_total{method="GET",route="/v1/tasks/:taskId",status="204"} 2`)
+	assert.Contains(t, body, `kickback_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `kickback_http_request_duration_seconds_count{method="GET",route="/v1/tasks/:taskId"} 2`)
+	assert.Contains(t, body, "kickback_http_requests_in_flight 1")
+	assert.False(t, strings.Contains(body, "/v1/tasks/1"), "raw paths should not be used as labels")
+}
+
+func TestMiddlewarePanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.Use(gin.Recovery(), metrics.Middleware)
+	app.GET("/v1/panics", func(c *gin.Context) {
+		panic("boom")
+	})
+	app.GET("/metrics", metrics.Handler())
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/panics", nil))
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/nope", nil))
+	metrics.RSVP("going", "sms")
+	metrics.RSVP("going", "'; drop table")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	assert.Contains(t, body, `kickback_http_requests_total{method="GET",route="/v1/panics",status="500"} 1`)
+	assert.Contains(t, body, `kickback_http_requests_total{method="other",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `kickback_rsvps_total{source="sms",status="going"} 1`)
+	assert.Contains(t, body, `kickback_rsvps_total{source="other",status="going"} 1`)
+	assert.NotContains(t, body, "PROPFIND")
+}
@@ -2,52 +2,64 @@ package middlewares
 
 import (
 	"fmt"
-	"net/http"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/config"
+	"github.com/kickback-app/api/server/handlers"
 )
 
-func Authorize(c *gin.Context) {
+func newTokenValidator(cfg config.Auth) (auth.Validator, error) {
+	keys := auth.NewKeySet()
+	if cfg.SigningToken != "" {
+		// legacy tokens are signed without a kid
+		keys.Add(auth.Key{ID: "", Material: []byte(cfg.SigningToken.Value())})
+	}
+	if err := keys.AddHMACKeys(cfg.SigningKeys.Value()); err != nil {
+		return auth.Validator{}, fmt.Errorf("invalid auth.signing_keys: %v", err)
+	}
+	if cfg.JWKSURL != "" {
+		keys.UseJWKS(cfg.JWKSURL)
+	}
+	return auth.Validator{
+		Keys:     keys,
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+	}, nil
+}
+
+// NewTokenIssuer returns an issuer signing with the active key, so that every
+// token it mints is accepted by Authorize
+func (mw *Middlewares) NewTokenIssuer(store auth.RefreshStore) *auth.Issuer {
+	return &auth.Issuer{
+		Keys:       mw.tokenValidator.Keys,
+		KeyID:      mw.cfg.Auth.ActiveKeyID,
+		Issuer:     mw.tokenValidator.Issuer,
+		Audience:   mw.tokenValidator.Audience,
+		AccessTTL:  mw.cfg.Auth.AccessTokenTTL,
+		RefreshTTL: mw.cfg.Auth.RefreshTokenTTL,
+		Store:      store,
+	}
+}
+
+func (mw *Middlewares) Authorize(c *gin.Context) {
 	tok := c.GetHeader(headerJWTToken)
 	if tok == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing JWT header"})
+		handlers.EncodeError(c, handlers.UnauthorizedError{Reason: "missing JWT header"})
+		c.Abort()
 		return
 	}
-	token, err := jwt.Parse(tok, func(token *jwt.Token) (interface{}, error) {
-
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected token type")
-		}
-		if jwtSigningToken == "" {
-			return nil, fmt.Errorf("signing token is empty")
-		}
-		return []byte(jwtSigningToken), nil
-	})
+	claims, err := mw.tokenValidator.Validate(tok)
 	if err != nil {
-		logger.Error(c, "auth err: "+err.Error())
-	}
-	// @todo uncomment once we finish development with hyperlink
-	// if err != nil {
-	// 	logger.Error(c, err.Error())
-	// 	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "unexpected error validating auth token"})
-	// 	return
-	// }
-	// if !token.Valid {
-	// 	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not authorized"})
-	// 	return
-	// }
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		iss := claims["iss"].(string)
-		aud := claims["aud"].(string)
-		userId := claims["userId"].(string)
-		c.Set("iss", iss)
-		c.Set("aud", aud)
-		c.Set("userId", userId)
-		logger.Info(c, "jwt info: iss '%s', aud '%s', userId '%s'", iss, aud, userId)
-	} else {
-		logger.Error(c, "unable to parse JWT claims")
+		logger.Warn(c, "rejecting token: %v", err)
+		handlers.EncodeError(c, handlers.UnauthorizedError{Reason: err.Error()})
+		c.Abort()
+		return
 	}
+	c.Set("iss", claims.Issuer)
+	c.Set("aud", claims.Audience)
+	c.Set("userId", claims.UserID)
+	logger.Info(c, "jwt info: iss '%s', aud '%s', userId '%s'", claims.Issuer, claims.Audience, claims.UserID)
 	c.Next()
 }
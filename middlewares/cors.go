@@ -5,8 +5,10 @@ import "github.com/gin-gonic/gin"
 func CORSMiddleware(c *gin.Context) {
 	c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 	c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-	c.Writer.Header().Set("Access-Control-Allow-Headers", "x-jwt, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-	c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+	c.Writer.Header().Set("Access-Control-Allow-Headers", "x-jwt, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, Idempotency-Key, If-Match, If-None-Match")
+	c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
+	// let browsers read the IDs that are also reported in the response envelope
+	c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Transaction-ID, Idempotent-Replayed, ETag")
 
 	if c.Request.Method == "OPTIONS" {
 		c.AbortWithStatus(204)
@@ -3,24 +3,45 @@ package middlewares
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/config"
+	"github.com/kickback-app/api/server/requestid"
 )
 
-const headerXRequestID = "X-Request-ID"
-const headerXTransactionID = "X-Transaction-ID"
+const headerXRequestID = requestid.HeaderRequestID
+const headerXTransactionID = requestid.HeaderTransactionID
 const headerJWTToken = "X-JWT"
 
-var jwtSigningToken = os.Getenv("JWT_SIGNING_TOKEN") // @todo change to KTOKEN
+// Middlewares are the middlewares that depend on config, AttachRequestIDs
+// doesn't and is a plain func
+type Middlewares struct {
+	cfg            config.Config
+	tokenValidator auth.Validator
+	// accessLog is where AccessLogger writes, stdout outside of tests
+	accessLog io.Writer
+}
+
+func New(cfg config.Config) (*Middlewares, error) {
+	validator, err := newTokenValidator(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	return &Middlewares{cfg: cfg, tokenValidator: validator, accessLog: os.Stdout}, nil
+}
 
 type MiddlewareFunc func(c *gin.Context)
 
+// AttachRequestIDs makes the request and transaction IDs available to
+// handlers, the logger and outbound calls, and echoes them in the response
 func AttachRequestIDs(c *gin.Context) {
 	// use assigned reqId from Heroku router; else generate one
-	reqID := c.GetHeader(headerXTransactionID)
+	reqID := c.GetHeader(headerXRequestID)
 	if reqID == "" {
 		reqID = uuid.New().String()
 	}
@@ -29,18 +50,23 @@ func AttachRequestIDs(c *gin.Context) {
 	if trxID == "" {
 		trxID = uuid.New().String()
 	}
+	c.Set(requestid.KeyRequestID, reqID)
+	c.Set(requestid.KeyTransactionID, trxID)
+	ids := requestid.IDs{RequestID: reqID, TransactionID: trxID}
+	c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), ids))
 	c.Header(headerXRequestID, reqID)
 	c.Header(headerXTransactionID, trxID)
 	c.Next()
 }
 
-func AccessLogger(c *gin.Context) {
-	out := os.Stdout
+func (mw *Middlewares) AccessLogger(c *gin.Context) {
+	out := mw.accessLog
 
 	// Start timer
 	start := time.Now()
-	path := c.Request.URL.Path
-	raw := c.Request.URL.RawQuery
+	// invite links and calendar feeds carry their token in the url
+	path := RedactedPath(c)
+	raw := RedactedQuery(c)
 
 	// Process request
 	c.Next()
@@ -59,8 +85,7 @@ func AccessLogger(c *gin.Context) {
 		path = path + "?" + raw
 	}
 
-	env := os.Getenv("ENV")
-	if env == "dev" {
+	if mw.cfg.IsDev() {
 		s := fmt.Sprintf("%s - [%s] \"%s %s %s %d %s \"%s\"\n",
 			clientIP,
 			timeStamp.Format(time.RFC1123),
@@ -84,8 +109,8 @@ func AccessLogger(c *gin.Context) {
 		"latency":       latency.Milliseconds(),
 		"userAgent":     c.Request.UserAgent(),
 		"bodyBytes":     bodySize,
-		"requestId":     c.Writer.Header().Get(headerXRequestID),
-		"transactionId": c.Writer.Header().Get(headerXTransactionID),
+		"requestId":     c.GetString(requestid.KeyRequestID),
+		"transactionId": c.GetString(requestid.KeyTransactionID),
 	}
 	b, err := json.Marshal(m)
 	if err != nil {
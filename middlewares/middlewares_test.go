@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestAccessLoggerRedactsSecrets(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	cases := []struct {
+		Name         string
+		Env          string
+		Route        string
+		Path         string
+		ExpectedPath string
+	}{
+		{
+			Name:         "invite tokens in the query",
+			Route:        "/v1/invites",
+			Path:         "/v1/invites?eventId=EVT_1&token=c2VjcmV0LmhtYWM",
+			ExpectedPath: "/v1/invites?eventId=EVT_1&token=REDACTED",
+		},
+		{
+			Name:         "calendar feed tokens in the path",
+			Route:        "/calendar/:token",
+			Path:         "/calendar/c2VjcmV0?refresh_token=abc",
+			ExpectedPath: "/calendar/REDACTED?refresh_token=REDACTED",
+		},
+		{
+			Name:         "dev logs are redacted too",
+			Env:          "dev",
+			Route:        "/v1/invites",
+			Path:         "/v1/invites?token=c2VjcmV0LmhtYWM",
+			ExpectedPath: "/v1/invites?token=REDACTED",
+		},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		mw := &Middlewares{cfg: config.Config{Env: c.Env}, accessLog: &out}
+		app := gin.New()
+		app.Use(mw.AccessLogger)
+		app.GET(c.Route, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+		line := out.String()
+		assert.NotContains(t, line, "c2VjcmV0", c.Name)
+		if c.Env == "dev" {
+			assert.Contains(t, line, c.ExpectedPath, c.Name)
+			continue
+		}
+		assert.Equal(t, c.ExpectedPath, gjson.Get(line, "path").String(), c.Name)
+	}
+}
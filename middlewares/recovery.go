@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/handlers"
+)
+
+// Recovery turns a panicking handler into a 500 in the usual APIResponse
+// envelope instead of dropping the connection
+func Recovery(c *gin.Context) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		// net/http uses this panic to abort a response on purpose
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+		logger.Error(c, "recovered from panic: %v\n%s", recovered, debug.Stack())
+		handlers.EncodeError(c, fmt.Errorf("panic: %v", recovered))
+		c.Abort()
+	}()
+	c.Next()
+}
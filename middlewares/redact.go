@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// redacted replaces secrets in paths that are logged or traced
+const redacted = "REDACTED"
+
+// secretParams are the route and query params that authenticate a request by
+// themselves, e.g. the token of calendar feed urls and of invite links
+var secretParams = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+}
+
+// RedactedPath is the path of the request with the values of secret route
+// params replaced
+func RedactedPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	for _, p := range c.Params {
+		if !secretParams[p.Key] || p.Value == "" {
+			continue
+		}
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if segment == p.Value {
+				segments[i] = redacted
+			}
+		}
+		path = strings.Join(segments, "/")
+	}
+	return path
+}
+
+// RedactedQuery is the raw query of the request with the values of secret
+// params replaced, the other params are kept as the client sent them
+func RedactedQuery(c *gin.Context) string {
+	raw := c.Request.URL.RawQuery
+	if raw == "" {
+		return ""
+	}
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && secretParams[name] {
+			pairs[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Tracing starts the span of the request like otelgin does, with the secret
+// route and query params redacted from the url it records. The span is named
+// after the route, handlers still read the params from the context.
+func Tracing(service string) gin.HandlerFunc {
+	trace := otelgin.Middleware(service)
+	return func(c *gin.Context) {
+		path, query := RedactedPath(c), RedactedQuery(c)
+		if path == c.Request.URL.Path && query == c.Request.URL.RawQuery {
+			trace(c)
+			return
+		}
+		original := c.Request
+		u := *original.URL
+		u.Path, u.RawPath, u.RawQuery = path, "", query
+		c.Request = original.Clone(original.Context())
+		c.Request.URL = &u
+		c.Request.RequestURI = u.RequestURI()
+		trace(c)
+		c.Request = original
+	}
+}
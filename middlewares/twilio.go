@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/twilio"
+)
+
+// VerifyTwilioSignature rejects webhook calls that weren't signed with our
+// twilio auth token. The parsed form is left on c.Request.PostForm.
+func (mw *Middlewares) VerifyTwilioSignature(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		c.Abort()
+		return
+	}
+	authToken := mw.cfg.Twilio.AuthToken.Value()
+	if authToken == "" {
+		logger.Error(c, "twilio.auth_token is not set, unable to verify webhook")
+	}
+	fullURL := twilio.RequestURL(c.Request, mw.cfg.HTTP.PublicBaseURL)
+	if !twilio.ValidSignature(authToken, fullURL, c.Request.PostForm, c.GetHeader(twilio.HeaderSignature)) {
+		logger.Warn(c, "invalid twilio signature for %s", fullURL)
+		handlers.EncodeError(c, handlers.ForbiddenError{Action: "call this webhook"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
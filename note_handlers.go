@@ -20,6 +20,9 @@ func (s S) GetNote(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
+	if notModified(c, note) {
+		return
+	}
 	handlers.EncodeSuccess(c, http.StatusOK, note)
 }
 
@@ -32,7 +35,7 @@ func (s S) UpdateNote(c *gin.Context) {
 	}
 	var data map[string]string
 	if err := json.NewDecoder(c.Request.Body).Decode(&data); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	content, ok := data["content"]
@@ -40,7 +43,15 @@ func (s S) UpdateNote(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MalformedBodyError{Field: "content"})
 		return
 	}
-	err := s.NoteService.UpdateNote(c, kickbackID, content)
+	release, err := s.guardWrite(c, "note", kickbackID, func() (interface{}, error) {
+		return s.NoteService.GetNote(c, kickbackID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	err = s.NoteService.UpdateNote(c, kickbackID, content)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
@@ -4,30 +4,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/logger"
 	"github.com/kickback-app/api/pkg/models"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/metrics"
+	"github.com/kickback-app/api/server/paging"
+	"github.com/kickback-app/api/server/requestid"
+	"github.com/kickback-app/api/server/telemetry"
+	"github.com/kickback-app/api/server/twilio"
 	"github.com/kickback-app/api/utils"
 	expo "github.com/oliveroneill/exponent-server-sdk-golang/sdk"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/mgo.v2/bson"
 )
 
 func (s S) GetNotifications(c *gin.Context) {
+	query, err := paging.Parse(c.Request.URL.Query(), notificationListing)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
 	res, err := s.NotificationService.GetNotifications(c)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
-	logger.Info(c, "retrieved %d notifcations", len(res))
-	handlers.EncodeSuccess(c, http.StatusOK, res)
+	page, nextCursor := query.Apply(normalizeEach(res))
+	notifications := []interface{}{}
+	for _, i := range page {
+		notifications = append(notifications, res[i])
+	}
+	logger.Info(c, "retrieved %d of %d notifcations", len(notifications), len(res))
+	handlers.EncodePage(c, http.StatusOK, notifications, nextCursor)
 }
 
 func (s S) SendNotification(c *gin.Context) {
 	var notification models.Notification
 	if err := json.NewDecoder(c.Request.Body).Decode(&notification); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	notifcationID, errReport, err := s.doSendNotification(c, notification)
@@ -35,10 +52,42 @@ func (s S) SendNotification(c *gin.Context) {
 		handlers.EncodeError(c, err)
 		return
 	}
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"notificationId": notifcationID, "errors": errReport})
+	if s.SMSDeliveries != nil {
+		// the report of a notification is only shown to whoever sent it
+		if err := s.SMSDeliveries.Sent(notifcationID, utils.CurrentUser(c).ID); err != nil {
+			logger.Warn(c, "unable to record the sender of notification %s: %v", notifcationID, err)
+		}
+	}
+	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"notificationId": notifcationID, "errors": traceableReport(c, errReport)})
 }
 
-func (s S) doSendNotification(c *gin.Context, notification models.Notification) (string, models.NotificationErrorReport, error) {
+// sendSMS sends message to phoneNumber and returns the SID twilio gave it.
+// Delivery statuses are only linked back to the notification an SMS was sent
+// for through that SID, the NotificationService doesn't report it so without
+// s.SMS the notification report lists no SMS.
+func (s S) sendSMS(c *gin.Context, message, phoneNumber string) (string, error) {
+	if s.SMS != nil {
+		return s.SMS.Send(c.Request.Context(), phoneNumber, message)
+	}
+	return "", s.NotificationService.SendSMS(c, message, phoneNumber)
+}
+
+// smsDelivery is a twilio.DeliveryStatus without the phone numbers, the sender
+// of a notification only needs to know which recipients didn't get it
+type smsDelivery struct {
+	MessageSID string    `json:"message_sid"`
+	UserID     string    `json:"userId,omitempty"`
+	Status     string    `json:"status"`
+	ErrorCode  string    `json:"error_code,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (s S) doSendNotification(c *gin.Context, notification models.Notification) (_ string, _ models.NotificationErrorReport, err error) {
+	endSpan := telemetry.StartSpan(c, "doSendNotification",
+		attribute.String("notification.type", string(notification.Type)),
+		attribute.StringSlice("notification.channels", notification.Channels),
+	)
+	defer func() { endSpan(err) }()
 	errReport := models.NotificationErrorReport{
 		Push: map[string]string{},
 		SMS:  map[string]string{},
@@ -61,10 +110,12 @@ func (s S) doSendNotification(c *gin.Context, notification models.Notification)
 			token, err := expo.NewExponentPushToken(user.ExpoPushNotificationToken)
 			if err != nil {
 				errReport.Push[user.ID] = "failed to get expo token"
+				metrics.Notifications.WithLabelValues("push", "failed").Inc()
 				continue
 			}
 			pushTokens = append(pushTokens, token)
 		}
+		endPushSpan := telemetry.StartSpan(c, "NotificationService.SendPushNotification", attribute.Int("count", len(pushTokens)))
 		err = s.NotificationService.SendPushNotification(c, &expo.PushMessage{
 			To:       pushTokens,
 			Title:    notification.Title,
@@ -73,16 +124,27 @@ func (s S) doSendNotification(c *gin.Context, notification models.Notification)
 			Sound:    "default",
 			Priority: expo.DefaultPriority,
 		})
+		endPushSpan(err)
+		metrics.Notifications.WithLabelValues("push", metrics.Result(err)).Add(float64(len(pushTokens)))
 		if err != nil {
 			errReport.Push["general"] = fmt.Sprintf("failed to send push: %v", err)
 		}
 	}
 	if utils.ContainsString(notification.Channels, "sms") && notification.SMSmessage != "" {
 		for _, user := range users {
-
-			err := s.NotificationService.SendSMS(c, notification.SMSmessage, user.PhoneNumber)
+			endSMSSpan := telemetry.StartSpan(c, "NotificationService.SendSMS", attribute.String("userId", user.ID))
+			sid, err := s.sendSMS(c, notification.SMSmessage, user.PhoneNumber)
+			endSMSSpan(err)
+			metrics.Notifications.WithLabelValues("sms", metrics.Result(err)).Inc()
 			if err != nil {
-				errReport.Push[user.ID] = fmt.Sprintf("failed to send SMS: %v", err)
+				errReport.SMS[user.ID] = fmt.Sprintf("failed to send SMS: %v", err)
+				continue
+			}
+			metrics.SMSSent.WithLabelValues("notification").Inc()
+			if s.SMSDeliveries != nil && sid != "" {
+				if err := s.SMSDeliveries.Track(sid, notifcationID, user.ID); err != nil {
+					logger.Warn(c, "unable to track sms delivery for %s: %v", user.ID, err)
+				}
 			}
 		}
 	}
@@ -90,6 +152,73 @@ func (s S) doSendNotification(c *gin.Context, notification models.Notification)
 	return notifcationID, errReport, nil
 }
 
+// GetNotificationReport returns the SMS delivery outcomes twilio reported
+// back for a notification, in the same shape SendNotification returns errors
+func (s S) GetNotificationReport(c *gin.Context) {
+	param := "notificationId"
+	notificationID := c.Param(param)
+	if notificationID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	sentBy, err := s.SMSDeliveries.SentBy(notificationID)
+	if err == twilio.ErrNotificationNotFound || (err == nil && sentBy != utils.CurrentUser(c).ID) {
+		// notifications sent by someone else look the same as unknown ones
+		handlers.EncodeError(c, handlers.NotFoundError{Resource: "notification", Err: twilio.ErrNotificationNotFound})
+		return
+	}
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	statuses, err := s.SMSDeliveries.ForNotification(notificationID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	report := models.NotificationErrorReport{
+		Push: map[string]string{},
+		SMS:  map[string]string{},
+	}
+	delivered := 0
+	deliveries := []smsDelivery{}
+	for _, status := range statuses {
+		deliveries = append(deliveries, smsDelivery{
+			MessageSID: status.MessageSID,
+			UserID:     status.UserID,
+			Status:     status.Status,
+			ErrorCode:  status.ErrorCode,
+			UpdatedAt:  status.UpdatedAt,
+		})
+		switch {
+		case status.Failed():
+			report.SMS[status.UserID] = fmt.Sprintf("%s (error code: %s)", status.Status, status.ErrorCode)
+		case status.Status == twilio.StatusDelivered:
+			delivered++
+		}
+	}
+	logger.Info(c, "notification %s: %d sms delivered, %d failed", notificationID, delivered, len(report.SMS))
+	handlers.EncodeSuccess(c, http.StatusOK, gin.H{
+		"notificationId": notificationID,
+		"errors":         traceableReport(c, report),
+		"sms_statuses":   deliveries,
+	})
+}
+
+// notificationReport is a NotificationErrorReport tagged with the request it
+// was produced by so support can find the matching logs
+type notificationReport struct {
+	models.NotificationErrorReport
+	requestid.IDs
+}
+
+func traceableReport(c *gin.Context, report models.NotificationErrorReport) notificationReport {
+	return notificationReport{
+		NotificationErrorReport: report,
+		IDs:                     requestid.FromContext(c),
+	}
+}
+
 func (s S) GetUsersNotificationSettings(c *gin.Context) {
 	userID := utils.CurrentUser(c).ID
 	settings, err := s.UserService.GetNotificationSettings(c)
@@ -128,7 +257,7 @@ func (s S) GetUsersNotificationSettings(c *gin.Context) {
 func (s S) UpdateUsersNotificationSettings(c *gin.Context) {
 	var updates models.UserNotificationSettingUpdates
 	if err := json.NewDecoder(c.Request.Body).Decode(&updates); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.UserService.UpdateNotificationSettings(c, &updates)
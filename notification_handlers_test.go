@@ -1,6 +1,19 @@
 package server_test
 
-import "testing"
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/internal/services"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/twilio"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
 
 func TestGetNotifications(t *testing.T) {
 	t.Skip("todo: implement")
@@ -10,6 +23,68 @@ func TestSendNotifications(t *testing.T) {
 	t.Skip("todo: implement")
 }
 
+func TestSendNotificationTracksSMS(t *testing.T) {
+	sent := []string{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("To") == "+15005550001" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": 21211, "message": "The 'To' number is not a valid phone number.", "status": 400}`))
+			return
+		}
+		sid := fmt.Sprintf("SM%d", len(sent)+1)
+		sent = append(sent, r.PostForm.Get("To"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid": "` + sid + `", "status": "queued"}`))
+	}))
+	defer api.Close()
+	store := twilio.NewMemoryDeliveryStore()
+	mockServer := server.S{
+		NotificationService: services.NotificationService{
+			DBClient: utils.MockDBClient{CallCount: new(int), DefaultResponse: "NTF_Mock"},
+		},
+		UserService: services.UserService{
+			DBClient: utils.MockDBClient{
+				CallCount: new(int),
+				DefaultResponse: `[
+					{"_id": "invitee", "phone_number": "+15005550006"},
+					{"_id": "unreachable", "phone_number": "+15005550001"}
+				]`,
+			},
+			Cache: &utils.MockCache{Callcount: new(int), Items: map[string]interface{}{}},
+		},
+		SMS:           &twilio.Client{AccountSID: "AC1", AuthToken: "secret", From: "+15005550002", BaseURL: api.URL},
+		SMSDeliveries: store,
+	}
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Set("userId", "mockUserId")
+	ctx.Request = &http.Request{Header: make(http.Header)}
+	utils.MockRequest(ctx, http.MethodPost, `{"type": "event_member_attending", "channels": ["sms"], "to": ["invitee", "unreachable"], "title": "Hi", "body": "Hi", "sms_message": "Hi"}`)
+	mockServer.SendNotification(ctx)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"+15005550006"}, sent)
+	assert.Contains(t, gjson.Get(w.Body.String(), "result.errors.sms.unreachable").String(), "not a valid phone number")
+
+	// twilio reports the delivery of the message by the SID it returned
+	status, err := store.Save(twilio.DeliveryStatus{MessageSID: "SM1", To: "+15005550006", Status: twilio.StatusDelivered})
+	assert.NoError(t, err)
+	assert.Equal(t, "NTF_Mock", status.NotificationID)
+	assert.Equal(t, "invitee", status.UserID)
+
+	w = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(w)
+	ctx.Set("userId", "mockUserId")
+	ctx.Request = &http.Request{Header: make(http.Header)}
+	ctx.Params = []gin.Param{{Key: "notificationId", Value: "NTF_Mock"}}
+	utils.MockRequest(ctx, http.MethodGet, "")
+	mockServer.GetNotificationReport(ctx)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "SM1", gjson.Get(w.Body.String(), "result.sms_statuses.0.message_sid").String())
+	assert.Equal(t, "delivered", gjson.Get(w.Body.String(), "result.sms_statuses.0.status").String())
+}
+
 func TestGetUsersNotificationSettings(t *testing.T) {
 	t.Skip("todo: implement")
 }
@@ -17,3 +92,59 @@ func TestGetUsersNotificationSettings(t *testing.T) {
 func TestUpdateUsersNotificationSettings(t *testing.T) {
 	t.Skip("todo: implement")
 }
+
+func TestGetNotificationReport(t *testing.T) {
+	store := twilio.NewMemoryDeliveryStore()
+	store.Sent("NTF_Mock", "mockUserId")
+	store.Track("SM_Mock", "NTF_Mock", "invitee")
+	store.Save(twilio.DeliveryStatus{MessageSID: "SM_Mock", To: "+15005550006", From: "+15005550001", Status: "undelivered", ErrorCode: "30003"})
+	mockServer := server.S{SMSDeliveries: store}
+	cases := []struct {
+		Name               string
+		UserID             string
+		NotificationID     string
+		ExpectedStatusCode int
+		PathToResult       string
+		ExpectedResult     string
+	}{
+		{
+			Name:               "happy path - the sender reads the report without phone numbers",
+			UserID:             "mockUserId",
+			NotificationID:     "NTF_Mock",
+			ExpectedStatusCode: http.StatusOK,
+			PathToResult:       "result.sms_statuses.0",
+			ExpectedResult:     `{"message_sid": "SM_Mock", "userId": "invitee", "status": "undelivered", "error_code": "30003"}`,
+		},
+		{
+			Name:               "other users can't read it",
+			UserID:             "invitee",
+			NotificationID:     "NTF_Mock",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "unknown notification",
+			UserID:             "mockUserId",
+			NotificationID:     "NTF_Unknown",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", c.UserID)
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Params = []gin.Param{{Key: "notificationId", Value: c.NotificationID}}
+		utils.MockRequest(ctx, http.MethodGet, "")
+		mockServer.GetNotificationReport(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		if c.ExpectedResult != "" {
+			result := gjson.Get(w.Body.String(), c.PathToResult)
+			for _, field := range []string{"message_sid", "userId", "status", "error_code"} {
+				assert.Equal(t, gjson.Get(c.ExpectedResult, field).String(), result.Get(field).String(), c.Name)
+			}
+			assert.False(t, result.Get("to").Exists(), c.Name)
+			assert.False(t, result.Get("from").Exists(), c.Name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kickback-app/api/server/validate"
+)
+
+// Operation returns the operation documented for the route registered with
+// gin under method and path
+func (d *Document) Operation(method, path string) (*Operation, bool) {
+	op, ok := d.Paths[Path(path)][strings.ToLower(method)]
+	return op, ok
+}
+
+// Resolve follows s to its definition when it is a reference
+func (d *Document) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix)]
+	}
+	return s
+}
+
+// CheckParam checks the raw value of a path or query parameter, present is
+// false when the request doesn't have it
+func (d *Document) CheckParam(v *validate.Validator, p Parameter, raw string, present bool) {
+	if !present || raw == "" {
+		v.Check(!p.Required, p.Name, validate.RuleRequired, "is required")
+		return
+	}
+	s := d.Resolve(p.Schema)
+	if s == nil {
+		return
+	}
+	var value interface{} = raw
+	switch s.Type {
+	case "integer", "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			v.Add(p.Name, validate.RuleType, "must be a number")
+			return
+		}
+		value = n
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			v.Add(p.Name, validate.RuleType, "must be true or false")
+			return
+		}
+		value = b
+	}
+	d.Check(v, p.Name, s, value, false)
+}
+
+// Check checks value, as decoded from JSON by encoding/json, against s.
+// Objects with properties reject fields they don't list. Nested fields are
+// reported dotted and list items by index, e.g. assignees[0].userId. A
+// partial value is a merge patch: null is allowed anywhere and required
+// fields may be left out.
+func (d *Document) Check(v *validate.Validator, field string, s *Schema, value interface{}, partial bool) {
+	s = d.Resolve(s)
+	if s == nil {
+		return
+	}
+	for _, sub := range s.AllOf {
+		d.Check(v, field, sub, value, partial)
+	}
+	if value == nil {
+		v.Check(partial || s.Nullable || s.Type == "", field, validate.RuleType, "must not be null")
+		return
+	}
+	switch s.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			v.Add(field, validate.RuleType, "must be a string")
+			return
+		}
+		if s.MaxLength != nil {
+			v.MaxLength(field, str, *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			v.Check(err != nil || re.MatchString(str), field, validate.RuleFormat, "is not in the expected format")
+		}
+		if len(s.Enum) > 0 {
+			allowed := make([]string, len(s.Enum))
+			for i, e := range s.Enum {
+				allowed[i] = fmt.Sprint(e)
+			}
+			v.OneOf(field, str, allowed)
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			v.Add(field, validate.RuleType, "must be a number")
+			return
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			v.Add(field, validate.RuleType, "must be a whole number")
+			return
+		}
+		if s.Minimum != nil {
+			v.Min(field, n, *s.Minimum)
+		}
+		if s.Maximum != nil {
+			v.Max(field, n, *s.Maximum)
+		}
+	case "boolean":
+		_, ok := value.(bool)
+		v.Check(ok, field, validate.RuleType, "must be true or false")
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.Add(field, validate.RuleType, "must be a list")
+			return
+		}
+		for i, item := range items {
+			d.Check(v, fmt.Sprintf("%s[%d]", field, i), s.Items, item, partial)
+		}
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.Add(field, validate.RuleType, "must be an object")
+			return
+		}
+		if !partial {
+			for _, name := range s.Required {
+				_, present := obj[name]
+				v.Check(present, join(field, name), validate.RuleRequired, "is required")
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, known := s.Properties[k]
+			switch {
+			case known:
+				d.Check(v, join(field, k), prop, obj[k], partial)
+			case s.AdditionalProperties != nil:
+				d.Check(v, join(field, k), s.AdditionalProperties, obj[k], partial)
+			case len(s.Properties) > 0:
+				v.Add(join(field, k), validate.RuleUnknownField, "is not a known field")
+			}
+		}
+	}
+}
+
+func join(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
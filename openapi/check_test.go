@@ -0,0 +1,108 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/openapi"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/stretchr/testify/assert"
+)
+
+type assignee struct {
+	UserID string  `json:"userId"`
+	Amount float64 `json:"amount"`
+}
+
+type expense struct {
+	Name      string     `json:"name"`
+	Guests    int        `json:"guests"`
+	IsPrivate bool       `json:"is_private"`
+	Assignees []assignee `json:"assignees"`
+	Note      *string    `json:"note"`
+	Tags      map[string]string
+}
+
+func TestCheck(t *testing.T) {
+	d := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	schema := d.SchemaOf(expense{})
+	def := d.Component(expense{})
+	def.Required = []string{"name"}
+	max := 5
+	def.Properties["name"].MaxLength = &max
+	d.Component(assignee{}).Required = []string{"userId"}
+
+	cases := []struct {
+		Name     string
+		Body     string
+		Partial  bool
+		Expected []handlers.FieldViolation
+	}{
+		{
+			Name: "valid body",
+			Body: `{"name": "cups", "guests": 3, "assignees": [{"userId": "USR_1", "amount": 2.5}], "note": null, "Tags": {"a": "b"}}`,
+		},
+		{
+			Name: "every violation is reported",
+			Body: `{"guests": 1.5, "is_private": "yes", "assignees": [{"amount": "2"}], "owner": "USR_1", "Tags": {"a": 1}}`,
+			Expected: []handlers.FieldViolation{
+				{Field: "name", Rule: validate.RuleRequired, Message: "is required"},
+				{Field: "Tags.a", Rule: validate.RuleType, Message: "must be a string"},
+				{Field: "assignees[0].userId", Rule: validate.RuleRequired, Message: "is required"},
+				{Field: "assignees[0].amount", Rule: validate.RuleType, Message: "must be a number"},
+				{Field: "guests", Rule: validate.RuleType, Message: "must be a whole number"},
+				{Field: "is_private", Rule: validate.RuleType, Message: "must be true or false"},
+				{Field: "owner", Rule: validate.RuleUnknownField, Message: "is not a known field"},
+			},
+		},
+		{
+			Name: "constraints on strings and nulls",
+			Body: `{"name": "kickback", "guests": null}`,
+			Expected: []handlers.FieldViolation{
+				{Field: "guests", Rule: validate.RuleType, Message: "must not be null"},
+				{Field: "name", Rule: validate.RuleMaxLength, Message: "must be at most 5 characters"},
+			},
+		},
+		{
+			Name:    "merge patches may remove fields",
+			Body:    `{"guests": null, "assignees": [{"amount": 1}]}`,
+			Partial: true,
+		},
+	}
+	for i, c := range cases {
+		t.Logf("executing case %d: %v", i, c.Name)
+		var body interface{}
+		if err := json.Unmarshal([]byte(c.Body), &body); err != nil {
+			t.Fatal(err)
+		}
+		var v validate.Validator
+		d.Check(&v, "", schema, body, c.Partial)
+		err := v.Err()
+		if c.Expected == nil {
+			assert.NoError(t, err, c.Name)
+			continue
+		}
+		if assert.IsType(t, handlers.ValidationError{}, err, c.Name) {
+			assert.Equal(t, c.Expected, err.(handlers.ValidationError).Violations, c.Name)
+		}
+	}
+}
+
+func TestCheckParam(t *testing.T) {
+	d := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	min := 1.0
+	var v validate.Validator
+	d.CheckParam(&v, openapi.Parameter{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer", Minimum: &min}}, "0", true)
+	d.CheckParam(&v, openapi.Parameter{Name: "sort", In: "query", Schema: openapi.Enum("name", "-name")}, "name", true)
+	d.CheckParam(&v, openapi.Parameter{Name: "is_completed", In: "query", Schema: openapi.Boolean()}, "yes", true)
+	d.CheckParam(&v, openapi.Parameter{Name: "taskId", In: "path", Required: true, Schema: openapi.String()}, "", false)
+	err := v.Err()
+	if assert.IsType(t, handlers.ValidationError{}, err) {
+		assert.Equal(t, []handlers.FieldViolation{
+			{Field: "limit", Rule: validate.RuleMin, Message: "must be at least 1"},
+			{Field: "is_completed", Rule: validate.RuleType, Message: "must be true or false"},
+			{Field: "taskId", Rule: validate.RuleRequired, Message: "is required"},
+		}, err.(handlers.ValidationError).Violations)
+	}
+}
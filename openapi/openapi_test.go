@@ -0,0 +1,69 @@
+package openapi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+type audit struct {
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type comment struct {
+	audit
+	ID       string    `json:"_id"`
+	Message  string    `json:"message,omitempty"`
+	Likes    int       `json:"likes,string"`
+	Replies  []comment `json:"replies"`
+	EditedBy *string   `json:"edited_by"`
+	Secret   string    `json:"-"`
+	internal string
+}
+
+func TestSchemaOf(t *testing.T) {
+	d := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	ref := d.SchemaOf(comment{})
+	assert.Equal(t, "#/components/schemas/Comment", ref.Ref)
+
+	s := d.Components.Schemas["Comment"]
+	assert.ElementsMatch(t, []string{"created_by", "created_at", "_id", "message", "likes", "replies", "edited_by"}, keys(s.Properties))
+	assert.Equal(t, "date-time", s.Properties["created_at"].Format)
+	assert.Equal(t, "string", s.Properties["likes"].Type)
+	assert.Equal(t, ref.Ref, s.Properties["replies"].Items.Ref)
+	assert.True(t, s.Properties["edited_by"].Nullable)
+
+	d.Describe("Likes", 0, openapi.Enum("a lot"))
+	assert.Equal(t, "#/components/schemas/Likes", d.SchemaOf(struct {
+		N int `json:"n"`
+	}{}).Properties["n"].Ref)
+}
+
+func TestAdd(t *testing.T) {
+	assert.Equal(t, "/v1/tasks/{taskId}", openapi.Path("/v1/tasks/:taskId"))
+	assert.Equal(t, "/v1/notes/{kickbackId}", openapi.Path("v1/notes/:kickbackId"))
+
+	d := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	d.Add("PUT", "/v1/chats/:kickbackId/channels/:channelId", openapi.Operation{
+		OperationID: "updateChannel",
+		Parameters:  []openapi.Parameter{{Name: "channelId", In: "path", Required: true, Description: "declared", Schema: openapi.String()}},
+	})
+	assert.True(t, d.Has("PUT", "/v1/chats/:kickbackId/channels/:channelId"))
+	assert.False(t, d.Has("GET", "/v1/chats/:kickbackId/channels/:channelId"))
+
+	params := d.Paths["/v1/chats/{kickbackId}/channels/{channelId}"]["put"].Parameters
+	assert.Len(t, params, 2)
+	assert.Equal(t, "kickbackId", params[0].Name)
+	assert.Equal(t, "declared", params[1].Description)
+}
+
+func keys(m map[string]*openapi.Schema) []string {
+	out := []string{}
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer", Format: "int64"} }
+func Number() *Schema  { return &Schema{Type: "number", Format: "double"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+// Any matches every JSON value
+func Any() *Schema { return &Schema{} }
+
+// Enum is a string schema restricted to values
+func Enum(values ...string) *Schema {
+	s := String()
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// MapOf is an object with arbitrary keys whose values match values
+func MapOf(values *Schema) *Schema {
+	return &Schema{Type: "object", AdditionalProperties: values}
+}
+
+// Object is an object with the given properties
+func Object(properties map[string]*Schema) *Schema {
+	return &Schema{Type: "object", Properties: properties}
+}
+
+// Ref points to a schema in the document's components
+func Ref(name string) *Schema {
+	return &Schema{Ref: refPrefix + name}
+}
+
+const refPrefix = "#/components/schemas/"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaOf generates the schema of the JSON encoding of v. Named structs are
+// added to the components and referenced, fields are read from their json
+// tags the same way encoding/json does.
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	if v == nil {
+		return Any()
+	}
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+// Define adds a named schema to the components and returns a reference to it
+func (d *Document) Define(name string, s *Schema) *Schema {
+	d.Components.Schemas[name] = s
+	return Ref(name)
+}
+
+// Component returns the definition generated for the named struct type of v
+// so constraints can be added to it, nil for other types
+func (d *Document) Component(v interface{}) *Schema {
+	ref := d.SchemaOf(v)
+	if ref.Ref == "" {
+		return nil
+	}
+	return d.Components.Schemas[strings.TrimPrefix(ref.Ref, refPrefix)]
+}
+
+// Describe defines the schema of the type of v instead of generating it, e.g.
+// to list the values of a string enum
+func (d *Document) Describe(name string, v interface{}, s *Schema) {
+	d.types[reflect.TypeOf(v)] = name
+	d.Define(name, s)
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if name, ok := d.types[t]; ok {
+		return Ref(name)
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return Any()
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// encodes itself, nothing to go by
+		return Any()
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return String()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := d.schemaOf(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return Number()
+	case reflect.String:
+		return String()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		s := ArrayOf(d.schemaOf(t.Elem()))
+		// nil slices and maps are encoded as null
+		s.Nullable = t.Kind() == reflect.Slice
+		return s
+	case reflect.Map:
+		s := MapOf(d.schemaOf(t.Elem()))
+		s.Nullable = true
+		return s
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := d.componentName(t)
+		d.types[t] = name
+		// registered before the fields are walked so recursive types end in a reference
+		d.Components.Schemas[name] = Any()
+		d.Components.Schemas[name] = d.structSchema(t)
+		return Ref(name)
+	}
+	return Any()
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := Object(map[string]*Schema{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// promoted like encoding/json does
+				for k, v := range d.structSchema(ft).Properties {
+					if _, ok := s.Properties[k]; !ok {
+						s.Properties[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(opts, "string") {
+			s.Properties[name] = String()
+		} else {
+			s.Properties[name] = d.schemaOf(ft)
+		}
+	}
+	return s
+}
+
+// componentName is the exported form of the type's name, prefixed with its
+// package when another type already took it
+func (d *Document) componentName(t reflect.Type) string {
+	r := []rune(t.Name())
+	r[0] = unicode.ToUpper(r[0])
+	name := string(r)
+	if _, taken := d.Components.Schemas[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		r := []rune(pkg)
+		r[0] = unicode.ToUpper(r[0])
+		name = string(r) + name
+	}
+	return name
+}
@@ -3,24 +3,65 @@ package server
 import (
 	"encoding/json"
 	"net/http"
+	"regexp"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/ratelimit"
+	"github.com/kickback-app/api/utils"
 )
 
+var e164PhoneNumber = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+// every OTP sent costs us an SMS, so keep these tight
+var (
+	otpSendPerPhone   = ratelimit.Every(3, 10*time.Minute)
+	otpSendPerIP      = ratelimit.Every(10, time.Hour)
+	otpSendGlobal     = ratelimit.Every(100, time.Minute)
+	otpVerifyPerPhone = ratelimit.Every(10, 10*time.Minute)
+	otpVerifyPerIP    = ratelimit.Every(30, time.Hour)
+)
+
+// lock a phone number out of verification after too many wrong codes
+const otpMaxFailedAttempts = 5
+const otpLockoutWindow = 15 * time.Minute
+
+type sendOTPRequest struct {
+	PhoneNumber string `json:"phoneNumber"`
+}
+
+type verifyOTPRequest struct {
+	PhoneNumber string `json:"phoneNumber"`
+	Code        string `json:"code"`
+}
+
+type rateLimitCheck struct {
+	key   string
+	limit ratelimit.Limit
+}
+
 func (s S) SendOTP(c *gin.Context) {
-	var reqBody struct {
-		PhoneNumber string `json:"phoneNumber"`
-	}
+	var reqBody sendOTPRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&reqBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "malformed request body"})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
-	if reqBody.PhoneNumber == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "phoneNumber must be a valid phone number"})
+	if !e164PhoneNumber.MatchString(reqBody.PhoneNumber) {
+		handlers.EncodeError(c, handlers.InvalidBodyFieldError{Field: "phoneNumber", Reason: "must be an E.164 phone number"})
+		return
+	}
+	err := s.throttle(c,
+		rateLimitCheck{key: "otp:send:phone:" + reqBody.PhoneNumber, limit: otpSendPerPhone},
+		rateLimitCheck{key: "otp:send:ip:" + c.ClientIP(), limit: otpSendPerIP},
+		rateLimitCheck{key: "otp:send:global", limit: otpSendGlobal},
+	)
+	if err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
-	err := s.IdentityVerificationService.SendOTP(c, reqBody.PhoneNumber)
+	err = s.IdentityVerificationService.SendOTP(c, reqBody.PhoneNumber)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
@@ -29,18 +70,121 @@ func (s S) SendOTP(c *gin.Context) {
 }
 
 func (s S) VerifyOTP(c *gin.Context) {
-	var reqBody struct {
-		PhoneNumber string `json:"phoneNumber"`
-		Code        string `json:"code"`
-	}
+	var reqBody verifyOTPRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&reqBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "malformed request body"})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if !e164PhoneNumber.MatchString(reqBody.PhoneNumber) {
+		handlers.EncodeError(c, handlers.InvalidBodyFieldError{Field: "phoneNumber", Reason: "must be an E.164 phone number"})
+		return
+	}
+	failedAttemptsKey := "otp:failed:" + reqBody.PhoneNumber
+	if err := s.checkLockout(c, failedAttemptsKey); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err := s.throttle(c,
+		rateLimitCheck{key: "otp:verify:phone:" + reqBody.PhoneNumber, limit: otpVerifyPerPhone},
+		rateLimitCheck{key: "otp:verify:ip:" + c.ClientIP(), limit: otpVerifyPerIP},
+	)
+	if err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
 	verificationReport, err := s.IdentityVerificationService.VerifyOTP(c, reqBody.PhoneNumber, reqBody.Code)
 	if err != nil {
+		s.recordFailedAttempt(c, failedAttemptsKey)
+		handlers.EncodeError(c, err)
+		return
+	}
+	if !otpApproved(verificationReport) {
+		s.recordFailedAttempt(c, failedAttemptsKey)
+		c.JSON(http.StatusOK, verificationReport)
+		return
+	}
+	if s.RateLimiter != nil {
+		if err := s.RateLimiter.Reset(failedAttemptsKey); err != nil {
+			logger.Warn(c, "unable to reset failed otp attempts: %v", err)
+		}
+	}
+	// people verifying their number to sign up don't have an account yet, they
+	// get the report alone and log in once they've signed up
+	user, err := s.UserService.GetUserByPhoneNumber(c, reqBody.PhoneNumber)
+	if err != nil {
+		logger.Info(c, "no user for verified phone number, not issuing tokens: %v", err)
+		c.JSON(http.StatusOK, verificationReport)
+		return
+	}
+	tokens, err := s.TokenIssuer.NewSession(user.ID)
+	if err != nil {
+		logger.Error(c, "unable to issue tokens: %v", err)
 		handlers.EncodeError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, verificationReport)
+	logger.Info(c, "issued new session for user %s", user.ID)
+	// the report keeps the shape clients already read, tokens are added to it
+	resp := utils.Normalize(verificationReport)
+	resp["tokens"] = tokens
+	c.JSON(http.StatusOK, resp)
+}
+
+// otpApproved reads the verifier's report the same way clients do: twilio marks
+// a correct code with valid=true and status=approved
+func otpApproved(report interface{}) bool {
+	m := utils.Normalize(report)
+	if valid, ok := m["valid"].(bool); ok {
+		return valid
+	}
+	status, _ := m["status"].(string)
+	return status == "approved"
+}
+
+// throttle takes a token from every bucket in checks. Limiter outages fail open
+// so a broken store can't lock everyone out of logging in.
+func (s S) throttle(c *gin.Context, checks ...rateLimitCheck) error {
+	if s.RateLimiter == nil {
+		return nil
+	}
+	for _, check := range checks {
+		allowed, retryAfter, err := s.RateLimiter.Allow(check.key, check.limit)
+		if err != nil {
+			logger.Error(c, "rate limiter unavailable: %v", err)
+			return nil
+		}
+		if !allowed {
+			logger.Warn(c, "rate limit exceeded for %s", check.key)
+			return handlers.TooManyRequestsError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+func (s S) checkLockout(c *gin.Context, key string) error {
+	if s.RateLimiter == nil {
+		return nil
+	}
+	failures, resetIn, err := s.RateLimiter.Count(key)
+	if err != nil {
+		logger.Error(c, "rate limiter unavailable: %v", err)
+		return nil
+	}
+	if failures >= otpMaxFailedAttempts {
+		return handlers.TooManyRequestsError{RetryAfter: resetIn}
+	}
+	return nil
+}
+
+func (s S) recordFailedAttempt(c *gin.Context, key string) {
+	if s.RateLimiter == nil {
+		return
+	}
+	failures, _, err := s.RateLimiter.Increment(key, otpLockoutWindow)
+	if err != nil {
+		logger.Error(c, "rate limiter unavailable: %v", err)
+		return
+	}
+	if failures >= otpMaxFailedAttempts {
+		logger.Warn(c, "locking out %s after %d failed otp attempts", key, failures)
+	}
 }
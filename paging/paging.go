@@ -0,0 +1,279 @@
+// Package paging implements the contract shared by the list endpoints:
+// ?limit=&cursor=&sort= plus filters specific to each endpoint. Cursors are
+// opaque to clients and hold the sort value and _id of the last item returned,
+// so pages don't shift when items are added or removed in between requests.
+//
+// Paging happens in memory: the services return whole listings and Apply
+// filters, sorts and pages them, so a limit bounds the response but not what
+// is read from the database.
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kickback-app/api/server/validate"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// idField breaks ties between items with the same sort value
+const idField = "_id"
+
+// Spec lists what an endpoint can be sorted and filtered by. Fields are the
+// JSON names of the listed model, a leading "-" sorts descending.
+type Spec struct {
+	Sorts       []string
+	DefaultSort string
+	Filters     []Filter
+}
+
+// Filter narrows a listing down with a query parameter
+type Filter struct {
+	Param string
+	// Values is shown to clients when the parameter can't be parsed, empty
+	// when any value is accepted
+	Values []string
+	match  func(doc map[string]interface{}, want string) bool
+}
+
+// Equal keeps items whose field named param is want
+func Equal(param string) Filter {
+	return Filter{Param: param, match: func(doc map[string]interface{}, want string) bool {
+		s, ok := doc[param].(string)
+		return ok && s == want
+	}}
+}
+
+// Bool keeps items whose boolean field named param is want, a missing field
+// counts as false
+func Bool(param string) Filter {
+	return Filter{Param: param, Values: []string{"true", "false"}, match: func(doc map[string]interface{}, want string) bool {
+		b, _ := doc[param].(bool)
+		return strconv.FormatBool(b) == want
+	}}
+}
+
+// Contains keeps items where want is one of the strings in field
+func Contains(param, field string) Filter {
+	return Filter{Param: param, match: func(doc map[string]interface{}, want string) bool {
+		values, _ := doc[field].([]interface{})
+		for _, v := range values {
+			if s, ok := v.(string); ok && s == want {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// ContainsWhere keeps items where field holds an object whose key is want,
+// e.g. the assignees of an expense
+func ContainsWhere(param, field, key string) Filter {
+	return Filter{Param: param, match: func(doc map[string]interface{}, want string) bool {
+		values, _ := doc[field].([]interface{})
+		for _, v := range values {
+			obj, _ := v.(map[string]interface{})
+			if s, ok := obj[key].(string); ok && s == want {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// Query is a parsed page request
+type Query struct {
+	Limit   int
+	Sort    string
+	Desc    bool
+	Filters map[string]string
+	spec    Spec
+	after   *position
+}
+
+// position is what a cursor decodes to
+type position struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// Parse reads the paging parameters of a request, every invalid parameter is
+// reported in a single handlers.ValidationError
+func Parse(params url.Values, spec Spec) (Query, error) {
+	var v validate.Validator
+	q := Query{Limit: DefaultLimit, Filters: map[string]string{}, spec: spec}
+
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			v.Add("limit", validate.RuleFormat, "must be a whole number")
+		} else {
+			v.Min("limit", float64(limit), 1)
+			v.Max("limit", float64(limit), MaxLimit)
+			q.Limit = limit
+		}
+	}
+
+	sortBy := params.Get("sort")
+	if sortBy == "" {
+		sortBy = spec.DefaultSort
+	}
+	q.Desc = strings.HasPrefix(sortBy, "-")
+	q.Sort = strings.TrimPrefix(sortBy, "-")
+	v.OneOf("sort", q.Sort, spec.Sorts)
+
+	if raw := params.Get("cursor"); raw != "" {
+		after, err := decodeCursor(raw)
+		if err != nil {
+			v.Add("cursor", validate.RuleFormat, "is not a cursor returned by this endpoint")
+		} else if after.Sort != sortBy {
+			v.Add("cursor", validate.RuleFormat, fmt.Sprintf("was issued for sort '%s', not '%s'", after.Sort, sortBy))
+		} else {
+			q.after = &after
+		}
+	}
+
+	for _, f := range spec.Filters {
+		want := params.Get(f.Param)
+		if want == "" {
+			continue
+		}
+		if len(f.Values) > 0 {
+			v.OneOf(f.Param, want, f.Values)
+		}
+		q.Filters[f.Param] = want
+	}
+	if err := v.Err(); err != nil {
+		return Query{}, err
+	}
+	return q, nil
+}
+
+// Apply filters, sorts and pages docs, the listed items as returned by
+// utils.Normalize. It returns the indices of the items on the page, in order,
+// and the cursor of the next page, which is empty on the last one.
+func (q Query) Apply(docs []map[string]interface{}) ([]int, string) {
+	matched := []int{}
+	for i, doc := range docs {
+		if q.matches(doc) {
+			matched = append(matched, i)
+		}
+	}
+	sort.SliceStable(matched, func(a, b int) bool {
+		return q.before(docs[matched[a]], docs[matched[b]][q.Sort], id(docs[matched[b]]))
+	})
+	start := 0
+	if q.after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return q.before(map[string]interface{}{q.Sort: q.after.Value, idField: q.after.ID}, docs[matched[i]][q.Sort], id(docs[matched[i]]))
+		})
+	}
+	end := start + q.Limit
+	if end >= len(matched) {
+		return matched[start:], ""
+	}
+	page := matched[start:end]
+	last := docs[page[len(page)-1]]
+	return page, q.encodeCursor(position{Value: last[q.Sort], ID: id(last)})
+}
+
+func (q Query) matches(doc map[string]interface{}) bool {
+	for _, f := range q.spec.Filters {
+		want, ok := q.Filters[f.Param]
+		if ok && !f.match(doc, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// before reports whether doc sorts strictly ahead of the item with value and
+// itemID
+func (q Query) before(doc map[string]interface{}, value interface{}, itemID string) bool {
+	cmp := compare(doc[q.Sort], value)
+	if cmp == 0 {
+		cmp = strings.Compare(id(doc), itemID)
+	}
+	if q.Desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+func (q Query) encodeCursor(p position) string {
+	p.Sort = q.Sort
+	if q.Desc {
+		p.Sort = "-" + q.Sort
+	}
+	b, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(raw string) (position, error) {
+	var p position
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return p, err
+	}
+	err = json.Unmarshal(b, &p)
+	return p, err
+}
+
+func id(doc map[string]interface{}) string {
+	s, _ := doc[idField].(string)
+	return s
+}
+
+// compare orders the JSON values of a sort field: missing values first, then
+// booleans, numbers and strings
+func compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch av := a.(type) {
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if !av {
+			return -1
+		}
+		return 1
+	case float64:
+		bv := b.(float64)
+		if av < bv {
+			return -1
+		}
+		if av > bv {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, b.(string))
+	}
+	return 0
+}
+
+func rank(v interface{}) int {
+	switch v.(type) {
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package paging_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/paging"
+	"github.com/stretchr/testify/assert"
+)
+
+var spec = paging.Spec{
+	Sorts:       []string{"created_at", "name"},
+	DefaultSort: "-created_at",
+	Filters: []paging.Filter{
+		paging.Bool("is_completed"),
+		paging.Contains("assignee", "assignees"),
+		paging.Equal("created_by"),
+	},
+}
+
+func docs() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"_id": "TSK_a", "name": "chips", "created_at": 100.0, "created_by": "USR_1", "assignees": []interface{}{"USR_2"}},
+		{"_id": "TSK_b", "name": "drinks", "created_at": 300.0, "created_by": "USR_2", "is_completed": true},
+		{"_id": "TSK_c", "name": "cups", "created_at": 200.0, "created_by": "USR_1", "assignees": []interface{}{"USR_1", "USR_2"}},
+		{"_id": "TSK_d", "name": "ice", "created_at": 200.0, "created_by": "USR_1"},
+	}
+}
+
+func ids(all []map[string]interface{}, page []int) []string {
+	out := []string{}
+	for _, i := range page {
+		out = append(out, all[i]["_id"].(string))
+	}
+	return out
+}
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Query    url.Values
+		Expected []string
+		HasNext  bool
+	}{
+		{
+			Name:     "defaults to the spec's sort",
+			Query:    url.Values{},
+			Expected: []string{"TSK_b", "TSK_d", "TSK_c", "TSK_a"},
+		},
+		{
+			Name:     "ascending sort with ties broken by id",
+			Query:    url.Values{"sort": {"created_at"}},
+			Expected: []string{"TSK_a", "TSK_c", "TSK_d", "TSK_b"},
+		},
+		{
+			Name:     "limit leaves a cursor",
+			Query:    url.Values{"sort": {"name"}, "limit": {"2"}},
+			Expected: []string{"TSK_a", "TSK_c"},
+			HasNext:  true,
+		},
+		{
+			Name:     "filters are combined",
+			Query:    url.Values{"assignee": {"USR_2"}, "created_by": {"USR_1"}},
+			Expected: []string{"TSK_c", "TSK_a"},
+		},
+		{
+			Name:     "missing booleans are false",
+			Query:    url.Values{"is_completed": {"false"}},
+			Expected: []string{"TSK_d", "TSK_c", "TSK_a"},
+		},
+	}
+	for i, c := range cases {
+		t.Logf("executing case %d: %v", i, c.Name)
+		q, err := paging.Parse(c.Query, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all := docs()
+		page, next := q.Apply(all)
+		assert.Equal(t, c.Expected, ids(all, page))
+		assert.Equal(t, c.HasNext, next != "")
+	}
+}
+
+func TestCursorWalksEveryPage(t *testing.T) {
+	all := docs()
+	seen := []string{}
+	params := url.Values{"limit": {"1"}}
+	for pages := 0; pages < 10; pages++ {
+		q, err := paging.Parse(params, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, next := q.Apply(all)
+		seen = append(seen, ids(all, page)...)
+		if next == "" {
+			break
+		}
+		params.Set("cursor", next)
+	}
+	assert.Equal(t, []string{"TSK_b", "TSK_d", "TSK_c", "TSK_a"}, seen)
+}
+
+func TestParseErrors(t *testing.T) {
+	q, err := paging.Parse(url.Values{"sort": {"created_at"}, "limit": {"1"}}, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, next := q.Apply(docs())
+
+	_, err = paging.Parse(url.Values{
+		"limit":        {"1000"},
+		"sort":         {"-assignees"},
+		"cursor":       {next},
+		"is_completed": {"yes"},
+	}, spec)
+	verr, ok := err.(handlers.ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	fields := []string{}
+	for _, v := range verr.Violations {
+		fields = append(fields, v.Field)
+	}
+	assert.Equal(t, []string{"limit", "sort", "cursor", "is_completed"}, fields)
+
+	_, err = paging.Parse(url.Values{"cursor": {"not a cursor"}}, spec)
+	assert.Error(t, err)
+}
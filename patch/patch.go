@@ -0,0 +1,106 @@
+// Package patch implements JSON Merge Patch (RFC 7396) for the PATCH routes.
+// Each resource whitelists the fields clients may patch, everything else
+// (ids, creators, timestamps, membership) can only change through the
+// dedicated endpoints.
+package patch
+
+import (
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/kickback-app/api/server/validate"
+)
+
+const ContentType = "application/merge-patch+json"
+
+// RuleNotPatchable is reported for fields outside the whitelist
+const RuleNotPatchable = "not_patchable"
+
+// AcceptsContentType reports whether a request body of contentType can be
+// read as a merge patch. Plain JSON and a missing header are accepted for
+// clients that don't set it.
+func AcceptsContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == ContentType || mediaType == "application/json"
+}
+
+// Merge applies patch to target as described in RFC 7396: objects are merged
+// recursively, null removes a member and any other value replaces it
+func Merge(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	merged := make(map[string]interface{}, len(targetObj))
+	for k, v := range targetObj {
+		merged[k] = v
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = Merge(merged[k], v)
+	}
+	return merged
+}
+
+// Check reports every member of doc that isn't in allowed as a single
+// handlers.ValidationError. Nested members are named with dots, e.g.
+// "chat.muted_channels", and allowing a member allows all of its children.
+func Check(doc map[string]interface{}, allowed []string) error {
+	var v validate.Validator
+	check(&v, doc, "", allowed)
+	return v.Err()
+}
+
+func check(v *validate.Validator, doc map[string]interface{}, prefix string, allowed []string) {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	// report in a stable order
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := prefix + k
+		if contains(allowed, path) {
+			continue
+		}
+		child, isObject := doc[k].(map[string]interface{})
+		if isObject && hasChildren(allowed, path) {
+			check(v, child, path+".", allowed)
+			continue
+		}
+		v.Add(path, RuleNotPatchable, fmt.Sprintf("can't be patched, patchable fields are %s", strings.Join(allowed, ", ")))
+	}
+}
+
+func contains(allowed []string, path string) bool {
+	for _, a := range allowed {
+		if a == path {
+			return true
+		}
+	}
+	return false
+}
+
+func hasChildren(allowed []string, path string) bool {
+	for _, a := range allowed {
+		if strings.HasPrefix(a, path+".") {
+			return true
+		}
+	}
+	return false
+}
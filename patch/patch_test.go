@@ -0,0 +1,74 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// examples from appendix A of RFC 7396
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		Target   string
+		Patch    string
+		Expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		merged := patch.Merge(decode(t, c.Target), decode(t, c.Patch))
+		assert.Equal(t, decode(t, c.Expected), merged, "%s + %s", c.Target, c.Patch)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	allowed := []string{"name", "chat.muted_channels"}
+	doc := decode(t, `{"name": "x", "chat": {"muted_channels": [], "open_channels": []}, "created_by": "USR_mock"}`).(map[string]interface{})
+	err := patch.Check(doc, allowed)
+	verr, ok := err.(handlers.ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	fields := []string{}
+	for _, v := range verr.Violations {
+		fields = append(fields, v.Field)
+		assert.Equal(t, patch.RuleNotPatchable, v.Rule)
+	}
+	assert.Equal(t, []string{"chat.open_channels", "created_by"}, fields)
+
+	doc = decode(t, `{"name": null, "chat": {"muted_channels": ["CHN_mock"]}}`).(map[string]interface{})
+	assert.NoError(t, patch.Check(doc, allowed))
+}
+
+func TestAcceptsContentType(t *testing.T) {
+	assert.True(t, patch.AcceptsContentType(""))
+	assert.True(t, patch.AcceptsContentType("application/merge-patch+json"))
+	assert.True(t, patch.AcceptsContentType("application/json; charset=utf-8"))
+	assert.False(t, patch.AcceptsContentType("application/json-patch+json"))
+	assert.False(t, patch.AcceptsContentType("text/plain"))
+}
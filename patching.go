@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/patch"
+	"github.com/kickback-app/api/utils"
+)
+
+// fields each PATCH route accepts, see patch.Check
+var (
+	eventPatchable         = []string{"name", "description"}
+	eventSettingsPatchable = []string{"chat.open_channels", "chat.muted_channels", "guests.max_per_member", "guests.require_names", "rsvp.deadline", "rsvp.reminders"}
+	taskPatchable          = []string{"name", "is_completed", "assignees"}
+	expensePatchable       = []string{"name", "assignees"}
+	mediaPatchable         = []string{"caption"}
+	channelPatchable       = []string{"name"}
+	userPatchable          = []string{"first_name", "last_name", "email", "profile_img_url", "venmo_username"}
+)
+
+// applyMergePatch applies the merge patch in the request body to current, the
+// resource as stored, and decodes the resulting document into patched so it
+// can be validated as a whole. The members the patch touched are decoded from
+// the result into updates, which is what gets persisted.
+func applyMergePatch(c *gin.Context, allowed []string, current, patched, updates interface{}) error {
+	contentType := c.GetHeader("Content-Type")
+	if !patch.AcceptsContentType(contentType) {
+		return handlers.UnsupportedMediaTypeError{ContentType: contentType, Supported: []string{patch.ContentType, "application/json"}}
+	}
+	var doc map[string]interface{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&doc); err != nil || doc == nil {
+		return handlers.MalformedBody(err)
+	}
+	if err := patch.Check(doc, allowed); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(patch.Merge(utils.Normalize(current), doc))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(merged, patched); err != nil {
+		return handlers.MalformedBody(err)
+	}
+	result := utils.Normalize(patched)
+	touched := map[string]interface{}{}
+	for k := range doc {
+		if v, ok := result[k]; ok {
+			touched[k] = v
+		}
+	}
+	b, err := json.Marshal(touched)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, updates); err != nil {
+		return handlers.MalformedBody(err)
+	}
+	return nil
+}
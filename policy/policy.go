@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/utils"
+)
+
+// Role is a user's relationship to a kickback, ordered from least to most
+// privileged
+type Role int
+
+const (
+	RoleOutsider Role = iota
+	RoleMember
+	RoleHost
+	RoleCreator
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleMember:
+		return "member"
+	case RoleHost:
+		return "host"
+	case RoleCreator:
+		return "creator"
+	}
+	return "outsider"
+}
+
+// Relationship describes how the caller relates to the kickback a request is
+// scoped to and, when the route targets a single resource (task, expense...),
+// whether the caller created it
+type Relationship struct {
+	Role    Role
+	Status  models.MemberStatus
+	IsOwner bool
+}
+
+// Resolve works out userID's relationship to event
+func Resolve(event models.Event, userID string) Relationship {
+	rel := Relationship{Role: RoleOutsider}
+	if userID == "" {
+		return rel
+	}
+	for _, m := range event.Members {
+		if m.UserID == userID {
+			rel.Role = RoleMember
+			rel.Status = m.Status
+			break
+		}
+	}
+	if utils.ContainsString(event.Hosts, userID) {
+		rel.Role = RoleHost
+	}
+	if event.CreatedBy == userID {
+		rel.Role = RoleCreator
+	}
+	return rel
+}
+
+// Rule decides whether a relationship is allowed to perform an action
+type Rule func(rel Relationship) bool
+
+// AtLeast allows role and anything more privileged
+func AtLeast(role Role) Rule {
+	return func(rel Relationship) bool {
+		return rel.Role >= role
+	}
+}
+
+// WithStatus allows members whose RSVP is one of statuses, hosts and the
+// creator are always allowed
+func WithStatus(statuses ...models.MemberStatus) Rule {
+	return func(rel Relationship) bool {
+		if rel.Role >= RoleHost {
+			return true
+		}
+		if rel.Role < RoleMember {
+			return false
+		}
+		for _, s := range statuses {
+			if rel.Status == s {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Owner allows the member who created the targeted resource
+func Owner() Rule {
+	return func(rel Relationship) bool {
+		return rel.Role >= RoleMember && rel.IsOwner
+	}
+}
+
+// AnyOf allows the action if any of rules does
+func AnyOf(rules ...Rule) Rule {
+	return func(rel Relationship) bool {
+		for _, r := range rules {
+			if r(rel) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var (
+	Members = AtLeast(RoleMember)
+	Hosts   = AtLeast(RoleHost)
+	Creator = AtLeast(RoleCreator)
+)
+
+// Table maps "METHOD /route/template" (as registered with gin) to the rule
+// guarding it. Routes that are not in the table are not scoped to a kickback
+// and are left alone.
+type Table map[string]Rule
+
+func (t Table) Lookup(method, fullPath string) (Rule, bool) {
+	r, ok := t[method+" "+fullPath]
+	return r, ok
+}
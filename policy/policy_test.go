@@ -0,0 +1,106 @@
+package policy_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockEvent = models.Event{
+	ID:        "EVT_Mock",
+	CreatedBy: "creator",
+	Hosts:     []string{"creator", "cohost"},
+	Members: []models.Member{
+		{UserID: "creator", Status: models.MemberStatusGoing},
+		{UserID: "cohost", Status: models.MemberStatusInvited},
+		{UserID: "guest", Status: models.MemberStatusGoing},
+		{UserID: "invitee", Status: models.MemberStatusInvited},
+	},
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		UserID   string
+		Expected policy.Relationship
+	}{
+		{UserID: "creator", Expected: policy.Relationship{Role: policy.RoleCreator, Status: models.MemberStatusGoing}},
+		{UserID: "cohost", Expected: policy.Relationship{Role: policy.RoleHost, Status: models.MemberStatusInvited}},
+		{UserID: "guest", Expected: policy.Relationship{Role: policy.RoleMember, Status: models.MemberStatusGoing}},
+		{UserID: "stranger", Expected: policy.Relationship{Role: policy.RoleOutsider}},
+		{UserID: "", Expected: policy.Relationship{Role: policy.RoleOutsider}},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.UserID)
+		assert.Equal(t, c.Expected, policy.Resolve(mockEvent, c.UserID))
+	}
+}
+
+func TestRules(t *testing.T) {
+	creator := policy.Resolve(mockEvent, "creator")
+	host := policy.Resolve(mockEvent, "cohost")
+	guest := policy.Resolve(mockEvent, "guest")
+	invitee := policy.Resolve(mockEvent, "invitee")
+	stranger := policy.Resolve(mockEvent, "stranger")
+	owningGuest := guest
+	owningGuest.IsOwner = true
+	owningStranger := stranger
+	owningStranger.IsOwner = true
+
+	cases := []struct {
+		Name     string
+		Rule     policy.Rule
+		Allowed  []policy.Relationship
+		Rejected []policy.Relationship
+	}{
+		{
+			Name:     "members",
+			Rule:     policy.Members,
+			Allowed:  []policy.Relationship{creator, host, guest, invitee},
+			Rejected: []policy.Relationship{stranger},
+		},
+		{
+			Name:     "hosts",
+			Rule:     policy.Hosts,
+			Allowed:  []policy.Relationship{creator, host},
+			Rejected: []policy.Relationship{guest, invitee, stranger},
+		},
+		{
+			Name:     "creator",
+			Rule:     policy.Creator,
+			Allowed:  []policy.Relationship{creator},
+			Rejected: []policy.Relationship{host, guest, stranger},
+		},
+		{
+			Name:     "hosts or owner",
+			Rule:     policy.AnyOf(policy.Hosts, policy.Owner()),
+			Allowed:  []policy.Relationship{creator, host, owningGuest},
+			Rejected: []policy.Relationship{guest, owningStranger},
+		},
+		{
+			Name:     "attending members",
+			Rule:     policy.WithStatus(models.MemberStatusGoing),
+			Allowed:  []policy.Relationship{creator, host, guest},
+			Rejected: []policy.Relationship{invitee, stranger},
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		for _, rel := range c.Allowed {
+			assert.True(t, c.Rule(rel), "%s should allow %+v", c.Name, rel)
+		}
+		for _, rel := range c.Rejected {
+			assert.False(t, c.Rule(rel), "%s should reject %+v", c.Name, rel)
+		}
+	}
+}
+
+func TestTableLookup(t *testing.T) {
+	table := policy.Table{"DELETE /v1/events/:eventId": policy.Creator}
+	_, ok := table.Lookup("DELETE", "/v1/events/:eventId")
+	assert.True(t, ok)
+	_, ok = table.Lookup("GET", "/v1/events/:eventId")
+	assert.False(t, ok)
+}
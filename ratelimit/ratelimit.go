@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Limit describes a token bucket: Burst requests can be made at once and the
+// bucket refills at Rate tokens per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every allows n requests per interval, all of which may be used at once
+func Every(n int, interval time.Duration) Limit {
+	return Limit{Rate: float64(n) / interval.Seconds(), Burst: n}
+}
+
+// Store keeps limiter state. DBStore shares it between instances, so a limit
+// caps the requests to all of them together rather than to each one.
+type Store interface {
+	// Allow takes a token from the bucket for key, when none is left it
+	// returns false and how long until the next one is available
+	Allow(key string, limit Limit) (bool, time.Duration, error)
+	// Increment bumps a counter that resets window after its first increment
+	Increment(key string, window time.Duration) (int, time.Duration, error)
+	// Count returns the current value of a counter and when it resets
+	Count(key string) (int, time.Duration, error)
+	Reset(key string) error
+}
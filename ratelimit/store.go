@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+// bucket is the state of a token bucket as of Last
+type bucket struct {
+	Tokens float64   `json:"tokens"`
+	Last   time.Time `json:"last"`
+	Rate   float64   `json:"rate"`
+	Burst  int       `json:"burst"`
+}
+
+// Expires is when the bucket is full again, it is then indistinguishable from
+// a new one
+func (b bucket) Expires() time.Time {
+	if b.Rate <= 0 {
+		return time.Time{}
+	}
+	refill := (float64(b.Burst) - b.Tokens) / b.Rate
+	return b.Last.Add(time.Duration(refill * float64(time.Second)))
+}
+
+type counter struct {
+	Count   int       `json:"count"`
+	ResetAt time.Time `json:"resetAt"`
+}
+
+func (c counter) Expires() time.Time {
+	return c.ResetAt
+}
+
+func bucketKey(key string) string  { return "bucket:" + key }
+func counterKey(key string) string { return "counter:" + key }
+
+// DBStore keeps the buckets and counters in the database, so limits apply
+// across every instance and survive deploys
+type DBStore struct {
+	docs docstore.Collection
+	now  func() time.Time
+}
+
+func NewDBStore(docs docstore.Collection) *DBStore {
+	return &DBStore{docs: docs, now: time.Now}
+}
+
+// NewMemoryStore keeps the limiter state in memory, for tests and single
+// instance deployments
+func NewMemoryStore() *DBStore {
+	return NewDBStore(docstore.NewMemory().Collection("rate_limits"))
+}
+
+func (d *DBStore) Allow(key string, limit Limit) (bool, time.Duration, error) {
+	var allowed bool
+	var wait time.Duration
+	err := docstore.Retry(func() error {
+		now := d.now()
+		b := bucket{Tokens: float64(limit.Burst), Last: now}
+		version, err := d.docs.Get(bucketKey(key), &b)
+		if err != nil && err != docstore.ErrNotFound {
+			return err
+		}
+		b.Rate, b.Burst = limit.Rate, limit.Burst
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+now.Sub(b.Last).Seconds()*limit.Rate)
+		b.Last = now
+		if b.Tokens < 1 {
+			allowed = false
+			if limit.Rate <= 0 {
+				wait = time.Duration(math.MaxInt64)
+			} else {
+				wait = time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+			}
+			return nil
+		}
+		b.Tokens--
+		allowed, wait = true, 0
+		_, err = d.docs.Put(bucketKey(key), version, b)
+		return err
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed, wait, nil
+}
+
+func (d *DBStore) Increment(key string, window time.Duration) (int, time.Duration, error) {
+	var c counter
+	var now time.Time
+	err := docstore.Retry(func() error {
+		now = d.now()
+		c = counter{}
+		version, err := d.docs.Get(counterKey(key), &c)
+		if err != nil && err != docstore.ErrNotFound {
+			return err
+		}
+		if err == docstore.ErrNotFound || !now.Before(c.ResetAt) {
+			c = counter{ResetAt: now.Add(window)}
+		}
+		c.Count++
+		_, err = d.docs.Put(counterKey(key), version, c)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return c.Count, c.ResetAt.Sub(now), nil
+}
+
+func (d *DBStore) Count(key string) (int, time.Duration, error) {
+	now := d.now()
+	var c counter
+	_, err := d.docs.Get(counterKey(key), &c)
+	if err == docstore.ErrNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if !now.Before(c.ResetAt) {
+		return 0, 0, nil
+	}
+	return c.Count, c.ResetAt.Sub(now), nil
+}
+
+func (d *DBStore) Reset(key string) error {
+	for _, id := range []string{bucketKey(key), counterKey(key)} {
+		err := docstore.Retry(func() error {
+			var doc map[string]interface{}
+			version, err := d.docs.Get(id, &doc)
+			if err == docstore.ErrNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			return d.docs.Delete(id, version)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+// newTestStore starts the clock at the wall time, the documents expire by it
+func newTestStore() (*DBStore, *fakeClock) {
+	clock := &fakeClock{t: time.Now()}
+	store := NewMemoryStore()
+	store.now = clock.now
+	return store, clock
+}
+
+func TestAllow(t *testing.T) {
+	store, clock := newTestStore()
+	limit := Every(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := store.Allow("phone", limit)
+		assert.NoError(t, err)
+		assert.True(t, allowed, "request %d should be allowed", i)
+	}
+	allowed, retryAfter, _ := store.Allow("phone", limit)
+	assert.False(t, allowed)
+	assert.Equal(t, 20*time.Second, retryAfter)
+
+	// other keys have their own bucket
+	allowed, _, _ = store.Allow("other-phone", limit)
+	assert.True(t, allowed)
+
+	clock.t = clock.t.Add(20 * time.Second)
+	allowed, _, _ = store.Allow("phone", limit)
+	assert.True(t, allowed)
+	allowed, _, _ = store.Allow("phone", limit)
+	assert.False(t, allowed)
+}
+
+func TestCounters(t *testing.T) {
+	store, clock := newTestStore()
+	for i := 1; i <= 3; i++ {
+		count, resetIn, err := store.Increment("attempts", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, i, count)
+		assert.Equal(t, time.Minute, resetIn)
+	}
+	clock.t = clock.t.Add(15 * time.Second)
+	count, resetIn, _ := store.Count("attempts")
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 45*time.Second, resetIn)
+
+	clock.t = clock.t.Add(45 * time.Second)
+	count, _, _ = store.Count("attempts")
+	assert.Equal(t, 0, count)
+
+	store.Increment("attempts", time.Minute)
+	store.Reset("attempts")
+	count, _, _ = store.Count("attempts")
+	assert.Equal(t, 0, count)
+}
+
+func TestInstancesShareLimits(t *testing.T) {
+	docs := docstore.NewMemory().Collection("rate_limits")
+	clock := &fakeClock{t: time.Now()}
+	first, second := NewDBStore(docs), NewDBStore(docs)
+	first.now, second.now = clock.now, clock.now
+	limit := Every(2, time.Minute)
+
+	allowed, _, _ := first.Allow("otp:send", limit)
+	assert.True(t, allowed)
+	allowed, _, _ = second.Allow("otp:send", limit)
+	assert.True(t, allowed)
+	allowed, _, _ = first.Allow("otp:send", limit)
+	assert.False(t, allowed, "the limit applies to both instances together")
+
+	first.Increment("attempts", time.Minute)
+	count, _, _ := second.Increment("attempts", time.Minute)
+	assert.Equal(t, 2, count)
+	second.Reset("attempts")
+	count, _, _ = first.Count("attempts")
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,367 @@
+// Package recurrence expands the iCalendar RRULE of recurring kickbacks into
+// occurrences. The supported subset covers what the apps let hosts pick: FREQ,
+// INTERVAL, COUNT, UNTIL, BYDAY (with an ordinal for monthly and yearly
+// rules), BYMONTHDAY and BYMONTH. WKST is accepted, weeks always start on
+// Monday.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	// zones are looked up by name, don't depend on the host having them
+	_ "time/tzdata"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// expansion gives up after this many periods, e.g. a rule only matching
+// February 30th never produces an occurrence
+const maxPeriods = 50000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Day is a BYDAY entry, N is the ordinal of the weekday within the month,
+// e.g. -1 for the last Friday, and 0 for every one of them
+type Day struct {
+	Weekday time.Weekday
+	N       int
+}
+
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []Day
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// ParseRule parses the value of an RRULE, with or without the "RRULE:" prefix.
+// A floating UNTIL, a date or a time without Z, is in loc like the start of
+// the schedule.
+func ParseRule(s string, loc *time.Location) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, fmt.Errorf("rule is empty")
+	}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("'%s' is not a NAME=VALUE pair", part)
+		}
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			if r.Freq != Daily && r.Freq != Weekly && r.Freq != Monthly && r.Freq != Yearly {
+				return r, fmt.Errorf("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value, loc)
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				day, dayErr := parseDay(d)
+				if dayErr != nil {
+					return r, dayErr
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, convErr := strconv.Atoi(d)
+				if convErr != nil || n == 0 || n < -31 || n > 31 {
+					return r, fmt.Errorf("BYMONTHDAY '%s' must be between 1 and 31 or -31 and -1", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, m := range strings.Split(value, ",") {
+				n, convErr := strconv.Atoi(m)
+				if convErr != nil || n < 1 || n > 12 {
+					return r, fmt.Errorf("BYMONTH '%s' must be between 1 and 12", m)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+		default:
+			return r, fmt.Errorf("%s is not supported", strings.ToUpper(name))
+		}
+		if err != nil {
+			return r, fmt.Errorf("%s %v", strings.ToUpper(name), err)
+		}
+	}
+	switch {
+	case r.Freq == "":
+		return r, fmt.Errorf("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return r, fmt.Errorf("COUNT and UNTIL can't both be set")
+	case r.Freq == Yearly && len(r.ByDay) > 0 && len(r.ByMonth) == 0:
+		return r, fmt.Errorf("BYDAY with FREQ=YEARLY requires BYMONTH")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return r, fmt.Errorf("BYDAY ordinals are only supported with FREQ=MONTHLY or YEARLY")
+		}
+	}
+	return r, nil
+}
+
+func positive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("must be a positive whole number")
+	}
+	return n, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		// the whole day is included
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("must be a date (20261231) or UTC time (20261231T235959Z)")
+}
+
+func parseDay(s string) (Day, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return Day{}, fmt.Errorf("BYDAY '%s' is not a weekday", s)
+	}
+	wd, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return Day{}, fmt.Errorf("BYDAY '%s' is not a weekday", s)
+	}
+	day := Day{Weekday: wd}
+	if ordinal := s[:len(s)-2]; ordinal != "" {
+		n, err := strconv.Atoi(ordinal)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return Day{}, fmt.Errorf("BYDAY '%s' has an invalid ordinal", s)
+		}
+		day.N = n
+	}
+	return day, nil
+}
+
+// String formats the rule as an RRULE value
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := []string{}
+		for _, d := range r.ByDay {
+			name := strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				name = strconv.Itoa(d.N) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		months := []int{}
+		for _, m := range r.ByMonth {
+			months = append(months, int(m))
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+// Starts returns the start of every occurrence before end, in order. The
+// first one is always start, the wall clock time of start in its location
+// is kept across daylight saving changes. yield may stop the expansion early
+// by returning false.
+func (r Rule) Starts(start, end time.Time, yield func(time.Time) bool) {
+	if !start.Before(end) || !yield(start) {
+		return
+	}
+	n := 1
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.candidates(start, period) {
+			if !t.After(start) {
+				continue
+			}
+			if (r.Count > 0 && n >= r.Count) || (!r.Until.IsZero() && t.After(r.Until)) || !t.Before(end) {
+				return
+			}
+			if !yield(t) {
+				return
+			}
+			n++
+		}
+	}
+}
+
+// candidates lists the starts in the nth period after the one start is in
+func (r Rule) candidates(start time.Time, n int) []time.Time {
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, loc)
+	}
+	var out []time.Time
+	switch r.Freq {
+	case Daily:
+		t := at(y, m, d+n*r.Interval)
+		if r.matchesMonth(t.Month()) && r.matchesWeekday(t.Weekday()) && r.matchesMonthDay(t) {
+			out = append(out, t)
+		}
+	case Weekly:
+		// Monday of the week start is in
+		monday := d - (int(start.Weekday())+6)%7 + 7*n*r.Interval
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []Day{{Weekday: start.Weekday()}}
+		}
+		for _, day := range days {
+			t := at(y, m, monday+(int(day.Weekday)+6)%7)
+			if r.matchesMonth(t.Month()) {
+				out = append(out, t)
+			}
+		}
+	case Monthly:
+		first := at(y, m+time.Month(n*r.Interval), 1)
+		if r.matchesMonth(first.Month()) {
+			out = r.inMonth(first, d, at)
+		}
+	case Yearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{m}
+		}
+		for _, month := range months {
+			out = append(out, r.inMonth(at(y+n*r.Interval, month, 1), d, at)...)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// inMonth lists the days of the month starting on first that the rule
+// matches, dayOfMonth is used when it has neither BYMONTHDAY nor BYDAY
+func (r Rule) inMonth(first time.Time, dayOfMonth int, at func(int, time.Month, int) time.Time) []time.Time {
+	y, m := first.Year(), first.Month()
+	last := at(y, m+1, 0).Day()
+	var out []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = last + 1 + md
+			}
+			if md >= 1 && md <= last {
+				t := at(y, m, md)
+				if r.matchesWeekday(t.Weekday()) {
+					out = append(out, t)
+				}
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, day := range r.ByDay {
+			// dates of every such weekday in the month
+			var dates []int
+			for md := 1 + (int(day.Weekday)-int(first.Weekday())+7)%7; md <= last; md += 7 {
+				dates = append(dates, md)
+			}
+			switch {
+			case day.N == 0:
+				for _, md := range dates {
+					out = append(out, at(y, m, md))
+				}
+			case day.N > 0 && day.N <= len(dates):
+				out = append(out, at(y, m, dates[day.N-1]))
+			case day.N < 0 && -day.N <= len(dates):
+				out = append(out, at(y, m, dates[len(dates)+day.N]))
+			}
+		}
+	case dayOfMonth <= last:
+		// months without the day are skipped, like RFC 5545 does
+		out = append(out, at(y, m, dayOfMonth))
+	}
+	return out
+}
+
+func (r Rule) matchesMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, month := range r.ByMonth {
+		if month == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesWeekday(wd time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, md := range r.ByMonthDay {
+		if md == t.Day() || last+1+md == t.Day() {
+			return true
+		}
+	}
+	return false
+}
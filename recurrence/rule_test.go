@@ -0,0 +1,120 @@
+package recurrence_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRule(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		Name          string
+		Rule          string
+		Location      *time.Location
+		ExpectedError string
+		Expected      string
+	}{
+		{Name: "weekly on two days", Rule: "RRULE:FREQ=WEEKLY;BYDAY=TU,TH;COUNT=6", Expected: "FREQ=WEEKLY;COUNT=6;BYDAY=TU,TH"},
+		{Name: "last friday of the month", Rule: "freq=monthly;byday=-1fr;wkst=MO", Expected: "FREQ=MONTHLY;BYDAY=-1FR"},
+		{Name: "until a date", Rule: "FREQ=DAILY;INTERVAL=2;UNTIL=20261231", Expected: "FREQ=DAILY;INTERVAL=2;UNTIL=20261231T235959Z"},
+		{Name: "until a date in the schedule's zone", Rule: "FREQ=DAILY;UNTIL=20261231", Location: ny, Expected: "FREQ=DAILY;UNTIL=20270101T045959Z"},
+		{Name: "until a floating time in the schedule's zone", Rule: "FREQ=DAILY;UNTIL=20261231T200000", Location: ny, Expected: "FREQ=DAILY;UNTIL=20270101T010000Z"},
+		{Name: "until a utc time", Rule: "FREQ=DAILY;UNTIL=20261231T200000Z", Location: ny, Expected: "FREQ=DAILY;UNTIL=20261231T200000Z"},
+		{Name: "missing freq", Rule: "COUNT=3", ExpectedError: "FREQ is required"},
+		{Name: "unsupported part", Rule: "FREQ=HOURLY", ExpectedError: "FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY"},
+		{Name: "unsupported by rule", Rule: "FREQ=DAILY;BYHOUR=9", ExpectedError: "BYHOUR is not supported"},
+		{Name: "count and until", Rule: "FREQ=DAILY;COUNT=2;UNTIL=20261231", ExpectedError: "COUNT and UNTIL can't both be set"},
+		{Name: "ordinal on a weekly rule", Rule: "FREQ=WEEKLY;BYDAY=2MO", ExpectedError: "BYDAY ordinals are only supported with FREQ=MONTHLY or YEARLY"},
+		{Name: "bad interval", Rule: "FREQ=DAILY;INTERVAL=0", ExpectedError: "INTERVAL must be a positive whole number"},
+	}
+	for i, c := range cases {
+		t.Logf("executing case %d: %v", i, c.Name)
+		loc := c.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		rule, err := recurrence.ParseRule(c.Rule, loc)
+		if c.ExpectedError != "" {
+			assert.EqualError(t, err, c.ExpectedError, c.Name)
+			continue
+		}
+		if assert.NoError(t, err, c.Name) {
+			assert.Equal(t, c.Expected, rule.String(), c.Name)
+		}
+	}
+}
+
+func TestStarts(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		Name     string
+		Rule     string
+		Start    time.Time
+		Expected []string
+	}{
+		{
+			Name:     "weekly keeps the wall clock time across daylight saving",
+			Rule:     "FREQ=WEEKLY;COUNT=3",
+			Start:    time.Date(2026, time.October, 24, 19, 0, 0, 0, ny),
+			Expected: []string{"2026-10-24 19:00 EDT", "2026-10-31 19:00 EDT", "2026-11-07 19:00 EST"},
+		},
+		{
+			Name:     "weekly on several days starting mid week",
+			Rule:     "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=4",
+			Start:    time.Date(2026, time.October, 15, 19, 0, 0, 0, time.UTC),
+			Expected: []string{"2026-10-15 19:00 UTC", "2026-10-20 19:00 UTC", "2026-10-22 19:00 UTC", "2026-10-27 19:00 UTC"},
+		},
+		{
+			Name:     "every other week",
+			Rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			Start:    time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC),
+			Expected: []string{"2026-10-16 20:00 UTC", "2026-10-30 20:00 UTC", "2026-11-13 20:00 UTC"},
+		},
+		{
+			Name:     "monthly skips months without the day",
+			Rule:     "FREQ=MONTHLY;COUNT=3",
+			Start:    time.Date(2027, time.January, 31, 18, 0, 0, 0, time.UTC),
+			Expected: []string{"2027-01-31 18:00 UTC", "2027-03-31 18:00 UTC", "2027-05-31 18:00 UTC"},
+		},
+		{
+			Name:     "last friday of the month",
+			Rule:     "FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20270101",
+			Start:    time.Date(2026, time.October, 30, 18, 0, 0, 0, time.UTC),
+			Expected: []string{"2026-10-30 18:00 UTC", "2026-11-27 18:00 UTC", "2026-12-25 18:00 UTC"},
+		},
+		{
+			Name:     "yearly on the first sunday of june",
+			Rule:     "FREQ=YEARLY;BYMONTH=6;BYDAY=1SU;COUNT=2",
+			Start:    time.Date(2027, time.June, 6, 12, 0, 0, 0, time.UTC),
+			Expected: []string{"2027-06-06 12:00 UTC", "2028-06-04 12:00 UTC"},
+		},
+		{
+			Name:     "start is always the first occurrence",
+			Rule:     "FREQ=WEEKLY;BYDAY=MO;COUNT=2",
+			Start:    time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC),
+			Expected: []string{"2026-10-16 20:00 UTC", "2026-10-19 20:00 UTC"},
+		},
+	}
+	for i, c := range cases {
+		t.Logf("executing case %d: %v", i, c.Name)
+		rule, err := recurrence.ParseRule(c.Rule, c.Start.Location())
+		if err != nil {
+			t.Fatal(err)
+		}
+		starts := []string{}
+		rule.Starts(c.Start, c.Start.AddDate(5, 0, 0), func(s time.Time) bool {
+			starts = append(starts, s.Format("2006-01-02 15:04 MST"))
+			return true
+		})
+		assert.Equal(t, c.Expected, starts, c.Name)
+	}
+}
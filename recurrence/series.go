@@ -0,0 +1,249 @@
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/validate"
+)
+
+// IDLayout formats occurrence IDs, the original start of the occurrence in
+// UTC, so they are stable when a single occurrence is moved
+const IDLayout = "20060102T150405Z"
+
+// Schedule is when a kickback happens. Without an RRule it happens once.
+type Schedule struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	TimeZone string    `json:"time_zone"`
+	RRule    string    `json:"rrule,omitempty"`
+}
+
+// Check reports every problem with the schedule, field names are prefixed
+// with prefix, e.g. "schedule."
+func (s Schedule) Check(v *validate.Validator, prefix string) {
+	v.Check(!s.Start.IsZero(), prefix+"start", validate.RuleRequired, "is required")
+	v.Check(s.End.After(s.Start), prefix+"end", validate.RuleMin, "must be after start")
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		v.Add(prefix+"time_zone", validate.RuleFormat, "is not an IANA time zone, e.g. America/New_York")
+	}
+	if s.RRule != "" {
+		if _, err := ParseRule(s.RRule, s.Location()); err != nil {
+			v.Add(prefix+"rrule", validate.RuleFormat, err.Error())
+		}
+	}
+}
+
+// Location is the time zone the wall clock times of the occurrences are kept in
+func (s Schedule) Location() *time.Location {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Rule is the parsed RRule, a schedule without one occurs once
+func (s Schedule) Rule() Rule {
+	rule, err := ParseRule(s.RRule, s.Location())
+	if s.RRule == "" || err != nil {
+		return Rule{Freq: Daily, Interval: 1, Count: 1}
+	}
+	return rule
+}
+
+// Exception overrides a single occurrence. A nil Start keeps the occurrence
+// at its scheduled time, a nil Updates keeps the kickback's details.
+type Exception struct {
+	Cancelled bool                 `json:"cancelled"`
+	Start     *time.Time           `json:"start,omitempty"`
+	End       *time.Time           `json:"end,omitempty"`
+	Updates   *models.EventUpdates `json:"updates,omitempty"`
+}
+
+// Series is the schedule of a kickback along with what was changed for
+// single occurrences, keyed by occurrence ID
+type Series struct {
+	EventID    string                                    `json:"eventId"`
+	Schedule   Schedule                                  `json:"schedule"`
+	Exceptions map[string]Exception                      `json:"exceptions"`
+	RSVPs      map[string]map[string]models.MemberStatus `json:"rsvps"`
+	// Version is what the series was read at, see Store
+	Version int64 `json:"-"`
+}
+
+func NewSeries(eventID string, schedule Schedule) Series {
+	return Series{
+		EventID:    eventID,
+		Schedule:   schedule,
+		Exceptions: map[string]Exception{},
+		RSVPs:      map[string]map[string]models.MemberStatus{},
+	}
+}
+
+// Occurrence is a single instance of a kickback. RSVPs only lists the members
+// who answered for this occurrence.
+type Occurrence struct {
+	ID        string                         `json:"occurrenceId"`
+	Start     time.Time                      `json:"start"`
+	End       time.Time                      `json:"end"`
+	Cancelled bool                           `json:"cancelled"`
+	Moved     bool                           `json:"moved"`
+	Overrides *models.EventUpdates           `json:"overrides,omitempty"`
+	RSVPs     map[string]models.MemberStatus `json:"rsvps"`
+}
+
+// OccurrenceID is the ID of the occurrence scheduled to start at start
+func OccurrenceID(start time.Time) string {
+	return start.UTC().Format(IDLayout)
+}
+
+func (s Series) duration() time.Duration {
+	return s.Schedule.End.Sub(s.Schedule.Start)
+}
+
+func (s Series) occurrence(start time.Time) Occurrence {
+	id := OccurrenceID(start)
+	o := Occurrence{
+		ID:    id,
+		Start: start,
+		End:   start.Add(s.duration()),
+		RSVPs: map[string]models.MemberStatus{},
+	}
+	for userID, status := range s.RSVPs[id] {
+		o.RSVPs[userID] = status
+	}
+	if e, ok := s.Exceptions[id]; ok {
+		o.Cancelled = e.Cancelled
+		o.Overrides = e.Updates
+		if e.Start != nil {
+			o.Moved = true
+			o.Start = e.Start.In(start.Location())
+			o.End = o.Start.Add(s.duration())
+		}
+		if e.End != nil {
+			o.End = e.End.In(start.Location())
+		}
+	}
+	return o
+}
+
+// Between lists the occurrences that overlap [from, to) ordered by start,
+// cancelled ones included. Moved occurrences are listed where they were moved
+// to.
+func (s Series) Between(from, to time.Time) []Occurrence {
+	rule := s.Schedule.Rule()
+	start := s.Schedule.Start.In(s.Schedule.Location())
+	found := map[string]bool{}
+	occurrences := []Occurrence{}
+	add := func(o Occurrence) {
+		if !found[o.ID] && o.Start.Before(to) && o.End.After(from) {
+			found[o.ID] = true
+			occurrences = append(occurrences, o)
+		}
+	}
+	earliest := from.Add(-s.duration())
+	rule.Starts(start, to, func(t time.Time) bool {
+		if t.After(earliest) {
+			add(s.occurrence(t))
+		}
+		return true
+	})
+	for id, e := range s.Exceptions {
+		if e.Start == nil || found[id] {
+			continue
+		}
+		if o, ok := s.Find(id); ok {
+			add(o)
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Start.Before(occurrences[j].Start) })
+	return occurrences
+}
+
+// Find returns the occurrence with id, false when the schedule doesn't have one
+func (s Series) Find(id string) (Occurrence, bool) {
+	t, err := time.Parse(IDLayout, id)
+	if err != nil {
+		return Occurrence{}, false
+	}
+	start := s.Schedule.Start.In(s.Schedule.Location())
+	var match time.Time
+	s.Schedule.Rule().Starts(start, t.Add(time.Second), func(candidate time.Time) bool {
+		if candidate.Equal(t) {
+			match = candidate
+			return false
+		}
+		return true
+	})
+	if match.IsZero() {
+		return Occurrence{}, false
+	}
+	return s.occurrence(match), true
+}
+
+// Prune forgets the exceptions and RSVPs of occurrences the schedule no
+// longer has, e.g. after it was changed
+func (s *Series) Prune() {
+	for id := range s.Exceptions {
+		if _, ok := s.Find(id); !ok {
+			delete(s.Exceptions, id)
+		}
+	}
+	for id := range s.RSVPs {
+		if _, ok := s.Find(id); !ok {
+			delete(s.RSVPs, id)
+		}
+	}
+}
+
+// Split ends the series before the occurrence with id and returns the series
+// made of it and the ones that follow, which keeps their exceptions and
+// RSVPs. The returned series has no EventID yet.
+func (s *Series) Split(id string) (Series, error) {
+	o, ok := s.Find(id)
+	if !ok {
+		return Series{}, fmt.Errorf("occurrence %s is not part of the schedule", id)
+	}
+	original, _ := time.Parse(IDLayout, id)
+	original = original.In(s.Schedule.Location())
+	if !original.After(s.Schedule.Start) {
+		return Series{}, fmt.Errorf("occurrence %s is the first of the series", id)
+	}
+	rule := s.Schedule.Rule()
+	before := 0
+	rule.Starts(s.Schedule.Start.In(s.Schedule.Location()), original, func(time.Time) bool {
+		before++
+		return true
+	})
+	tail := NewSeries("", Schedule{
+		Start:    original,
+		End:      original.Add(s.duration()),
+		TimeZone: s.Schedule.TimeZone,
+	})
+	tailRule := rule
+	head := rule
+	if rule.Count > 0 {
+		tailRule.Count = rule.Count - before
+		head.Count = before
+	} else {
+		head.Until = original.Add(-time.Second).UTC()
+	}
+	tail.Schedule.RRule = tailRule.String()
+	s.Schedule.RRule = head.String()
+	for occurrenceID, e := range s.Exceptions {
+		if occurrenceID >= o.ID {
+			tail.Exceptions[occurrenceID] = e
+			delete(s.Exceptions, occurrenceID)
+		}
+	}
+	for occurrenceID, rsvps := range s.RSVPs {
+		if occurrenceID >= o.ID {
+			tail.RSVPs[occurrenceID] = rsvps
+			delete(s.RSVPs, occurrenceID)
+		}
+	}
+	return tail, nil
+}
@@ -0,0 +1,100 @@
+package recurrence_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/stretchr/testify/assert"
+)
+
+func gameNight() recurrence.Series {
+	// every friday at 8pm in new york, starting 2026-10-16
+	return recurrence.NewSeries("EVT_1", recurrence.Schedule{
+		Start:    time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2026, time.October, 17, 3, 0, 0, 0, time.UTC),
+		TimeZone: "America/New_York",
+		RRule:    "FREQ=WEEKLY;BYDAY=FR",
+	})
+}
+
+func ids(occurrences []recurrence.Occurrence) []string {
+	out := []string{}
+	for _, o := range occurrences {
+		out = append(out, o.ID)
+	}
+	return out
+}
+
+func TestBetween(t *testing.T) {
+	series := gameNight()
+	moved := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	series.Exceptions["20261024T000000Z"] = recurrence.Exception{Cancelled: true}
+	// the 30th is moved to the next evening
+	series.Exceptions["20261031T000000Z"] = recurrence.Exception{Start: &moved}
+	series.RSVPs["20261107T010000Z"] = map[string]models.MemberStatus{"USR_1": models.MemberStatusGoing}
+
+	from := time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.November, 10, 0, 0, 0, 0, time.UTC)
+	occurrences := series.Between(from, to)
+	// the 6th of november is after the switch to EST, the wall clock time is kept
+	assert.Equal(t, []string{"20261024T000000Z", "20261031T000000Z", "20261107T010000Z"}, ids(occurrences))
+	assert.True(t, occurrences[0].Cancelled)
+	assert.True(t, occurrences[1].Moved)
+	assert.Equal(t, "2026-10-31 20:00 EDT", occurrences[1].Start.Format("2006-01-02 15:04 MST"))
+	assert.Equal(t, models.MemberStatusGoing, occurrences[2].RSVPs["USR_1"])
+
+	// moved out of the window it was scheduled in
+	occurrences = series.Between(moved, moved.Add(time.Hour))
+	assert.Equal(t, []string{"20261031T000000Z"}, ids(occurrences))
+
+	_, ok := series.Find("20261025T000000Z")
+	assert.False(t, ok)
+}
+
+func TestSplit(t *testing.T) {
+	series := gameNight()
+	series.Schedule.RRule = "FREQ=WEEKLY;BYDAY=FR;COUNT=5"
+	series.RSVPs["20261017T000000Z"] = map[string]models.MemberStatus{"USR_1": models.MemberStatusGoing}
+	series.RSVPs["20261107T010000Z"] = map[string]models.MemberStatus{"USR_1": models.MemberStatusMaybe}
+
+	_, err := series.Split("20261017T000000Z")
+	assert.EqualError(t, err, "occurrence 20261017T000000Z is the first of the series")
+
+	tail, err := series.Split("20261031T000000Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=2;BYDAY=FR", series.Schedule.RRule)
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=3;BYDAY=FR", tail.Schedule.RRule)
+	everything := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"20261017T000000Z", "20261024T000000Z"}, ids(series.Between(series.Schedule.Start, everything)))
+	assert.Equal(t, []string{"20261031T000000Z", "20261107T010000Z", "20261114T010000Z"}, ids(tail.Between(tail.Schedule.Start, everything)))
+	assert.Len(t, series.RSVPs, 1)
+	assert.Equal(t, models.MemberStatusMaybe, tail.RSVPs["20261107T010000Z"]["USR_1"])
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := recurrence.NewMemoryStore()
+	_, err := store.Get("EVT_1")
+	assert.ErrorIs(t, err, recurrence.ErrNotFound)
+
+	series := gameNight()
+	assert.NoError(t, store.Save(series))
+	got, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	got.Exceptions["20261024T000000Z"] = recurrence.Exception{Cancelled: true}
+	again, _ := store.Get("EVT_1")
+	assert.Empty(t, again.Exceptions, "changes aren't kept until saved")
+
+	// got was read before again was saved
+	assert.NoError(t, store.Save(again))
+	assert.ErrorIs(t, store.Save(got), docstore.ErrConflict)
+	assert.ErrorIs(t, store.Save(gameNight()), docstore.ErrConflict, "a new series can't replace a stored one")
+
+	assert.NoError(t, store.Delete("EVT_1"))
+	_, err = store.Get("EVT_1")
+	assert.ErrorIs(t, err, recurrence.ErrNotFound)
+}
@@ -0,0 +1,73 @@
+package recurrence
+
+import (
+	"errors"
+
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/docstore"
+)
+
+var ErrNotFound = errors.New("schedule not found")
+
+// Store persists the series of recurring kickbacks keyed by event ID. Save
+// only applies while the stored series is still at the Version it was read
+// at, and a new series only while there is none, otherwise it returns
+// docstore.ErrConflict.
+type Store interface {
+	Get(eventID string) (Series, error)
+	Save(series Series) error
+	Delete(eventID string) error
+}
+
+// DBStore keeps the series in the database, shared by every instance
+type DBStore struct {
+	docs docstore.Collection
+}
+
+func NewDBStore(docs docstore.Collection) *DBStore {
+	return &DBStore{docs: docs}
+}
+
+// NewMemoryStore keeps the series in memory, for tests and single instance
+// deployments
+func NewMemoryStore() *DBStore {
+	return NewDBStore(docstore.NewMemory().Collection("schedules"))
+}
+
+func (d *DBStore) Get(eventID string) (Series, error) {
+	var series Series
+	version, err := d.docs.Get(eventID, &series)
+	if err == docstore.ErrNotFound {
+		return Series{}, ErrNotFound
+	}
+	if err != nil {
+		return Series{}, err
+	}
+	series.Version = version
+	if series.Exceptions == nil {
+		series.Exceptions = map[string]Exception{}
+	}
+	if series.RSVPs == nil {
+		series.RSVPs = map[string]map[string]models.MemberStatus{}
+	}
+	return series, nil
+}
+
+func (d *DBStore) Save(series Series) error {
+	_, err := d.docs.Put(series.EventID, series.Version, series)
+	return err
+}
+
+func (d *DBStore) Delete(eventID string) error {
+	return docstore.Retry(func() error {
+		var series Series
+		version, err := d.docs.Get(eventID, &series)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.docs.Delete(eventID, version)
+	})
+}
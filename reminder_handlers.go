@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/metrics"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/kickback-app/api/server/waitlist"
+)
+
+// memberStatusNoResponse is how members who were still invited when the RSVP
+// deadline passed are shown, see withNoResponse
+const memberStatusNoResponse models.MemberStatus = "no_response"
+
+// the earliest a reminder can go out, in hours before the deadline
+const maxReminderHours = 90 * 24
+
+// deadlineLayout is how the deadline reads in reminders, in the time zone the
+// hosts set it in
+const deadlineLayout = "Mon Jan 2 at 3:04 PM MST"
+
+func checkRSVPPolicy(policy reminders.Policy, now time.Time) error {
+	var v validate.Validator
+	if policy.Deadline != nil {
+		v.Check(policy.Deadline.After(now), "rsvp.deadline", validate.RuleMin, "must be in the future")
+	}
+	hours := make([]string, 0, len(policy.Reminders))
+	for i, r := range policy.Reminders {
+		field := fmt.Sprintf("rsvp.reminders[%d]", i)
+		v.Min(field+".hours_before", float64(r.HoursBefore), 1)
+		v.Max(field+".hours_before", float64(r.HoursBefore), maxReminderHours)
+		v.Check(len(r.Channels) > 0, field+".channels", validate.RuleRequired, "is required")
+		for j, channel := range r.Channels {
+			v.OneOf(fmt.Sprintf("%s.channels[%d]", field, j), channel, []string{"push", "sms"})
+		}
+		hours = append(hours, fmt.Sprint(r.HoursBefore))
+	}
+	v.Unique("rsvp.reminders", hours)
+	return v.Err()
+}
+
+// takeRSVPPolicy removes the RSVP deadline from the settings of a PUT and
+// returns it, nil when the settings don't change it
+func takeRSVPPolicy(settings models.M) (*reminders.Policy, error) {
+	var policy reminders.Policy
+	ok, err := takeSetting(settings, "rsvp", &policy)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return &policy, checkRSVPPolicy(policy, time.Now())
+}
+
+// rsvpPolicy is the RSVP deadline of the event, the zero policy when it has none
+func (s S) rsvpPolicy(eventID string) (reminders.Policy, error) {
+	schedule, err := s.Deadlines.Get(eventID)
+	if err == reminders.ErrNotFound {
+		return reminders.Policy{}, nil
+	}
+	return schedule.Policy, err
+}
+
+// deadlineConflict asks the client to retry a change to the RSVP deadline
+// that kept losing to other changes of it
+func deadlineConflict(err error) error {
+	if err == docstore.ErrConflict {
+		return handlers.ConflictError{Resource: "rsvp deadline"}
+	}
+	return err
+}
+
+// saveRSVPPolicy sets the RSVP deadline of the event, without a deadline
+// members can answer at any time
+func (s S) saveRSVPPolicy(eventID string, policy reminders.Policy) error {
+	if policy.Deadline == nil {
+		return s.Deadlines.Delete(eventID)
+	}
+	err := docstore.Retry(func() error {
+		schedule, err := s.Deadlines.Get(eventID)
+		switch {
+		case err == reminders.ErrNotFound:
+			schedule = reminders.New(eventID, policy)
+		case err != nil:
+			return err
+		default:
+			schedule.SetPolicy(policy)
+		}
+		return s.Deadlines.Save(schedule)
+	})
+	return deadlineConflict(err)
+}
+
+// checkRSVPOpen rejects answers that say the member is coming once the RSVP
+// deadline of the event passed, they can still say they aren't
+func (s S) checkRSVPOpen(eventID string, status models.MemberStatus) error {
+	if status != models.MemberStatusGoing && status != models.MemberStatusMaybe {
+		return nil
+	}
+	schedule, err := s.Deadlines.Get(eventID)
+	if err == reminders.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !schedule.Open(time.Now()) {
+		return handlers.RSVPClosedError{Deadline: *schedule.Policy.Deadline}
+	}
+	return nil
+}
+
+// runReminders sends the reminders that are due and closes the deadlines that
+// passed every interval, until ctx is done
+func (s S) runReminders(ctx context.Context) {
+	interval := s.config.Reminders.Interval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.lifecycle.goBackground(backgroundContext(), func(c *gin.Context) {
+				s.processDeadlines(c, now)
+			})
+		}
+	}
+}
+
+// processDeadlines goes over every event with an open RSVP deadline
+func (s S) processDeadlines(c *gin.Context, now time.Time) {
+	pending, err := s.Deadlines.Pending()
+	if err != nil {
+		logger.Error(c, "unable to list rsvp deadlines: %v", err)
+		return
+	}
+	for _, schedule := range pending {
+		if err := s.processDeadline(c, schedule.EventID, now); err != nil {
+			logger.Error(c, "unable to process the rsvp deadline of event %s: %v", schedule.EventID, err)
+		}
+	}
+}
+
+// processDeadline sends the reminder of the event that is due at now, or
+// closes the deadline with the members who never answered once it passed. The
+// schedule is saved before anyone is reminded, so of the instances processing
+// it at once only one goes on, and a deadline the hosts moved meanwhile waits
+// for the next run.
+func (s S) processDeadline(c *gin.Context, eventID string, now time.Time) error {
+	schedule, err := s.Deadlines.Get(eventID)
+	if err == reminders.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	due, closing := schedule.Due(now)
+	if due == nil && !closing {
+		return nil
+	}
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		return err
+	}
+	unanswered, err := s.unansweredMembers(event)
+	if err != nil {
+		return err
+	}
+	if closing {
+		schedule.Close(unanswered)
+	} else {
+		schedule.MarkSent(*due)
+	}
+	if err := s.Deadlines.Save(schedule); err == docstore.ErrConflict {
+		logger.Info(c, "the rsvp deadline of event %s changed while it was processed, skipping it until the next run", eventID)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !closing {
+		s.remind(c, event, *schedule.Policy.Deadline, *due, unanswered)
+		logger.Info(c, "reminded %d members of event %s to rsvp %d hours before the deadline", len(unanswered), eventID, due.HoursBefore)
+		return nil
+	}
+	for range unanswered {
+		metrics.RSVP(string(memberStatusNoResponse), "deadline")
+	}
+	logger.Info(c, "closed rsvps for event %s, %d members never responded", eventID, len(unanswered))
+	return nil
+}
+
+// unansweredMembers are the invited members of the event who haven't
+// answered, neither by joining its waitlist nor for one of its occurrences
+func (s S) unansweredMembers(event models.Event) ([]string, error) {
+	list, err := s.Waitlists.Get(event.ID)
+	if err != nil && err != waitlist.ErrNotFound {
+		return nil, err
+	}
+	series, err := s.Schedules.Get(event.ID)
+	if err != nil && err != recurrence.ErrNotFound {
+		return nil, err
+	}
+	unanswered := []string{}
+	for _, m := range event.Members {
+		if m.Status != models.MemberStatusInvited || list.Position(m.UserID) != 0 {
+			continue
+		}
+		answered := false
+		for _, rsvps := range series.RSVPs {
+			if _, ok := rsvps[m.UserID]; ok {
+				answered = true
+				break
+			}
+		}
+		if !answered {
+			unanswered = append(unanswered, m.UserID)
+		}
+	}
+	return unanswered, nil
+}
+
+// withNoResponse shows the members of the event who were still invited when
+// its RSVP deadline passed as no_response. The deadline keeps them rather than
+// the event, whose service only takes the statuses of models.
+func (s S) withNoResponse(c *gin.Context, event models.Event) models.Event {
+	if s.Deadlines == nil {
+		return event
+	}
+	schedule, err := s.Deadlines.Get(event.ID)
+	if err != nil {
+		if err != reminders.ErrNotFound {
+			logger.Warn(c, "unable to read the rsvp deadline of event %s: %v", event.ID, err)
+		}
+		return event
+	}
+	if len(schedule.NoResponse) == 0 {
+		return event
+	}
+	members := make([]models.Member, len(event.Members))
+	for i, m := range event.Members {
+		if m.Status == models.MemberStatusInvited && schedule.NotResponding(m.UserID) {
+			m.Status = memberStatusNoResponse
+		}
+		members[i] = m
+	}
+	event.Members = members
+	return event
+}
+
+// remind asks the members who haven't answered to RSVP, the text carries
+// their personal RSVP link
+func (s S) remind(c *gin.Context, event models.Event, deadline time.Time, r reminders.Reminder, userIDs []string) {
+	body := fmt.Sprintf("Let the hosts of %v know if you're coming by %v", event.Name, deadline.Format(deadlineLayout))
+	for _, userID := range userIDs {
+		notification := models.Notification{
+			Type:     models.EventRSVP,
+			Channels: r.Channels,
+			To:       []string{userID},
+			Title:    "Don't forget to RSVP",
+			Body:     body,
+			Data: map[string]string{
+				"eventId": event.ID,
+			},
+		}
+		if link, err := s.rsvpLink(event.ID, userID); err == nil {
+			notification.SMSmessage = fmt.Sprintf("%v\n\n%v", body, link)
+		} else {
+			logger.Error(c, "unable to create rsvp link for %s: %v", userID, err)
+			notification.SMSmessage = body
+		}
+		if _, _, err := s.doSendNotification(c, notification); err != nil {
+			logger.Error(c, "unable to remind %s to rsvp to event %s: %v", userID, event.ID, err)
+		}
+	}
+}
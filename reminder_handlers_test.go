@@ -0,0 +1,297 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/internal/services"
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/waitlist"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestReminderHandlers(t *testing.T) {
+	store := reminders.NewMemoryStore()
+	passed := time.Now().Add(-time.Hour)
+	assert.NoError(t, store.Save(reminders.New("mockEventId", reminders.Policy{Deadline: &passed})))
+	mockEvent := `{"_id": "mockEventId", "name": "Game night", "createdBy": "mockHostId", "members": [
+		{"userId": "mockHostId", "status": "going"},
+		{"userId": "mockUserId", "status": "not_going"}
+	]}`
+	mockServer := server.S{
+		EventService: services.EventService{
+			DBClient: utils.MockDBClient{
+				CallCount: new(int),
+				Responses: []interface{}{int64(1), mockEvent},
+			},
+		},
+		UserService: services.UserService{
+			DBClient: utils.MockDBClient{
+				CallCount:       new(int),
+				DefaultResponse: `{"_id": "mockUserId", "first_name": "Mock"}`,
+			},
+			Cache: &utils.MockCache{
+				Callcount: new(int),
+				Items:     map[string]interface{}{},
+			},
+		},
+		NotificationService: services.NotificationService{
+			DBClient: utils.MockDBClient{
+				CallCount:       new(int),
+				DefaultResponse: "NTF_Mock",
+			},
+		},
+		Deadlines: store,
+		Waitlists: waitlist.NewMemoryStore(),
+		Guests:    guests.NewMemoryStore(),
+	}
+	deadline := time.Now().AddDate(0, 0, 7).UTC().Truncate(time.Second)
+	cases := []struct {
+		Name               string
+		Method             string
+		Body               string
+		Handler            func(server.S) gin.HandlerFunc
+		ExpectedStatusCode int
+		PathToResult       string
+		ExpectedResult     string
+	}{
+		{
+			Name:               "members can't answer no_response",
+			Method:             http.MethodPut,
+			Body:               `{"status": "no_response"}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.RSVP },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.0.field",
+			ExpectedResult:     `"status"`,
+		},
+		{
+			Name:               "rsvps are closed after the deadline",
+			Method:             http.MethodPut,
+			Body:               `{"status": "going"}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.RSVP },
+			ExpectedStatusCode: http.StatusConflict,
+			PathToResult:       "meta.error.errorCode",
+			ExpectedResult:     `"RSVP_CLOSED"`,
+		},
+		{
+			Name:               "happy path - members can still say they aren't coming after the deadline",
+			Method:             http.MethodPut,
+			Body:               `{"status": "not_going"}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.RSVP },
+			ExpectedStatusCode: http.StatusOK,
+			PathToResult:       "result.mockUserId",
+			ExpectedResult:     `"not_going"`,
+		},
+		{
+			Name:               "deadline has to be in the future",
+			Method:             http.MethodPut,
+			Body:               fmt.Sprintf(`{"rsvp": {"deadline": %q}}`, passed.Format(time.RFC3339)),
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventSettings },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.0.field",
+			ExpectedResult:     `"rsvp.deadline"`,
+		},
+		{
+			Name:               "reminders are checked",
+			Method:             http.MethodPut,
+			Body:               fmt.Sprintf(`{"rsvp": {"deadline": %q, "reminders": [{"hours_before": 24, "channels": ["email"]}]}}`, deadline.Format(time.RFC3339)),
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventSettings },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.0.field",
+			ExpectedResult:     `"rsvp.reminders[0].channels[0]"`,
+		},
+		{
+			Name:               "happy path - move the deadline",
+			Method:             http.MethodPut,
+			Body:               fmt.Sprintf(`{"rsvp": {"deadline": %q, "reminders": [{"hours_before": 48, "channels": ["sms"]}]}}`, deadline.Format(time.RFC3339)),
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventSettings },
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}}
+		utils.MockRequest(ctx, c.Method, c.Body)
+		c.Handler(mockServer)(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		if c.ExpectedResult != "" {
+			assert.JSONEq(t, c.ExpectedResult, gjson.Get(w.Body.String(), c.PathToResult).Raw, c.Name)
+		}
+	}
+	schedule, err := store.Get("mockEventId")
+	assert.NoError(t, err)
+	if assert.NotNil(t, schedule.Policy.Deadline) {
+		assert.True(t, deadline.Equal(*schedule.Policy.Deadline))
+	}
+	assert.Equal(t, []reminders.Reminder{{HoursBefore: 48, Channels: []string{"sms"}}}, schedule.Policy.Reminders)
+	assert.True(t, schedule.Open(time.Now()), "moving the deadline opens rsvps again")
+}
+
+func TestProcessDeadline(t *testing.T) {
+	mockEvent := `{"_id": "mockEventId", "name": "Game night", "createdBy": "mockHostId", "members": [
+		{"userId": "mockHostId", "status": "going"},
+		{"userId": "mockUserId", "status": "invited"},
+		{"userId": "mockWaitingUserId", "status": "invited"},
+		{"userId": "mockOccurrenceUserId", "status": "invited"}
+	]}`
+	deadline := time.Date(2026, time.October, 30, 12, 0, 0, 0, time.UTC)
+	policy := reminders.Policy{Deadline: &deadline, Reminders: []reminders.Reminder{{HoursBefore: 24, Channels: []string{"sms"}}}}
+	cases := []struct {
+		Name                    string
+		Now                     time.Time
+		Closed                  bool
+		EventServiceDBResponses []interface{}
+		ExpectedError           bool
+		ExpectedEventCalls      int
+		ExpectedReminders       int
+		ExpectedSent            []int
+		ExpectedClosed          bool
+		ExpectedNoResponse      []string
+	}{
+		{
+			Name:               "nothing is due before the first reminder",
+			Now:                deadline.AddDate(0, 0, -2),
+			ExpectedSent:       []int{},
+			ExpectedEventCalls: 0,
+		},
+		{
+			Name:                    "happy path - only members who haven't answered are reminded",
+			Now:                     deadline.Add(-time.Hour),
+			EventServiceDBResponses: []interface{}{mockEvent},
+			ExpectedEventCalls:      1,
+			ExpectedReminders:       1,
+			ExpectedSent:            []int{24},
+		},
+		{
+			Name:                    "happy path - only members who haven't answered are kept as not responding once the deadline passed",
+			Now:                     deadline,
+			EventServiceDBResponses: []interface{}{mockEvent},
+			ExpectedEventCalls:      1,
+			ExpectedSent:            []int{},
+			ExpectedClosed:          true,
+			ExpectedNoResponse:      []string{"mockUserId"},
+		},
+		{
+			Name:                    "the deadline stays open when the event can't be read",
+			Now:                     deadline,
+			EventServiceDBResponses: []interface{}{utils.MockCaughtError{StatusCode: http.StatusInternalServerError}},
+			ExpectedError:           true,
+			ExpectedEventCalls:      1,
+			ExpectedSent:            []int{},
+		},
+		{
+			Name:               "closed deadlines are left alone",
+			Now:                deadline.Add(time.Hour),
+			Closed:             true,
+			ExpectedEventCalls: 0,
+			ExpectedSent:       []int{},
+			ExpectedClosed:     true,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		deadlines := reminders.NewMemoryStore()
+		schedule := reminders.New("mockEventId", policy)
+		schedule.Closed = c.Closed
+		assert.NoError(t, deadlines.Save(schedule))
+		waitlists := waitlist.NewMemoryStore()
+		list := waitlist.New("mockEventId", 1)
+		list.Grant("mockHostId", 1)
+		list.Join("mockWaitingUserId", deadline.AddDate(0, 0, -7))
+		assert.NoError(t, waitlists.Save(list))
+		schedules := recurrence.NewMemoryStore()
+		series := recurrence.NewSeries("mockEventId", recurrence.Schedule{Start: deadline.AddDate(0, 0, 1), End: deadline.AddDate(0, 0, 1).Add(3 * time.Hour), TimeZone: "UTC", RRule: "FREQ=WEEKLY"})
+		series.RSVPs["20261107T120000Z"] = map[string]models.MemberStatus{"mockOccurrenceUserId": models.MemberStatusGoing}
+		assert.NoError(t, schedules.Save(series))
+		eventCalls, notificationCalls := 0, 0
+		mockServer := server.S{
+			EventService: services.EventService{
+				DBClient: utils.MockDBClient{
+					CallCount: &eventCalls,
+					Responses: c.EventServiceDBResponses,
+				},
+			},
+			// without users to text only the notifications are counted
+			UserService: services.UserService{
+				DBClient: utils.MockDBClient{
+					CallCount:       new(int),
+					DefaultResponse: `[]`,
+				},
+			},
+			NotificationService: services.NotificationService{
+				DBClient: utils.MockDBClient{
+					CallCount:       &notificationCalls,
+					DefaultResponse: "NTF_Mock",
+				},
+			},
+			Deadlines: deadlines,
+			Waitlists: waitlists,
+			Schedules: schedules,
+		}
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		err := server.ProcessDeadline(mockServer, ctx, "mockEventId", c.Now)
+		if c.ExpectedError {
+			assert.Error(t, err, c.Name)
+		} else {
+			assert.NoError(t, err, c.Name)
+		}
+		assert.Equal(t, c.ExpectedEventCalls, eventCalls, c.Name)
+		assert.Equal(t, c.ExpectedReminders, notificationCalls, c.Name)
+		saved, err := deadlines.Get("mockEventId")
+		if assert.NoError(t, err, c.Name) {
+			assert.Equal(t, c.ExpectedSent, saved.Sent, c.Name)
+			assert.Equal(t, c.ExpectedClosed, saved.Closed, c.Name)
+			assert.Equal(t, c.ExpectedNoResponse, saved.NoResponse, c.Name)
+		}
+	}
+}
+
+func TestMembersWhoNeverAnsweredAreShownAsNoResponse(t *testing.T) {
+	deadline := time.Date(2026, time.October, 30, 12, 0, 0, 0, time.UTC)
+	deadlines := reminders.NewMemoryStore()
+	schedule := reminders.New("mockEventId", reminders.Policy{Deadline: &deadline})
+	schedule.Close([]string{"mockUserId", "mockDecliningUserId"})
+	assert.NoError(t, deadlines.Save(schedule))
+	mockServer := server.S{
+		EventService: services.EventService{
+			DBClient: utils.MockDBClient{
+				CallCount: new(int),
+				DefaultResponse: `{"_id": "mockEventId", "name": "Game night", "createdBy": "mockHostId", "members": [
+					{"userId": "mockHostId", "status": "going"},
+					{"userId": "mockUserId", "status": "invited"},
+					{"userId": "mockDecliningUserId", "status": "not_going"}
+				]}`,
+			},
+		},
+		Deadlines: deadlines,
+	}
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Set("userId", "mockHostId")
+	ctx.Request = &http.Request{Header: make(http.Header)}
+	ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}}
+	utils.MockRequest(ctx, http.MethodGet, "")
+	mockServer.GetEvent(ctx)
+	assert.Equal(t, http.StatusOK, w.Code)
+	members := gjson.Get(w.Body.String(), "result.members").Array()
+	if assert.Len(t, members, 3) {
+		assert.Equal(t, "going", members[0].Get("status").String())
+		assert.Equal(t, "no_response", members[1].Get("status").String())
+		assert.Equal(t, "not_going", members[2].Get("status").String(), "answering after the deadline counts")
+	}
+}
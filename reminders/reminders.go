@@ -0,0 +1,129 @@
+package reminders
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultReminders are sent when hosts set a deadline without choosing
+// reminders, 3 days and 1 day before it
+var DefaultReminders = []Reminder{
+	{HoursBefore: 72, Channels: []string{"push", "sms"}},
+	{HoursBefore: 24, Channels: []string{"push", "sms"}},
+}
+
+// Reminder is sent to the members who haven't answered yet, HoursBefore the
+// deadline
+type Reminder struct {
+	HoursBefore int      `json:"hours_before"`
+	Channels    []string `json:"channels"`
+}
+
+// Policy is when members have to answer by, set by hosts in the event
+// settings. Without reminders the DefaultReminders are sent, an empty list
+// sends none.
+type Policy struct {
+	Deadline  *time.Time `json:"deadline"`
+	Reminders []Reminder `json:"reminders"`
+}
+
+func (p Policy) reminders() []Reminder {
+	if p.Reminders == nil {
+		return DefaultReminders
+	}
+	return p.Reminders
+}
+
+// Schedule is the deadline of a kickback and how far along it is: which
+// reminders went out and, once it passed, the members who never answered
+type Schedule struct {
+	EventID string `json:"eventId"`
+	Policy  Policy `json:"policy"`
+	Sent    []int  `json:"sent"`
+	Closed  bool   `json:"closed"`
+	// NoResponse are the members who were still invited when the deadline
+	// passed. The event keeps them as invited.
+	NoResponse []string `json:"no_response"`
+	// Version is what the schedule was read at, see Store
+	Version int64 `json:"-"`
+}
+
+func New(eventID string, policy Policy) Schedule {
+	return Schedule{EventID: eventID, Policy: policy, Sent: []int{}}
+}
+
+// SetPolicy changes the deadline, moving it starts the reminders over
+func (s *Schedule) SetPolicy(p Policy) {
+	if s.Policy.Deadline == nil || p.Deadline == nil || !s.Policy.Deadline.Equal(*p.Deadline) {
+		s.Sent = []int{}
+		s.Closed = false
+		s.NoResponse = nil
+	}
+	s.Policy = p
+}
+
+// Close ends the RSVPs once the deadline passed, unanswered never responded
+func (s *Schedule) Close(unanswered []string) {
+	s.Closed = true
+	s.NoResponse = append([]string{}, unanswered...)
+}
+
+// NotResponding reports whether userID was still invited when the deadline
+// passed
+func (s Schedule) NotResponding(userID string) bool {
+	for _, id := range s.NoResponse {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Open reports whether members can still answer at now
+func (s Schedule) Open(now time.Time) bool {
+	return s.Policy.Deadline == nil || now.Before(*s.Policy.Deadline)
+}
+
+// Due returns the reminder to send at now, if any, and whether the deadline
+// passed without the schedule being closed. When several reminders are
+// overdue only the last of them is sent.
+func (s Schedule) Due(now time.Time) (*Reminder, bool) {
+	if s.Policy.Deadline == nil || s.Closed {
+		return nil, false
+	}
+	deadline := *s.Policy.Deadline
+	if !now.Before(deadline) {
+		return nil, true
+	}
+	var due *Reminder
+	for _, r := range s.Policy.reminders() {
+		r := r
+		sendAt := deadline.Add(-time.Duration(r.HoursBefore) * time.Hour)
+		if now.Before(sendAt) || s.sent(r.HoursBefore) {
+			continue
+		}
+		if due == nil || r.HoursBefore < due.HoursBefore {
+			due = &r
+		}
+	}
+	return due, false
+}
+
+// MarkSent records r and every reminder meant to go out before it as sent
+func (s *Schedule) MarkSent(r Reminder) {
+	for _, other := range s.Policy.reminders() {
+		if other.HoursBefore >= r.HoursBefore && !s.sent(other.HoursBefore) {
+			s.Sent = append(s.Sent, other.HoursBefore)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(s.Sent)))
+}
+
+func (s Schedule) sent(hoursBefore int) bool {
+	for _, h := range s.Sent {
+		if h == hoursBefore {
+			return true
+		}
+	}
+	return false
+}
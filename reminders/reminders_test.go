@@ -0,0 +1,104 @@
+package reminders_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDue(t *testing.T) {
+	deadline := time.Date(2026, time.October, 30, 12, 0, 0, 0, time.UTC)
+	schedule := reminders.New("EVT_1", reminders.Policy{Deadline: &deadline})
+
+	due, closing := schedule.Due(deadline.AddDate(0, 0, -4))
+	assert.Nil(t, due, "too early for any reminder")
+	assert.False(t, closing)
+
+	due, _ = schedule.Due(deadline.AddDate(0, 0, -3))
+	if assert.NotNil(t, due) {
+		assert.Equal(t, 72, due.HoursBefore)
+		schedule.MarkSent(*due)
+	}
+	due, _ = schedule.Due(deadline.AddDate(0, 0, -2))
+	assert.Nil(t, due, "the 3 day reminder went out already")
+
+	due, _ = schedule.Due(deadline.Add(-time.Hour))
+	if assert.NotNil(t, due) {
+		assert.Equal(t, 24, due.HoursBefore)
+		schedule.MarkSent(*due)
+	}
+	assert.True(t, schedule.Open(deadline.Add(-time.Second)))
+	assert.False(t, schedule.Open(deadline))
+
+	due, closing = schedule.Due(deadline)
+	assert.Nil(t, due)
+	assert.True(t, closing)
+	schedule.Closed = true
+	_, closing = schedule.Due(deadline.Add(time.Hour))
+	assert.False(t, closing, "members are only marked once")
+}
+
+func TestOverdueReminders(t *testing.T) {
+	deadline := time.Date(2026, time.October, 30, 12, 0, 0, 0, time.UTC)
+	schedule := reminders.New("EVT_1", reminders.Policy{Deadline: &deadline})
+	// the deadline was set less than a day before it
+	due, _ := schedule.Due(deadline.Add(-12 * time.Hour))
+	if assert.NotNil(t, due) {
+		assert.Equal(t, 24, due.HoursBefore, "only the last overdue reminder is sent")
+		schedule.MarkSent(*due)
+	}
+	assert.Equal(t, []int{72, 24}, schedule.Sent)
+
+	later := deadline.AddDate(0, 0, 7)
+	schedule.SetPolicy(reminders.Policy{Deadline: &later, Reminders: []reminders.Reminder{}})
+	assert.Empty(t, schedule.Sent, "moving the deadline starts over")
+	due, _ = schedule.Due(later.Add(-time.Hour))
+	assert.Nil(t, due, "no reminders were asked for")
+}
+
+func TestClose(t *testing.T) {
+	deadline := time.Date(2026, time.October, 30, 12, 0, 0, 0, time.UTC)
+	schedule := reminders.New("EVT_1", reminders.Policy{Deadline: &deadline})
+	schedule.Close([]string{"USR_1"})
+	assert.True(t, schedule.Closed)
+	assert.True(t, schedule.NotResponding("USR_1"))
+	assert.False(t, schedule.NotResponding("USR_2"))
+
+	later := deadline.AddDate(0, 0, 7)
+	schedule.SetPolicy(reminders.Policy{Deadline: &later})
+	assert.False(t, schedule.NotResponding("USR_1"), "moving the deadline lets members answer again")
+}
+
+func TestPending(t *testing.T) {
+	store := reminders.NewMemoryStore()
+	deadline := time.Date(2026, time.October, 30, 12, 0, 0, 0, time.UTC)
+	open := reminders.New("EVT_1", reminders.Policy{Deadline: &deadline})
+	closed := reminders.New("EVT_2", reminders.Policy{Deadline: &deadline})
+	closed.Closed = true
+	assert.NoError(t, store.Save(open))
+	assert.NoError(t, store.Save(closed))
+
+	pending, err := store.Pending()
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "EVT_1", pending[0].EventID)
+	}
+
+	// open is still at the version it had before it was saved
+	open.Closed = true
+	assert.ErrorIs(t, store.Save(open), docstore.ErrConflict)
+	saved, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	saved.Closed = true
+	assert.NoError(t, store.Save(saved))
+	pending, err = store.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	assert.NoError(t, store.Delete("EVT_1"))
+	_, err = store.Get("EVT_1")
+	assert.ErrorIs(t, err, reminders.ErrNotFound)
+}
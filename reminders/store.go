@@ -0,0 +1,80 @@
+package reminders
+
+import (
+	"errors"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+var ErrNotFound = errors.New("rsvp deadline not found")
+
+// Store persists the schedules of kickbacks with an RSVP deadline keyed by
+// event ID. Save only applies while the stored schedule is still at the
+// Version it was read at, and a new schedule only while there is none,
+// otherwise it returns docstore.ErrConflict.
+type Store interface {
+	Get(eventID string) (Schedule, error)
+	Save(schedule Schedule) error
+	Delete(eventID string) error
+	// Pending lists the schedules that haven't been closed yet. They don't
+	// carry their Version, Get one before changing it.
+	Pending() ([]Schedule, error)
+}
+
+// DBStore keeps the schedules in the database, shared by every instance
+type DBStore struct {
+	docs docstore.Collection
+}
+
+func NewDBStore(docs docstore.Collection) *DBStore {
+	return &DBStore{docs: docs}
+}
+
+// NewMemoryStore keeps the schedules in memory, for tests and single instance
+// deployments
+func NewMemoryStore() *DBStore {
+	return NewDBStore(docstore.NewMemory().Collection("deadlines"))
+}
+
+func (d *DBStore) Get(eventID string) (Schedule, error) {
+	var schedule Schedule
+	version, err := d.docs.Get(eventID, &schedule)
+	if err == docstore.ErrNotFound {
+		return Schedule{}, ErrNotFound
+	}
+	if err != nil {
+		return Schedule{}, err
+	}
+	schedule.Version = version
+	if schedule.Sent == nil {
+		schedule.Sent = []int{}
+	}
+	return schedule, nil
+}
+
+func (d *DBStore) Save(schedule Schedule) error {
+	_, err := d.docs.Put(schedule.EventID, schedule.Version, schedule)
+	return err
+}
+
+func (d *DBStore) Delete(eventID string) error {
+	return docstore.Retry(func() error {
+		var schedule Schedule
+		version, err := d.docs.Get(eventID, &schedule)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.docs.Delete(eventID, version)
+	})
+}
+
+func (d *DBStore) Pending() ([]Schedule, error) {
+	pending := []Schedule{}
+	if err := d.docs.Find(map[string]interface{}{"closed": false}, &pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
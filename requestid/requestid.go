@@ -0,0 +1,72 @@
+// Package requestid carries the request and transaction IDs assigned to an
+// incoming request through to logs and the outbound calls made while
+// handling it, so a single user action can be traced end to end.
+package requestid
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	HeaderRequestID     = "X-Request-ID"
+	HeaderTransactionID = "X-Transaction-ID"
+)
+
+// keys the IDs are stored under in gin.Context, which is also what the
+// logger reads them from
+const (
+	KeyRequestID     = "requestId"
+	KeyTransactionID = "transactionId"
+)
+
+type IDs struct {
+	RequestID     string `json:"requestId,omitempty"`
+	TransactionID string `json:"transactionId,omitempty"`
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, ids IDs) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids)
+}
+
+// FromContext returns the IDs stored with NewContext. Services are handed the
+// *gin.Context itself, so its string keys are checked as well.
+func FromContext(ctx context.Context) IDs {
+	if ctx == nil {
+		return IDs{}
+	}
+	if ids, ok := ctx.Value(contextKey{}).(IDs); ok {
+		return ids
+	}
+	reqID, _ := ctx.Value(KeyRequestID).(string)
+	trxID, _ := ctx.Value(KeyTransactionID).(string)
+	return IDs{RequestID: reqID, TransactionID: trxID}
+}
+
+// Transport forwards the IDs found on the outgoing request's context as
+// headers, it never overrides headers the caller already set
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ids := FromContext(r.Context())
+	if ids.RequestID == "" && ids.TransactionID == "" {
+		return base.RoundTrip(r)
+	}
+	// a RoundTripper must not modify the request it was given
+	r = r.Clone(r.Context())
+	if ids.RequestID != "" && r.Header.Get(HeaderRequestID) == "" {
+		r.Header.Set(HeaderRequestID, ids.RequestID)
+	}
+	if ids.TransactionID != "" && r.Header.Get(HeaderTransactionID) == "" {
+		r.Header.Set(HeaderTransactionID, ids.TransactionID)
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,45 @@
+package requestid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kickback-app/api/server/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: requestid.Transport{}}
+
+	ctx := requestid.NewContext(context.Background(), requestid.IDs{RequestID: "req-1", TransactionID: "trx-1"})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	_, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "req-1", got.Get(requestid.HeaderRequestID))
+	assert.Equal(t, "trx-1", got.Get(requestid.HeaderTransactionID))
+	assert.Empty(t, req.Header.Get(requestid.HeaderRequestID), "caller's request should not be modified")
+
+	// headers set by the caller win
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	req.Header.Set(requestid.HeaderTransactionID, "trx-2")
+	_, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "trx-2", got.Get(requestid.HeaderTransactionID))
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	_, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Empty(t, got.Get(requestid.HeaderRequestID))
+}
+
+func TestFromContextStringKeys(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestid.KeyRequestID, "req-1")
+	assert.Equal(t, requestid.IDs{RequestID: "req-1"}, requestid.FromContext(ctx))
+}
@@ -5,50 +5,157 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/metrics"
 	"github.com/kickback-app/api/server/middlewares"
+	"github.com/kickback-app/api/server/openapi"
+	"github.com/kickback-app/api/server/policy"
 )
 
+// permissions lists who may call each kickback scoped /v1 route. Routes that
+// aren't scoped to a kickback (users, notifications, listing/creating events)
+// are not listed and only require a valid token.
+var permissions = policy.Table{
+	// Events
+	"GET /v1/events/:eventId":            policy.Members,
+	"PUT /v1/events/:eventId":            policy.Hosts,
+	"PATCH /v1/events/:eventId":          policy.Hosts,
+	"DELETE /v1/events/:eventId":         policy.Creator,
+	"GET /v1/events/:eventId/settings":   policy.Members,
+	"PUT /v1/events/:eventId/settings":   policy.Hosts,
+	"PATCH /v1/events/:eventId/settings": policy.Hosts,
+	"GET /v1/events/:eventId/members":    policy.Members,
+	"POST /v1/events/:eventId/members":   policy.Hosts,
+	"PUT /v1/events/:eventId/rsvp":       policy.Members,
+
+	// Recurring events
+	"GET /v1/events/:eventId/schedule":                  policy.Members,
+	"PUT /v1/events/:eventId/schedule":                  policy.Hosts,
+	"GET /v1/events/:eventId/occurrences":               policy.Members,
+	"PUT /v1/events/:eventId/occurrences/:occurrenceId": policy.Hosts,
+	"GET /v1/events/:eventId/calendar.ics":              policy.Members,
+
+	// Capacity and waitlist
+	"GET /v1/events/:eventId/waitlist":                  policy.Members,
+	"PUT /v1/events/:eventId/capacity":                  policy.Hosts,
+	"PUT /v1/events/:eventId/waitlist/:userId":          policy.Hosts,
+	"POST /v1/events/:eventId/waitlist/:userId/promote": policy.Hosts,
+
+	// Tasks
+	"POST /v1/kickbacks/:kickbackId/tasks": policy.Members,
+	"GET /v1/kickbacks/:kickbackId/tasks":  policy.Members,
+	"GET /v1/tasks/:taskId":                policy.Members,
+	"PUT /v1/tasks/:taskId":                policy.Members,
+	"PATCH /v1/tasks/:taskId":              policy.Members,
+	"DELETE /v1/tasks/:taskId":             policy.AnyOf(policy.Hosts, policy.Owner()),
+
+	// Expenses
+	"GET /v1/kickbacks/:kickbackId/expenses":  policy.Members,
+	"POST /v1/kickbacks/:kickbackId/expenses": policy.Members,
+	"GET /v1/expenses/:expenseId":             policy.Members,
+	"PUT /v1/expenses/:expenseId":             policy.AnyOf(policy.Hosts, policy.Owner()),
+	"PATCH /v1/expenses/:expenseId":           policy.AnyOf(policy.Hosts, policy.Owner()),
+	"DELETE /v1/expenses/:expenseId":          policy.AnyOf(policy.Hosts, policy.Owner()),
+	"PUT /v1/expenses/:expenseId/assignees":   policy.Members,
+
+	// Notes
+	"GET /v1/notes/:kickbackId": policy.Members,
+	"PUT /v1/notes/:kickbackId": policy.Members,
+
+	// Media
+	"GET /v1/kickbacks/:kickbackId/media":                               policy.Members,
+	"GET /v1/kickbacks/:kickbackId/media/:itemId":                       policy.Members,
+	"POST /v1/kickbacks/:kickbackId/media":                              policy.Members,
+	"PUT /v1/kickbacks/:kickbackId/media/:itemId/metadata":              policy.Hosts,
+	"PATCH /v1/kickbacks/:kickbackId/media/:itemId/metadata":            policy.Hosts,
+	"DELETE /v1/kickbacks/:kickbackId/media/:itemId":                    policy.Hosts,
+	"POST /v1/kickbacks/:kickbackId/media/:itemId/comment":              policy.Members,
+	"PUT /v1/kickbacks/:kickbackId/media/:itemId/comment/:commentId":    policy.AnyOf(policy.Hosts, policy.Owner()),
+	"DELETE /v1/kickbacks/:kickbackId/media/:itemId/comment/:commentId": policy.AnyOf(policy.Hosts, policy.Owner()),
+
+	// Chats
+	"GET /v1/kickbacks/:kickbackId/channels":                  policy.Members,
+	"POST /v1/kickbacks/:kickbackId/channels":                 policy.Members,
+	"PUT /v1/chats/:kickbackId/channels/:channelId":           policy.Hosts,
+	"PATCH /v1/chats/:kickbackId/channels/:channelId":         policy.Hosts,
+	"DELETE /v1/chats/:kickbackId/channels/:channelId":        policy.Hosts,
+	"PUT /v1/chats/:kickbackId/channels/:channelId/members":   policy.Hosts,
+	"POST /v1/chats/:kickbackId/channels/:channelId/pinned":   policy.Members,
+	"DELETE /v1/chats/:kickbackId/channels/:channelId/pinned": policy.Members,
+	"GET /v1/chats/:kickbackId/pinned":                        policy.Members,
+}
+
 func (s S) AttachRoutes() {
 	app := s.app
+	spec := apiSpec()
+	validateRequest := s.ValidateRequests(spec)
+	app.Use(middlewares.Recovery, middlewares.Tracing(s.config.Telemetry.ServiceName), metrics.Middleware)
+	app.HandleMethodNotAllowed = true
+	app.NoRoute(handlers.NoRoute)
+	app.NoMethod(handlers.NoMethod)
 	app.GET("/", func(c *gin.Context) {
 		c.String(http.StatusOK, "Welcome to Kickback")
 	})
 	app.GET("/healthcheck", handlers.Healthcheck)
-	// needs to be unauthorized bc its being called from twilio
+	app.GET("/health/live", handlers.Healthcheck)
+	app.GET("/health/ready", s.Readiness)
+	app.GET("/openapi.json", openapi.Handler(spec))
+	// no JWT bc its being called from twilio, requests are authenticated by their signature instead
 	// https://www.twilio.com/docs/sms/send-messages#monitor-the-status-of-your-message
-	app.POST("/sms-status", handlers.SentSMSStatus)
+	app.POST("/sms-status", s.mw.VerifyTwilioSignature, handlers.SentSMSStatus(s.SMSDeliveries))
+	// replies to invite texts, configured as the messaging webhook of the twilio number
+	app.POST("/sms-inbound", s.mw.VerifyTwilioSignature, s.ReceiveSMS)
 
 	// OTP Verification APIs  -- @todo should these be unauthorized?
-	app.POST("/otp/send", s.SendOTP)
-	app.POST("/otp/verify", s.VerifyOTP)
+	app.POST("/otp/send", validateRequest, s.SendOTP)
+	app.POST("/otp/verify", validateRequest, s.VerifyOTP)
+
+	// token refresh/logout only require the refresh token, the access token may already be expired
+	app.POST("/auth/refresh", validateRequest, s.RefreshToken)
+	app.POST("/auth/logout", validateRequest, s.Logout)
 
-	// expose unauthorized endpoints for web app to interact with
-	// @todo do manual validation
-	app.GET("/events/:eventId", s.GetEvent)
-	app.PUT("/events/:eventId/rsvp", s.RSVP)
+	// expose endpoints for the web app, authenticated by the signed token in the invite link
+	app.GET("/events/:eventId", s.RequireInviteToken, validateRequest, s.GetEvent)
+	app.PUT("/events/:eventId/rsvp", s.RequireInviteToken, validateRequest, s.RSVP)
+	app.GET("/events/:eventId/calendar.ics", s.RequireInviteToken, validateRequest, s.ExportEventCalendar)
+	// calendar apps subscribe without credentials, the secret token in the url authenticates the feed
+	app.GET("/calendar/:token", validateRequest, s.GetCalendarFeed)
 
 	v1 := app.Group("/v1")
-	v1.Use(middlewares.Authorize)
+	v1.Use(s.mw.Authorize, validateRequest, s.Enforce(permissions), s.Idempotent)
 	{
 		// Events APIs
 		v1.GET("/events", s.GetUsersEvents)
 		v1.GET("/events/:eventId", s.GetEvent)
 		v1.POST("/events", s.CreateEvent)
 		v1.PUT("/events/:eventId", s.UpdateEvent)
+		v1.PATCH("/events/:eventId", s.PatchEvent)
 		v1.DELETE("/events/:eventId", s.DeleteEvent)
 		// event settings
 		v1.GET("/events/:eventId/settings", s.GetEventSettings)
 		v1.PUT("/events/:eventId/settings", s.UpdateEventSettings)
+		v1.PATCH("/events/:eventId/settings", s.PatchEventSettings)
 		// event memership
 		v1.GET("/events/:eventId/members", s.GetEventMembers)
 		v1.POST("/events/:eventId/members", s.InviteEventMembers)
 		v1.PUT("/events/:eventId/rsvp", s.RSVP)
+		// recurring events
+		v1.GET("/events/:eventId/schedule", s.GetEventSchedule)
+		v1.PUT("/events/:eventId/schedule", s.UpdateEventSchedule)
+		v1.GET("/events/:eventId/occurrences", s.GetEventOccurrences)
+		v1.PUT("/events/:eventId/occurrences/:occurrenceId", s.UpdateOccurrence)
+		v1.GET("/events/:eventId/calendar.ics", s.ExportEventCalendar)
+		// capacity and waitlist
+		v1.PUT("/events/:eventId/capacity", s.UpdateEventCapacity)
+		v1.GET("/events/:eventId/waitlist", s.GetWaitlist)
+		v1.PUT("/events/:eventId/waitlist/:userId", s.MoveWaitlistMember)
+		v1.POST("/events/:eventId/waitlist/:userId/promote", s.PromoteWaitlistMember)
 
 		// Tasks APIs
 		v1.POST("/kickbacks/:kickbackId/tasks", s.CreateTask)
 		v1.GET("/kickbacks/:kickbackId/tasks", s.GetTasks)
 		v1.GET("/tasks/:taskId", s.GetTask)
 		v1.PUT("/tasks/:taskId", s.UpdateTask)
+		v1.PATCH("/tasks/:taskId", s.PatchTask)
 		v1.DELETE("/tasks/:taskId", s.DeleteTask)
 
 		// Expenses APIs
@@ -56,6 +163,7 @@ func (s S) AttachRoutes() {
 		v1.POST("/kickbacks/:kickbackId/expenses", s.CreateExpense)
 		v1.GET("/expenses/:expenseId", s.GetExpense)
 		v1.PUT("/expenses/:expenseId", s.UpdateExpense)
+		v1.PATCH("/expenses/:expenseId", s.PatchExpense)
 		v1.DELETE("/expenses/:expenseId", s.DeleteExpense)
 		v1.PUT("/expenses/:expenseId/assignees", s.UpdateExpenseAssignee)
 
@@ -68,6 +176,7 @@ func (s S) AttachRoutes() {
 		v1.GET("/kickbacks/:kickbackId/media/:itemId", s.GetMediaItem)
 		v1.POST("/kickbacks/:kickbackId/media", s.CreateMediaItem)
 		v1.PUT("/kickbacks/:kickbackId/media/:itemId/metadata", s.UpdateMediaItemMetadata)
+		v1.PATCH("/kickbacks/:kickbackId/media/:itemId/metadata", s.PatchMediaItemMetadata)
 		v1.DELETE("/kickbacks/:kickbackId/media/:itemId", s.DeleteMediaItem)
 		v1.POST("/kickbacks/:kickbackId/media/:itemId/comment", s.AddMediaItemComment)
 		v1.PUT("/kickbacks/:kickbackId/media/:itemId/comment/:commentId", s.UpdateMediaItemComment)
@@ -77,6 +186,7 @@ func (s S) AttachRoutes() {
 		v1.GET("/kickbacks/:kickbackId/channels", s.GetChannels)
 		v1.POST("/kickbacks/:kickbackId/channels", s.CreateChannel)
 		v1.PUT("/chats/:kickbackId/channels/:channelId", s.UpdateChannel)
+		v1.PATCH("/chats/:kickbackId/channels/:channelId", s.PatchChannel)
 		v1.DELETE("/chats/:kickbackId/channels/:channelId", s.DeleteChannel)
 		v1.PUT("/chats/:kickbackId/channels/:channelId/members", s.UpdateChannelMembers)
 		v1.POST("/chats/:kickbackId/channels/:channelId/pinned", s.PinChatMessage)
@@ -87,16 +197,20 @@ func (s S) AttachRoutes() {
 		v1.GET("/notifications", s.GetNotifications)
 		v1.POST("/notifications", s.SendNotification)
 		v1.GET("/notifications/settings", s.GetUsersNotificationSettings)
+		v1.GET("/notifications/reports/:notificationId", s.GetNotificationReport)
 		v1.PUT("/notifications/settings", s.UpdateUsersNotificationSettings)
 
 		// Users APIs
 		v1.GET("/users/:userId", s.GetUser) // @todo need to implment some access controls for users getting each others profiles
 		v1.POST("/users", s.CreateUser)
 		v1.PUT("/users", s.UpdateUser)
+		v1.PATCH("/users", s.PatchUser)
 		v1.DELETE("/users", s.DeleteUser)
 		v1.GET("/users/connections", s.GetUsersConnections)
 		v1.GET("/users/following/default", s.GetSponsoredUsers)
 		v1.POST("/users/search", s.SearchUsers)
 		v1.POST("/users/invite", s.InviteUser) // invite new user to the platform
+		v1.POST("/users/calendar", s.CreateCalendarFeed)
+		v1.DELETE("/users/calendar", s.DeleteCalendarFeed)
 	}
 }
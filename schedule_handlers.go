@@ -0,0 +1,468 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/kickback-app/api/utils"
+)
+
+// window of occurrences listed when from and to aren't given, and the widest
+// one that may be asked for
+const (
+	defaultOccurrenceWindow = 90 * 24 * time.Hour
+	maxOccurrenceWindow     = 366 * 24 * time.Hour
+)
+
+// which occurrences of a recurring kickback UpdateEvent and DeleteEvent apply to
+const (
+	scopeThis      = "this"
+	scopeFollowing = "following"
+	scopeAll       = "all"
+)
+
+var editScopes = []string{scopeThis, scopeFollowing, scopeAll}
+
+// createEventRequest is a new event, optionally with the schedule it recurs on
+// and how many members can be going
+type createEventRequest struct {
+	models.Event
+	Schedule *recurrence.Schedule `json:"schedule"`
+	Capacity *int                 `json:"capacity"`
+}
+
+// occurrenceRequest moves, cancels or restores a single occurrence. Without
+// start and end the occurrence goes back to its scheduled time.
+type occurrenceRequest struct {
+	Start     *time.Time `json:"start"`
+	End       *time.Time `json:"end"`
+	Cancelled bool       `json:"cancelled"`
+}
+
+func scheduleNotFound(err error) error {
+	if err == recurrence.ErrNotFound {
+		return handlers.NotFoundError{Resource: "schedule", Err: err}
+	}
+	return err
+}
+
+// occurrenceWindow reads the from and to query parameters, present is false
+// when neither was given
+func occurrenceWindow(c *gin.Context) (time.Time, time.Time, bool, error) {
+	rawFrom, rawTo := c.Query("from"), c.Query("to")
+	var v validate.Validator
+	parse := func(field, raw string, fallback time.Time) time.Time {
+		if raw == "" {
+			return fallback
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		v.Check(err == nil, field, validate.RuleFormat, "must be an RFC 3339 time, e.g. 2026-10-16T20:00:00Z")
+		return t
+	}
+	from := parse("from", rawFrom, time.Now())
+	to := parse("to", rawTo, from.Add(defaultOccurrenceWindow))
+	if v.Err() == nil {
+		v.Check(to.After(from), "to", validate.RuleMin, "must be after from")
+		v.Check(to.Sub(from) <= maxOccurrenceWindow, "to", validate.RuleMax, "must be at most 366 days after from")
+	}
+	return from, to, rawFrom != "" || rawTo != "", v.Err()
+}
+
+// editScope reads which occurrences an update or delete applies to, all of
+// them unless scope says otherwise
+func editScope(c *gin.Context) (string, string, error) {
+	scope := c.DefaultQuery("scope", scopeAll)
+	occurrenceID := c.Query("occurrence")
+	var v validate.Validator
+	v.OneOf("scope", scope, editScopes)
+	v.Check(scope == scopeAll || occurrenceID != "", "occurrence", validate.RuleRequired, "is required unless scope is all")
+	return scope, occurrenceID, v.Err()
+}
+
+// scheduleConflict asks the client to retry a write that kept losing to other
+// writes of the same series
+func scheduleConflict(err error) error {
+	if err == docstore.ErrConflict {
+		return handlers.ConflictError{Resource: "schedule"}
+	}
+	return err
+}
+
+// updateSeries applies change to the series of the event and saves it. change
+// runs again on a fresh copy when another write saved the series first, so it
+// must not have side effects.
+func (s S) updateSeries(eventID string, change func(*recurrence.Series) error) error {
+	err := docstore.Retry(func() error {
+		series, err := s.Schedules.Get(eventID)
+		if err != nil {
+			return scheduleNotFound(err)
+		}
+		if err := change(&series); err != nil {
+			return err
+		}
+		return s.Schedules.Save(series)
+	})
+	return scheduleConflict(err)
+}
+
+// changeOccurrence applies change to the exception of a single occurrence and
+// returns the occurrence as changed
+func (s S) changeOccurrence(eventID, occurrenceID string, change func(*recurrence.Exception, recurrence.Occurrence) error) (recurrence.Occurrence, error) {
+	var changed recurrence.Occurrence
+	err := s.updateSeries(eventID, func(series *recurrence.Series) error {
+		o, ok := series.Find(occurrenceID)
+		if !ok {
+			return handlers.NotFoundError{Resource: "occurrence"}
+		}
+		e := series.Exceptions[o.ID]
+		if err := change(&e, o); err != nil {
+			return err
+		}
+		if e == (recurrence.Exception{}) {
+			delete(series.Exceptions, o.ID)
+		} else {
+			series.Exceptions[o.ID] = e
+		}
+		changed, _ = series.Find(o.ID)
+		return nil
+	})
+	return changed, err
+}
+
+// mergeEventUpdates layers the fields set in next over the ones set in prev
+func mergeEventUpdates(prev *models.EventUpdates, next models.EventUpdates) (*models.EventUpdates, error) {
+	merged := map[string]interface{}{}
+	if prev != nil {
+		merged = utils.Normalize(prev)
+	}
+	for k, v := range utils.Normalize(next) {
+		if v != nil {
+			merged[k] = v
+		}
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var updates models.EventUpdates
+	if err := json.Unmarshal(b, &updates); err != nil {
+		return nil, err
+	}
+	return &updates, nil
+}
+
+// notifyOccurrenceChange lets the other members know a single occurrence was
+// changed
+func (s S) notifyOccurrenceChange(c *gin.Context, eventID string, o recurrence.Occurrence) {
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		logger.Warn(c, "unable to get event %s to notify members of a changed occurrence: %v", eventID, err)
+		return
+	}
+	usersToNotify := []string{}
+	for _, userID := range event.MemberUserIDs() {
+		if userID != utils.CurrentUser(c).ID {
+			usersToNotify = append(usersToNotify, userID)
+		}
+	}
+	title := fmt.Sprintf("Your event %v on %s has been updated", event.Name, o.Start.Format("Jan 2"))
+	if o.Cancelled {
+		title = fmt.Sprintf("Your event %v on %s has been cancelled", event.Name, o.Start.Format("Jan 2"))
+	}
+	s.sendInBackground(c, models.Notification{
+		Type:     models.EventUpdated,
+		Channels: []string{"push"},
+		To:       usersToNotify,
+		Title:    title,
+		Body:     "check your new event details",
+		Data: map[string]string{
+			"eventId":      eventID,
+			"occurrenceId": o.ID,
+		},
+	})
+}
+
+func (s S) GetEventSchedule(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	series, err := s.Schedules.Get(eventID)
+	if err != nil {
+		handlers.EncodeError(c, scheduleNotFound(err))
+		return
+	}
+	logger.Info(c, "retrieved schedule of event %s", eventID)
+	handlers.EncodeSuccess(c, http.StatusOK, series.Schedule)
+}
+
+// UpdateEventSchedule sets when the event happens. Exceptions and RSVPs of
+// occurrences the new schedule doesn't have are dropped.
+func (s S) UpdateEventSchedule(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	var schedule recurrence.Schedule
+	if err := json.NewDecoder(c.Request.Body).Decode(&schedule); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	var v validate.Validator
+	schedule.Check(&v, "")
+	if err := v.Err(); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err := docstore.Retry(func() error {
+		series, err := s.Schedules.Get(eventID)
+		switch {
+		case err == recurrence.ErrNotFound:
+			series = recurrence.NewSeries(eventID, schedule)
+		case err != nil:
+			return err
+		default:
+			series.Schedule = schedule
+			series.Prune()
+		}
+		return s.Schedules.Save(series)
+	})
+	if err != nil {
+		handlers.EncodeError(c, scheduleConflict(err))
+		return
+	}
+	logger.Info(c, "updated schedule of event %s", eventID)
+	handlers.EncodeSuccess(c, http.StatusOK, schedule)
+}
+
+// GetEventOccurrences lists the occurrences of the event between from and to,
+// the next 90 days by default
+func (s S) GetEventOccurrences(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	from, to, _, err := occurrenceWindow(c)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	series, err := s.Schedules.Get(eventID)
+	if err != nil {
+		handlers.EncodeError(c, scheduleNotFound(err))
+		return
+	}
+	occurrences := series.Between(from, to)
+	logger.Info(c, "retrieved %d occurrences of event %s", len(occurrences), eventID)
+	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"occurrences": occurrences})
+}
+
+// UpdateOccurrence moves, cancels or restores a single occurrence
+func (s S) UpdateOccurrence(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	param = "occurrenceId"
+	occurrenceID := c.Param(param)
+	if occurrenceID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	var body occurrenceRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	o, err := s.changeOccurrence(eventID, occurrenceID, func(e *recurrence.Exception, o recurrence.Occurrence) error {
+		if body.End != nil {
+			start, _ := time.Parse(recurrence.IDLayout, o.ID)
+			if body.Start != nil {
+				start = *body.Start
+			}
+			var v validate.Validator
+			v.Check(body.End.After(start), "end", validate.RuleMin, "must be after start")
+			if err := v.Err(); err != nil {
+				return err
+			}
+		}
+		e.Start, e.End, e.Cancelled = body.Start, body.End, body.Cancelled
+		return nil
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	s.notifyOccurrenceChange(c, eventID, o)
+	logger.Info(c, "updated occurrence %s of event %s", o.ID, eventID)
+	handlers.EncodeSuccess(c, http.StatusOK, o)
+}
+
+// updateOccurrenceDetails overrides the details of a single occurrence, the
+// "this" scope of UpdateEvent
+func (s S) updateOccurrenceDetails(c *gin.Context, eventID, occurrenceID string, eventUpdates models.EventUpdates) {
+	o, err := s.changeOccurrence(eventID, occurrenceID, func(e *recurrence.Exception, _ recurrence.Occurrence) error {
+		updates, err := mergeEventUpdates(e.Updates, eventUpdates)
+		e.Updates = updates
+		return err
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	s.notifyOccurrenceChange(c, eventID, o)
+	logger.Info(c, "updated occurrence %s of event %s", o.ID, eventID)
+	handlers.EncodeSuccess(c, http.StatusOK, o)
+}
+
+// updateFollowing is the "following" scope of UpdateEvent: the series ends
+// before the occurrence and a copy of the event with the updates applied takes
+// over the occurrences from there on. Updating from the first occurrence
+// updates the whole series. The original series is only cut short once the
+// copy is set up, and the copy is discarded when that fails.
+func (s S) updateFollowing(c *gin.Context, eventID, occurrenceID string, eventUpdates models.EventUpdates) {
+	series, err := s.Schedules.Get(eventID)
+	if err != nil {
+		handlers.EncodeError(c, scheduleNotFound(err))
+		return
+	}
+	if occurrenceID == recurrence.OccurrenceID(series.Schedule.Start) {
+		s.saveEventUpdates(c, eventID, eventUpdates)
+		return
+	}
+	tail, err := series.Split(occurrenceID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFoundError{Resource: "occurrence", Err: err})
+		return
+	}
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("event", err))
+		return
+	}
+	following := event
+	following.ID = ""
+	created, err := s.EventService.CreateEvent(c, &following)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	tail.EventID = created.ID
+	err = s.setUpFollowing(c, event, created, tail, eventUpdates)
+	if err == nil {
+		// saved at the version it was read at, another change to the series
+		// in the meantime may have moved or answered occurrences of the tail
+		err = scheduleConflict(s.Schedules.Save(series))
+	}
+	if err != nil {
+		s.discardEvent(c, created.ID)
+		handlers.EncodeError(c, err)
+		return
+	}
+	updatedEvent, err := s.EventService.GetEvent(c, created.ID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	logger.Info(c, "split event %s at occurrence %s into event %s", eventID, occurrenceID, created.ID)
+	s.encodeEvent(c, updatedEvent)
+}
+
+// setUpFollowing applies the updates to created, the copy of event taking over
+// the occurrences of tail, and gives it what event has
+func (s S) setUpFollowing(c *gin.Context, event, created models.Event, tail recurrence.Series, eventUpdates models.EventUpdates) error {
+	if err := s.EventService.UpdateEvent(c, created.ID, &eventUpdates); err != nil {
+		return err
+	}
+	// the new event keeps the guests members bring
+	guestList, err := s.Guests.Get(event.ID)
+	if err != nil {
+		return err
+	}
+	// a new document, not the original's at the version it was read at
+	guestList.EventID, guestList.Version = created.ID, 0
+	if err := s.Guests.Save(guestList); err != nil {
+		return err
+	}
+	// and the capacity, the members waiting stay on the original's list
+	if list, err := s.Waitlists.Get(event.ID); err == nil {
+		if err := s.Waitlists.Save(newWaitlist(created, guestList, list.Capacity)); err != nil {
+			return err
+		}
+	}
+	// and the rsvp deadline, its reminders start over for the new members
+	if schedule, err := s.Deadlines.Get(event.ID); err == nil {
+		if err := s.Deadlines.Save(reminders.New(created.ID, schedule.Policy)); err != nil {
+			return err
+		}
+	}
+	if err := s.Schedules.Save(tail); err != nil {
+		return err
+	}
+	return s.setUpEvent(c, created, event.MemberUserIDs())
+}
+
+// endSeriesBefore is the "following" scope of DeleteEvent, whole is true when
+// the occurrence is the first one and the whole event has to go
+func (s S) endSeriesBefore(eventID, occurrenceID string) (bool, error) {
+	whole := false
+	err := s.updateSeries(eventID, func(series *recurrence.Series) error {
+		if occurrenceID == recurrence.OccurrenceID(series.Schedule.Start) {
+			whole = true
+			return nil
+		}
+		if _, err := series.Split(occurrenceID); err != nil {
+			return handlers.NotFoundError{Resource: "occurrence", Err: err}
+		}
+		return nil
+	})
+	return whole, err
+}
+
+// rsvpOccurrence sets the status of the user for a single occurrence
+func (s S) rsvpOccurrence(c *gin.Context, eventID, occurrenceID, userID string, status models.MemberStatus) error {
+	if err := s.checkRSVPOpen(eventID, status); err != nil {
+		return err
+	}
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		return handlers.NotFound("event", err)
+	}
+	return s.updateSeries(eventID, func(series *recurrence.Series) error {
+		o, ok := series.Find(occurrenceID)
+		if !ok {
+			return handlers.NotFoundError{Resource: "occurrence"}
+		}
+		if o.Cancelled {
+			return handlers.InvalidBodyFieldError{Field: "occurrence", Reason: "has been cancelled"}
+		}
+		if status == models.MemberStatusGoing {
+			if err := s.checkOccurrenceCapacity(event, *series, o.ID, userID); err != nil {
+				return err
+			}
+		}
+		if series.RSVPs[o.ID] == nil {
+			series.RSVPs[o.ID] = map[string]models.MemberStatus{}
+		}
+		series.RSVPs[o.ID][userID] = status
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestScheduleHandlers(t *testing.T) {
+	mockServer := server.S{Schedules: recurrence.NewMemoryStore()}
+	cases := []struct {
+		Name               string
+		Method             string
+		Query              string
+		Body               string
+		Handler            func(server.S) gin.HandlerFunc
+		ExpectedStatusCode int
+		PathToResult       string
+		ExpectedResult     string
+	}{
+		{
+			Name:               "unscheduled events have no occurrences",
+			Method:             http.MethodGet,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.GetEventOccurrences },
+			ExpectedStatusCode: http.StatusNotFound,
+			PathToResult:       "meta.error.errorCode",
+			ExpectedResult:     `"SCHEDULE_NOT_FOUND"`,
+		},
+		{
+			Name:               "schedule is checked",
+			Method:             http.MethodPut,
+			Body:               `{"start": "2026-10-17T00:00:00Z", "end": "2026-10-16T00:00:00Z", "time_zone": "Mars/Olympus", "rrule": "FREQ=HOURLY"}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventSchedule },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details",
+			ExpectedResult: `[
+				{"field": "end", "rule": "min", "message": "must be after start"},
+				{"field": "time_zone", "rule": "format", "message": "is not an IANA time zone, e.g. America/New_York"},
+				{"field": "rrule", "rule": "format", "message": "FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY"}
+			]`,
+		},
+		{
+			Name:               "happy path - schedule weekly game nights",
+			Method:             http.MethodPut,
+			Body:               `{"start": "2026-10-17T00:00:00Z", "end": "2026-10-17T03:00:00Z", "time_zone": "America/New_York", "rrule": "FREQ=WEEKLY;BYDAY=FR;COUNT=10"}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventSchedule },
+			ExpectedStatusCode: http.StatusOK,
+			PathToResult:       "result.rrule",
+			ExpectedResult:     `"FREQ=WEEKLY;BYDAY=FR;COUNT=10"`,
+		},
+		{
+			Name:               "happy path - occurrences in a window keep the local time",
+			Method:             http.MethodGet,
+			Query:              "from=2026-10-30T00:00:00Z&to=2026-11-10T00:00:00Z",
+			Handler:            func(s server.S) gin.HandlerFunc { return s.GetEventOccurrences },
+			ExpectedStatusCode: http.StatusOK,
+			PathToResult:       "result.occurrences.#.start",
+			ExpectedResult:     `["2026-10-30T20:00:00-04:00", "2026-11-06T20:00:00-05:00"]`,
+		},
+		{
+			Name:               "window is checked",
+			Method:             http.MethodGet,
+			Query:              "from=2026-10-30&to=2028-01-01T00:00:00Z",
+			Handler:            func(s server.S) gin.HandlerFunc { return s.GetEventOccurrences },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details",
+			ExpectedResult:     `[{"field": "from", "rule": "format", "message": "must be an RFC 3339 time, e.g. 2026-10-16T20:00:00Z"}]`,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}}
+		utils.MockRequest(ctx, c.Method, c.Body)
+		ctx.Request.URL.RawQuery = c.Query
+		c.Handler(mockServer)(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		assert.JSONEq(t, c.ExpectedResult, gjson.Get(w.Body.String(), c.PathToResult).Raw, c.Name)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/openapi"
+	"github.com/kickback-app/api/server/validate"
+)
+
+// ValidateRequests checks the path and query parameters and the JSON body of
+// a request against the operation doc describes for its route, before the
+// handler runs. Every violation is reported at once in a ValidationError.
+// Bodies of PATCH requests are merge patches and are checked as such, bodies
+// in a content type the operation doesn't declare are rejected with a 415.
+func (s S) ValidateRequests(doc *openapi.Document) gin.HandlerFunc {
+	limit := s.config.HTTP.MaxBodyBytes
+	return func(c *gin.Context) {
+		op, ok := doc.Operation(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+		var v validate.Validator
+		query := c.Request.URL.Query()
+		for _, p := range op.Parameters {
+			switch p.In {
+			case "path":
+				value := c.Param(p.Name)
+				doc.CheckParam(&v, p, value, value != "")
+			case "query":
+				values, present := query[p.Name]
+				raw := ""
+				if present {
+					raw = values[0]
+				}
+				doc.CheckParam(&v, p, raw, present)
+			}
+		}
+		// every body counts against the limit, whether the operation takes one or not
+		body, err := readBody(c, limit)
+		if err != nil {
+			handlers.EncodeError(c, err)
+			c.Abort()
+			return
+		}
+		// an empty body is left to the handler, which knows whether it needs one
+		if op.RequestBody != nil && len(bytes.TrimSpace(body)) > 0 {
+			schema, err := bodySchema(op, c.ContentType())
+			if err != nil {
+				handlers.EncodeError(c, err)
+				c.Abort()
+				return
+			}
+			if schema != nil {
+				var value interface{}
+				if err := json.Unmarshal(body, &value); err != nil {
+					handlers.EncodeError(c, handlers.MalformedBody(err))
+					c.Abort()
+					return
+				}
+				doc.Check(&v, "", schema, value, c.Request.Method == http.MethodPatch)
+			}
+		}
+		if err := v.Err(); err != nil {
+			logger.Warn(c, "rejecting request to %s %s: %v", c.Request.Method, c.FullPath(), err)
+			handlers.EncodeError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// bodySchema is the schema of the JSON body of op for contentType, nil for
+// the other content types op declares. Content types op doesn't declare are
+// rejected, a missing one is taken as JSON.
+func bodySchema(op *openapi.Operation, contentType string) (*openapi.Schema, error) {
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	media, ok := op.RequestBody.Content[contentType]
+	if !ok {
+		supported := make([]string, 0, len(op.RequestBody.Content))
+		for declared := range op.RequestBody.Content {
+			supported = append(supported, declared)
+		}
+		sort.Strings(supported)
+		return nil, handlers.UnsupportedMediaTypeError{ContentType: contentType, Supported: supported}
+	}
+	if contentType != "application/json" && !strings.HasSuffix(contentType, "+json") {
+		return nil, nil
+	}
+	return media.Schema, nil
+}
+
+// readBody reads the request body, up to limit bytes, and puts it back for
+// the handler
+func readBody(c *gin.Context, limit int) ([]byte, error) {
+	if limit > 0 && c.Request.ContentLength > int64(limit) {
+		return nil, handlers.PayloadTooLargeError{Limit: limit}
+	}
+	r := io.Reader(c.Request.Body)
+	if limit > 0 {
+		r = io.LimitReader(r, int64(limit)+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, handlers.MalformedBody(err)
+	}
+	if limit > 0 && len(body) > limit {
+		return nil, handlers.PayloadTooLargeError{Limit: limit}
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,148 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestValidateRequests(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.SigningToken = "mock-signing-token"
+	cfg.HTTP.MaxBodyBytes = 256
+	s, err := server.New(cfg, gin.New(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AttachRoutes()
+	tokens, err := s.TokenIssuer.NewSession("mockUserId")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		Body               string
+		ExpectedStatusCode int
+		ExpectedErrorCode  string
+		ExpectedDetails    string
+		ContentType        string
+	}{
+		{
+			Name:               "phone number format is checked before sending an OTP",
+			Method:             http.MethodPost,
+			Path:               "/otp/send",
+			Body:               `{"phoneNumber": "555-1234"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedErrorCode:  "VALIDATION_FAILED",
+			ExpectedDetails:    `[{"field": "phoneNumber", "rule": "format", "message": "is not in the expected format"}]`,
+		},
+		{
+			Name:               "unknown fields are rejected",
+			Method:             http.MethodPost,
+			Path:               "/auth/refresh",
+			Body:               `{"refreshToken": "abc", "accessToken": "def"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedErrorCode:  "VALIDATION_FAILED",
+			ExpectedDetails:    `[{"field": "accessToken", "rule": "unknown_field", "message": "is not a known field"}]`,
+		},
+		{
+			Name:               "bodies over the limit are rejected",
+			Method:             http.MethodPost,
+			Path:               "/auth/refresh",
+			Body:               fmt.Sprintf(`{"refreshToken": "%s"}`, strings.Repeat("a", 300)),
+			ExpectedStatusCode: http.StatusRequestEntityTooLarge,
+			ExpectedErrorCode:  "PAYLOAD_TOO_LARGE",
+		},
+		{
+			Name:               "bodies over the limit are rejected on operations without one",
+			Method:             http.MethodGet,
+			Path:               "/v1/events",
+			Body:               strings.Repeat("a", 300),
+			ExpectedStatusCode: http.StatusRequestEntityTooLarge,
+			ExpectedErrorCode:  "PAYLOAD_TOO_LARGE",
+		},
+		{
+			Name:               "undeclared content types are rejected",
+			Method:             http.MethodPost,
+			Path:               "/v1/events",
+			Body:               `name=5`,
+			ContentType:        "application/x-www-form-urlencoded",
+			ExpectedStatusCode: http.StatusUnsupportedMediaType,
+			ExpectedErrorCode:  "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			Name:               "json patches aren't merge patches",
+			Method:             http.MethodPatch,
+			Path:               "/v1/users",
+			Body:               `[{"op": "remove", "path": "/nickname"}]`,
+			ContentType:        "application/json-patch+json",
+			ExpectedStatusCode: http.StatusUnsupportedMediaType,
+			ExpectedErrorCode:  "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			Name:               "malformed json",
+			Method:             http.MethodPost,
+			Path:               "/v1/events",
+			Body:               `{"name": `,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedErrorCode:  "MALFORMED_BODY",
+		},
+		{
+			Name:               "authenticated bodies are checked",
+			Method:             http.MethodPost,
+			Path:               "/v1/events",
+			Body:               `{"name": 5}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedErrorCode:  "VALIDATION_FAILED",
+			ExpectedDetails:    `[{"field": "name", "rule": "type", "message": "must be a string"}]`,
+		},
+		{
+			Name:               "query parameters are checked",
+			Method:             http.MethodGet,
+			Path:               "/v1/events?limit=0&sort=-hosts",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedErrorCode:  "VALIDATION_FAILED",
+			ExpectedDetails: `[
+				{"field": "limit", "rule": "min", "message": "must be at least 1"},
+				{"field": "sort", "rule": "one_of", "message": "must be one of created_at, -created_at, updated_at, -updated_at, name, -name"}
+			]`,
+		},
+		{
+			Name:               "merge patches are checked for unknown fields",
+			Method:             http.MethodPatch,
+			Path:               "/v1/users",
+			Body:               `{"nickname": null}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedErrorCode:  "VALIDATION_FAILED",
+			ExpectedDetails:    `[{"field": "nickname", "rule": "unknown_field", "message": "is not a known field"}]`,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.Method, c.Path, strings.NewReader(c.Body))
+		contentType := c.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-JWT", tokens.AccessToken)
+		s.Engine().ServeHTTP(w, req)
+		assert.Equal(t, c.ExpectedStatusCode, w.Code, c.Name)
+		assert.Equal(t, c.ExpectedErrorCode, gjson.Get(w.Body.String(), "meta.error.errorCode").String(), c.Name)
+		if c.ExpectedDetails != "" {
+			assert.JSONEq(t, c.ExpectedDetails, gjson.Get(w.Body.String(), "meta.error.details").Raw, c.Name)
+		}
+	}
+}
@@ -1,15 +1,32 @@
 package server
 
 import (
+	"context"
+	"log"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/kickback-app/api/internal/database"
 	"github.com/kickback-app/api/internal/services"
 	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/auth"
+	"github.com/kickback-app/api/server/comments"
+	"github.com/kickback-app/api/server/config"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/idempotency"
+	"github.com/kickback-app/api/server/middlewares"
+	"github.com/kickback-app/api/server/ratelimit"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/requestid"
+	"github.com/kickback-app/api/server/telemetry"
+	"github.com/kickback-app/api/server/twilio"
+	"github.com/kickback-app/api/server/waitlist"
 	"github.com/patrickmn/go-cache"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type S struct {
@@ -23,57 +40,177 @@ type S struct {
 	UserService                 models.UserManager
 	ExpenseService              models.ExpenseManager
 	EventService                models.EventManager
+	TokenIssuer                 *auth.Issuer
+	InviteSigner                auth.InviteSigner
+	RateLimiter                 ratelimit.Store
+	Idempotency                 idempotency.Store
+	// SMS sends texts when twilio is configured, the NotificationService
+	// does otherwise
+	SMS             *twilio.Client
+	SMSDeliveries   twilio.DeliveryStore
+	Schedules       recurrence.Store
+	CalendarFeeds   auth.FeedStore
+	Waitlists       waitlist.Store
+	Guests          guests.Store
+	Deadlines       reminders.Store
+	Comments        comments.Store
+	shutdownTracing func(context.Context) error
+	lifecycle       *lifecycle
+	docs            docstore.DB
+	locks           *writeLocks
+	db              database.Manager
+	s3Client        *s3.S3
+	probeClient     *http.Client
+	config          config.Config
+	mw              *middlewares.Middlewares
 }
 
 func (s S) Engine() *gin.Engine {
 	return s.app
 }
 
-func New(app *gin.Engine, s3Client *s3.S3, dbClient database.Manager) S {
+// Middlewares returns the configured middlewares, e.g. the AccessLogger to
+// attach to the engine
+func (s S) Middlewares() *middlewares.Middlewares {
+	return s.mw
+}
+
+// Shutdown flushes telemetry that hasn't been exported yet and disconnects
+// from the database of the documents
+func (s S) Shutdown(ctx context.Context) error {
+	if mongo, ok := s.docs.(*docstore.Mongo); ok {
+		defer mongo.Close()
+	}
+	if s.shutdownTracing == nil {
+		return nil
+	}
+	return s.shutdownTracing(ctx)
+}
+
+// documents is the database the API keeps its own documents in, in dev and
+// without one, e.g. in tests, they are kept in memory
+func documents(cfg config.Config) (docstore.DB, error) {
+	if cfg.Storage.MongoURL == "" {
+		log.Printf("storage.mongo_url isn't set, keeping documents in memory")
+		return docstore.NewMemory(), nil
+	}
+	return docstore.DialMongo(cfg.Storage.MongoURL.Value(), cfg.Clients.Timeout)
+}
+
+// smsClient sends SMS through twilio when an account is configured, twilio
+// reports how each was delivered to /sms-status when the public URL is known
+func smsClient(cfg config.Config, client *http.Client) *twilio.Client {
+	if cfg.Twilio.AccountSID == "" {
+		log.Printf("twilio.account_sid isn't set, SMS deliveries can't be linked to notifications")
+		return nil
+	}
+	sms := &twilio.Client{
+		AccountSID: cfg.Twilio.AccountSID,
+		AuthToken:  cfg.Twilio.AuthToken.Value(),
+		From:       cfg.Twilio.From,
+		HTTPClient: client,
+	}
+	if cfg.HTTP.PublicBaseURL != "" {
+		sms.StatusCallback = strings.TrimSuffix(cfg.HTTP.PublicBaseURL, "/") + "/sms-status"
+	}
+	return sms
+}
+
+// New wires the services together, cfg should come from config.Load so that
+// it has already been validated
+func New(cfg config.Config, app *gin.Engine, s3Client *s3.S3, dbClient database.Manager) (S, error) {
+	mw, err := middlewares.New(cfg)
+	if err != nil {
+		return S{}, err
+	}
+	// gin trusts every proxy unless told otherwise, letting any client pick
+	// its own IP with X-Forwarded-For
+	if err := app.SetTrustedProxies(cfg.HTTP.TrustedProxyList()); err != nil {
+		return S{}, err
+	}
+	docs, err := documents(cfg)
+	if err != nil {
+		return S{}, err
+	}
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Telemetry)
+	if err != nil {
+		return S{}, err
+	}
+	if s3Client != nil {
+		telemetry.InstrumentAWS(&s3Client.Handlers)
+	}
+	// outbound calls carry the request IDs and trace context of the request that made them
+	transport := otelhttp.NewTransport(requestid.Transport{})
 	userservice := services.UserService{
-		Collection: "users",
+		Collection: cfg.Storage.Collections.Users,
 		DBClient:   dbClient,
-		Cache:      cache.New(5*time.Minute, 10*time.Minute),
+		Cache:      cache.New(cfg.Storage.UserCacheTTL, cfg.Storage.UserCacheCleanup),
 	}
+	// a write can't take longer than the server lets it, its lock expires then
+	locks := &writeLocks{docs: docs.Collection(cfg.Storage.Collections.Locks), ttl: cfg.HTTP.WriteTimeout}
 	return S{
 		app: app,
 		IdentityVerificationService: services.IdentityVerificationManager{
-			Client:      &http.Client{Timeout: 1 * time.Minute},
+			Client:      &http.Client{Timeout: cfg.Clients.Timeout, Transport: transport},
 			UserService: userservice,
 		},
 		ChatService: services.ChatService{
-			Collection: "chats",
+			Collection: cfg.Storage.Collections.Chats,
 			DBClient:   dbClient,
 		},
 		NotificationService: services.NotificationService{
-			Collection: "notifications",
+			Collection: cfg.Storage.Collections.Notifications,
 			DBClient:   dbClient,
-			HTTPClient: &http.Client{Timeout: 1 * time.Minute},
+			HTTPClient: &http.Client{Timeout: cfg.Clients.Timeout, Transport: transport},
 		},
 		NoteService: services.NoteService{
-			Collection: "notes",
+			Collection: cfg.Storage.Collections.Notes,
 			DBClient:   dbClient,
 		},
 		MediaService: services.MediaService{
-			Collection:  "media",
+			Collection:  cfg.Storage.Collections.Media,
 			DBClient:    dbClient,
 			S3Client:    s3Client,
 			UserService: userservice,
 		},
 		TaskService: services.TaskService{
-			Collection: "tasks",
+			Collection: cfg.Storage.Collections.Tasks,
 			DBClient:   dbClient,
 		},
 		ExpenseService: services.ExpenseService{
-			Collection:  "expenses",
+			Collection:  cfg.Storage.Collections.Expenses,
 			DBClient:    dbClient,
 			UserService: userservice,
 		},
 		EventService: services.EventService{
-			Collection:  "events",
+			Collection:  cfg.Storage.Collections.Events,
 			DBClient:    dbClient,
 			UserService: userservice,
 		},
 		UserService: userservice,
-	}
+		TokenIssuer: mw.NewTokenIssuer(auth.NewDBRefreshStore(docs.Collection(cfg.Storage.Collections.RefreshTokens))),
+		InviteSigner: auth.InviteSigner{
+			Secret: []byte(cfg.Auth.InviteSigningSecret.Value()),
+			TTL:    cfg.Auth.InviteTTL,
+		},
+		RateLimiter:     ratelimit.NewDBStore(docs.Collection(cfg.Storage.Collections.RateLimits)),
+		Idempotency:     idempotency.NewDBStore(docs.Collection(cfg.Storage.Collections.Idempotency)),
+		SMS:             smsClient(cfg, &http.Client{Timeout: cfg.Clients.Timeout, Transport: transport}),
+		SMSDeliveries:   twilio.NewDBDeliveryStore(docs.Collection(cfg.Storage.Collections.SMSDeliveries), cfg.Twilio.DeliveryRetention),
+		Schedules:       recurrence.NewDBStore(docs.Collection(cfg.Storage.Collections.Schedules)),
+		CalendarFeeds:   auth.NewDBFeedStore(docs.Collection(cfg.Storage.Collections.CalendarFeeds)),
+		Waitlists:       waitlist.NewDBStore(docs.Collection(cfg.Storage.Collections.Waitlists)),
+		Guests:          guests.NewDBStore(docs.Collection(cfg.Storage.Collections.Guests)),
+		Deadlines:       reminders.NewDBStore(docs.Collection(cfg.Storage.Collections.Deadlines)),
+		Comments:        comments.NewDBStore(docs.Collection(cfg.Storage.Collections.Comments)),
+		shutdownTracing: shutdownTracing,
+		lifecycle:       &lifecycle{},
+		docs:            docs,
+		locks:           locks,
+		db:              dbClient,
+		s3Client:        s3Client,
+		probeClient:     &http.Client{Timeout: probeTimeout},
+		config:          cfg,
+		mw:              mw,
+	}, nil
 }
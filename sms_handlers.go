@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/metrics"
+	"github.com/kickback-app/api/server/twilio"
+)
+
+const smsReplyHelp = "Reply YES, NO or MAYBE to RSVP to your Kickback invite"
+
+var smsReplyStatuses = map[string]models.MemberStatus{
+	"YES":   models.MemberStatusGoing,
+	"Y":     models.MemberStatusGoing,
+	"GOING": models.MemberStatusGoing,
+	"NO":    models.MemberStatusNotGoing,
+	"N":     models.MemberStatusNotGoing,
+	"MAYBE": models.MemberStatusMaybe,
+}
+
+var smsReplyStatusText = map[models.MemberStatus]string{
+	models.MemberStatusGoing:    "going to",
+	models.MemberStatusNotGoing: "not going to",
+	models.MemberStatusMaybe:    "a maybe for",
+}
+
+// ReceiveSMS handles replies to invite texts, invitees can RSVP by texting
+// back YES, NO or MAYBE. When they have several pending invites we list them
+// and they pick one by number, e.g. "YES 2".
+// https://www.twilio.com/docs/messaging/guides/webhook-request
+func (s S) ReceiveSMS(c *gin.Context) {
+	from := c.PostForm("From")
+	body := c.PostForm("Body")
+	reply := s.rsvpBySMS(c, from, body)
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", twilio.MessagingResponse(reply))
+}
+
+// rsvpBySMS applies the RSVP in an sms reply and returns the text to send back
+func (s S) rsvpBySMS(c *gin.Context, from, body string) string {
+	status, choice, ok := parseSMSReply(body)
+	if !ok {
+		return smsReplyHelp
+	}
+	user, err := s.UserService.GetUserByPhoneNumber(c, from)
+	if err != nil {
+		logger.Warn(c, "unable to find user for inbound sms: %v", err)
+		return "We couldn't find a Kickback account for this number"
+	}
+	pending, err := s.pendingInvites(c, user.ID)
+	if err != nil {
+		logger.Error(c, "unable to get pending invites for %s: %v", user.ID, err)
+		return "Something went wrong, please try again later"
+	}
+	var event models.Event
+	switch {
+	case len(pending) == 0:
+		return "You don't have any pending Kickback invites"
+	case choice > 0 && choice <= len(pending):
+		event = pending[choice-1]
+	case choice == 0 && len(pending) == 1:
+		event = pending[0]
+	default:
+		msg := fmt.Sprintf("You have %d pending invites:\n", len(pending))
+		for i, e := range pending {
+			msg += fmt.Sprintf("%d. %s\n", i+1, e.Name)
+		}
+		return msg + "Reply YES, NO or MAYBE followed by the number, e.g. YES 1"
+	}
+	waiting, err := s.setRSVP(c, event.ID, user.ID, status, nil)
+	if closed, ok := err.(handlers.RSVPClosedError); ok {
+		return fmt.Sprintf("Sorry, RSVPs for %s closed on %s", event.Name, closed.Deadline.Format(deadlineLayout))
+	}
+	if err != nil {
+		logger.Error(c, "unable to rsvp %s to event %s: %v", user.ID, event.ID, err)
+		return "Something went wrong, please try again later"
+	}
+	if waiting != nil {
+		metrics.RSVP("waitlisted", "sms")
+		logger.Info(c, "put member %s on the waitlist of event %s at position %d by sms", user.ID, event.ID, waiting.Position)
+		return fmt.Sprintf("%s is full, you're #%d on the waitlist. We'll text you if a spot opens up", event.Name, waiting.Position)
+	}
+	s.announceRSVP(c, event, user, status, "sms")
+	logger.Info(c, "set member %s status to %s for event %s by sms", user.ID, status, event.ID)
+	return fmt.Sprintf("Thanks! You're %s %s", smsReplyStatusText[status], event.Name)
+}
+
+// pendingInvites returns the events the user hasn't responded to yet, oldest
+// first so the numbering we send stays the same when they reply
+func (s S) pendingInvites(c *gin.Context, userID string) ([]models.Event, error) {
+	events, err := s.EventService.GetUsersEvents(c, userID, &models.GetFilters{})
+	if err != nil {
+		return nil, err
+	}
+	pending := []models.Event{}
+	for _, event := range events {
+		for _, member := range event.Members {
+			if member.UserID == userID && member.Status == models.MemberStatusInvited {
+				pending = append(pending, event)
+				break
+			}
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].CreatedAt == pending[j].CreatedAt {
+			return pending[i].ID < pending[j].ID
+		}
+		return pending[i].CreatedAt < pending[j].CreatedAt
+	})
+	return pending, nil
+}
+
+// parseSMSReply reads "<status> [number]", choice is 0 when no number was given
+func parseSMSReply(body string) (models.MemberStatus, int, bool) {
+	fields := strings.Fields(strings.ToUpper(strings.Trim(body, " \t\n.!")))
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", 0, false
+	}
+	status, ok := smsReplyStatuses[fields[0]]
+	if !ok {
+		return "", 0, false
+	}
+	if len(fields) == 1 {
+		return status, 0, true
+	}
+	choice, err := strconv.Atoi(strings.TrimPrefix(fields[1], "#"))
+	if err != nil || choice < 1 {
+		return "", 0, false
+	}
+	return status, choice, true
+}
@@ -0,0 +1,132 @@
+package server_test
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/internal/services"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/waitlist"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveSMS(t *testing.T) {
+	mockUser := `{"_id": "mockUserId", "phone_number": "+15005550006"}`
+	pendingInvites := `[
+		{"_id": "EVT_1", "name": "Game night", "created_at": 1, "members": [{"userId": "mockUserId", "status": "invited"}]},
+		{"_id": "EVT_2", "name": "Beach day", "created_at": 2, "members": [{"userId": "mockUserId", "status": "invited"}]},
+		{"_id": "EVT_3", "name": "Brunch", "created_at": 3, "members": [{"userId": "mockUserId", "status": "going"}]}
+	]`
+	closed := time.Now().Add(-time.Hour)
+	deadlines := reminders.NewMemoryStore()
+	deadlines.Save(reminders.New("EVT_Closed", reminders.Policy{Deadline: &closed}))
+	cases := []struct {
+		Name                    string
+		Body                    string
+		UserServiceDBResponses  []interface{}
+		EventServiceDBResponses []interface{}
+		ExpectedReply           string
+	}{
+		{
+			Name:          "not an rsvp",
+			Body:          "see you there",
+			ExpectedReply: "Reply YES, NO or MAYBE to RSVP to your Kickback invite",
+		},
+		{
+			Name:                   "unknown number",
+			Body:                   "YES",
+			UserServiceDBResponses: []interface{}{utils.MockCaughtError{StatusCode: http.StatusNotFound}},
+			ExpectedReply:          "We couldn't find a Kickback account for this number",
+		},
+		{
+			Name:                    "no pending invites",
+			Body:                    "yes",
+			UserServiceDBResponses:  []interface{}{mockUser},
+			EventServiceDBResponses: []interface{}{`[{"_id": "EVT_3", "members": [{"userId": "mockUserId", "status": "going"}]}]`},
+			ExpectedReply:           "You don't have any pending Kickback invites",
+		},
+		{
+			Name:                    "several pending invites are listed oldest first",
+			Body:                    "MAYBE",
+			UserServiceDBResponses:  []interface{}{mockUser},
+			EventServiceDBResponses: []interface{}{pendingInvites},
+			ExpectedReply:           "You have 2 pending invites:\n1. Game night\n2. Beach day\n",
+		},
+		{
+			Name:                    "happy path - pick an invite by number",
+			Body:                    "No #2.",
+			UserServiceDBResponses:  []interface{}{mockUser},
+			EventServiceDBResponses: []interface{}{pendingInvites, int64(1)},
+			ExpectedReply:           "Thanks! You're not going to Beach day",
+		},
+		{
+			Name:                   "happy path - single pending invite",
+			Body:                   "y",
+			UserServiceDBResponses: []interface{}{mockUser},
+			EventServiceDBResponses: []interface{}{
+				`[{"_id": "EVT_1", "name": "Game night", "members": [{"userId": "mockUserId", "status": "invited"}]}]`,
+				int64(1),
+			},
+			ExpectedReply: "Thanks! You're going to Game night",
+		},
+		{
+			Name:                   "rsvps closed after the deadline",
+			Body:                   "YES",
+			UserServiceDBResponses: []interface{}{mockUser},
+			EventServiceDBResponses: []interface{}{
+				`[{"_id": "EVT_Closed", "name": "Potluck", "members": [{"userId": "mockUserId", "status": "invited"}]}]`,
+			},
+			ExpectedReply: "Sorry, RSVPs for Potluck closed on",
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		mockServer := server.S{
+			UserService: services.UserService{
+				DBClient: utils.MockDBClient{
+					CallCount:       new(int),
+					DefaultResponse: "[]",
+					Responses:       c.UserServiceDBResponses,
+				},
+				Cache: &utils.MockCache{
+					Callcount: new(int),
+					Items:     map[string]interface{}{},
+				},
+			},
+			EventService: services.EventService{
+				DBClient: utils.MockDBClient{
+					CallCount: new(int),
+					Responses: c.EventServiceDBResponses,
+				},
+			},
+			NotificationService: services.NotificationService{
+				DBClient: utils.MockDBClient{
+					CallCount:       new(int),
+					DefaultResponse: "NTF_Mock",
+				},
+			},
+			Guests:    guests.NewMemoryStore(),
+			Waitlists: waitlist.NewMemoryStore(),
+			Deadlines: deadlines,
+		}
+		form := url.Values{"From": {"+15005550006"}, "Body": {c.Body}}
+		utils.MockRequest(ctx, http.MethodPost, form.Encode())
+		mockServer.ReceiveSMS(ctx)
+		assert.EqualValues(t, http.StatusOK, w.Code, c.Name)
+		// the reply is TwiML, unescape it to compare the text
+		assert.Contains(t, html.UnescapeString(w.Body.String()), c.ExpectedReply, c.Name)
+	}
+}
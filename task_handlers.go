@@ -8,7 +8,10 @@ import (
 	"github.com/kickback-app/api/logger"
 	"github.com/kickback-app/api/pkg/models"
 	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/paging"
+	"github.com/kickback-app/api/server/telemetry"
 	"github.com/kickback-app/api/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func (s S) CreateTask(c *gin.Context) {
@@ -20,7 +23,11 @@ func (s S) CreateTask(c *gin.Context) {
 	}
 	var t models.Task
 	if err := json.NewDecoder(c.Request.Body).Decode(&t); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if err := validateTask(t); err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
 	t.ParentID = kickbackID
@@ -42,7 +49,10 @@ func (s S) GetTask(c *gin.Context) {
 	}
 	task, err := s.TaskService.GetTask(c, taskID)
 	if err != nil {
-		handlers.EncodeError(c, err)
+		handlers.EncodeError(c, handlers.NotFound("task", err))
+		return
+	}
+	if notModified(c, task) {
 		return
 	}
 	logger.Info(c, "retrieved task %s", taskID)
@@ -56,21 +66,32 @@ func (s S) GetTasks(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
+	query, err := paging.Parse(c.Request.URL.Query(), taskListing)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	endSpan := telemetry.StartSpan(c, "TaskService.GetTasks")
 	res, err := s.TaskService.GetTasks(c, kickbackID)
+	endSpan(err)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
 	}
+	page, nextCursor := query.Apply(normalizeEach(res))
 	tasksWithUserInfo := []models.M{}
-	for _, task := range res {
+	for _, i := range page {
+		task := res[i]
+		endSpan = telemetry.StartSpan(c, "UserService.SummarizeUsers", attribute.String("taskId", task.ID))
 		assignees := s.UserService.SummarizeUsers(c, task.Assignees).Summaries
 		taskAsMap := utils.Normalize(task)
 		taskAsMap["assignees"] = assignees
 		taskAsMap["created_by"] = s.UserService.SummarizeUsers(c, []string{task.CreatedBy}).Find(task.CreatedBy)
+		endSpan(nil)
 		tasksWithUserInfo = append(tasksWithUserInfo, taskAsMap)
 	}
 	logger.Info(c, "retrieved %d tasks for kickback %s", len(tasksWithUserInfo), kickbackID)
-	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"tasks": tasksWithUserInfo})
+	handlers.EncodePage(c, http.StatusOK, gin.H{"tasks": tasksWithUserInfo}, nextCursor)
 }
 
 func (s S) UpdateTask(c *gin.Context) {
@@ -82,10 +103,22 @@ func (s S) UpdateTask(c *gin.Context) {
 	}
 	var updates models.TaskUpdates
 	if err := json.NewDecoder(c.Request.Body).Decode(&updates); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if err := validateTaskUpdates(updates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	release, err := s.guardWrite(c, "task", taskID, func() (interface{}, error) {
+		return s.TaskService.GetTask(c, taskID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
 		return
 	}
-	err := s.TaskService.UpdateTask(c, taskID, updates)
+	defer release()
+	err = s.TaskService.UpdateTask(c, taskID, updates)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
@@ -94,6 +127,46 @@ func (s S) UpdateTask(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
 
+// PatchTask applies a merge patch to the task, see applyMergePatch
+func (s S) PatchTask(c *gin.Context) {
+	param := "taskId"
+	taskID := c.Param(param)
+	if taskID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	release, err := s.guardWrite(c, "task", taskID, func() (interface{}, error) {
+		return s.TaskService.GetTask(c, taskID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	current, err := s.TaskService.GetTask(c, taskID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("task", err))
+		return
+	}
+	var patched models.Task
+	var updates models.TaskUpdates
+	if err := applyMergePatch(c, taskPatchable, current, &patched, &updates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if err := validateTask(patched); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err = s.TaskService.UpdateTask(c, taskID, updates)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	logger.Info(c, "successfully patched task %s", taskID)
+	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+}
+
 func (s S) DeleteTask(c *gin.Context) {
 	param := "taskId"
 	taskID := c.Param(param)
@@ -101,7 +174,15 @@ func (s S) DeleteTask(c *gin.Context) {
 		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
 		return
 	}
-	err := s.TaskService.DeleteTask(c, taskID)
+	release, err := s.guardWrite(c, "task", taskID, func() (interface{}, error) {
+		return s.TaskService.GetTask(c, taskID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	err = s.TaskService.DeleteTask(c, taskID)
 	if err != nil {
 		handlers.EncodeError(c, err)
 		return
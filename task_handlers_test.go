@@ -60,7 +60,7 @@ func TestGetTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "missing required path parameter 'taskId'",
-				"errorCode": ""
+				"errorCode": "MISSING_PATH_PARAM"
 				}`,
 		},
 		{
@@ -71,7 +71,7 @@ func TestGetTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "caught err",
-				"errorCode": ""
+				"errorCode": "UNKNOWN_ERROR"
 				}`,
 		},
 		{
@@ -82,7 +82,7 @@ func TestGetTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "internal service error",
-				"errorCode": ""
+				"errorCode": "INTERNAL_ERROR"
 				}`,
 		},
 	}
@@ -189,7 +189,7 @@ func TestGetTasksHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "missing required path parameter 'kickbackId'",
-				"errorCode": ""
+				"errorCode": "MISSING_PATH_PARAM"
 				}`,
 		},
 		{
@@ -200,7 +200,7 @@ func TestGetTasksHandler(t *testing.T) {
 			PathToResult:         "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "caught err",
-				"errorCode": ""
+				"errorCode": "UNKNOWN_ERROR"
 				}`,
 		},
 		{
@@ -211,7 +211,7 @@ func TestGetTasksHandler(t *testing.T) {
 			PathToResult:         "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "internal service error",
-				"errorCode": ""
+				"errorCode": "INTERNAL_ERROR"
 				}`,
 		},
 	}
@@ -277,7 +277,7 @@ func TestCreateTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "missing required path parameter 'kickbackId'",
-				"errorCode": ""
+				"errorCode": "MISSING_PATH_PARAM"
 				}`,
 		},
 		{
@@ -288,31 +288,43 @@ func TestCreateTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "malformed request body",
-				"errorCode": ""
+				"errorCode": "MALFORMED_BODY"
+				}`,
+		},
+		{
+			Name:               "missing name fails validation",
+			Params:             []gin.Param{{Key: "kickbackId", Value: "EVT_mock"}},
+			RequestBody:        `{"name": "  "}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error",
+			ExpectedResult: `{
+				"errorMessage": "invalid request body: 'name' is required",
+				"errorCode": "VALIDATION_FAILED",
+				"details": [{"field": "name", "rule": "required", "message": "is required"}]
 				}`,
 		},
 		{
 			Name:               "internal caught error",
 			Params:             []gin.Param{{Key: "kickbackId", Value: "EVT_mock"}},
-			RequestBody:        "{}",
+			RequestBody:        `{"name": "mock task"}`,
 			DBResponse:         utils.MockCaughtError{StatusCode: 861},
 			ExpectedStatusCode: 861,
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "caught err",
-				"errorCode": ""
+				"errorCode": "UNKNOWN_ERROR"
 				}`,
 		},
 		{
 			Name:               "internal service error",
 			Params:             []gin.Param{{Key: "kickbackId", Value: "EVT_mock"}},
-			RequestBody:        "{}",
+			RequestBody:        `{"name": "mock task"}`,
 			DBResponse:         utils.MockUncaughtError{},
 			ExpectedStatusCode: http.StatusInternalServerError,
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "internal service error",
-				"errorCode": ""
+				"errorCode": "INTERNAL_ERROR"
 				}`,
 		},
 	}
@@ -368,7 +380,7 @@ func TestUpdateTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "missing required path parameter 'taskId'",
-				"errorCode": ""
+				"errorCode": "MISSING_PATH_PARAM"
 				}`,
 		},
 		{
@@ -379,7 +391,7 @@ func TestUpdateTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "malformed request body",
-				"errorCode": ""
+				"errorCode": "MALFORMED_BODY"
 				}`,
 		},
 		{
@@ -391,7 +403,7 @@ func TestUpdateTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "caught err",
-				"errorCode": ""
+				"errorCode": "UNKNOWN_ERROR"
 				}`,
 		},
 		{
@@ -403,7 +415,7 @@ func TestUpdateTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "internal service error",
-				"errorCode": ""
+				"errorCode": "INTERNAL_ERROR"
 				}`,
 		},
 	}
@@ -461,7 +473,7 @@ func TestDeleteTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "missing required path parameter 'taskId'",
-				"errorCode": ""
+				"errorCode": "MISSING_PATH_PARAM"
 				}`,
 		},
 		{
@@ -473,7 +485,7 @@ func TestDeleteTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "caught err",
-				"errorCode": ""
+				"errorCode": "UNKNOWN_ERROR"
 				}`,
 		},
 		{
@@ -485,7 +497,7 @@ func TestDeleteTaskHandler(t *testing.T) {
 			PathToResult:       "meta.error",
 			ExpectedResult: `{
 				"errorMessage": "internal service error",
-				"errorCode": ""
+				"errorCode": "INTERNAL_ERROR"
 				}`,
 		},
 	}
@@ -516,3 +528,123 @@ func TestDeleteTaskHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestTaskConditionalRequests(t *testing.T) {
+	storedTask := `{"_id": "TSK_Mock", "name": "mock task", "parentId": "mockParentId", "updated_at": 666}`
+	newServer := func(responses ...interface{}) server.S {
+		callcount := 0
+		return server.S{
+			TaskService: services.TaskService{
+				DBClient: utils.MockDBClient{
+					CallCount:       &callcount,
+					DefaultResponse: 0,
+					Responses:       responses,
+				},
+			},
+		}
+	}
+	request := func(method, body string, header http.Header) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: header}
+		ctx.Params = []gin.Param{{Key: "taskId", Value: "TSK_Mock"}}
+		utils.MockRequest(ctx, method, body)
+		return ctx, w
+	}
+
+	ctx, w := request(http.MethodGet, "", http.Header{})
+	newServer(storedTask).GetTask(ctx)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	tag := w.Header().Get("ETag")
+	assert.NotEmpty(t, tag)
+
+	// the client's copy is current
+	ctx, w = request(http.MethodGet, "", http.Header{"If-None-Match": {tag}})
+	newServer(storedTask).GetTask(ctx)
+	assert.EqualValues(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// someone else renamed the task since it was fetched
+	changedTask := `{"_id": "TSK_Mock", "name": "renamed task", "parentId": "mockParentId", "updated_at": 777}`
+	ctx, w = request(http.MethodPut, `{"name": "my rename"}`, http.Header{"If-Match": {tag}})
+	newServer(changedTask).UpdateTask(ctx)
+	assert.EqualValues(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, "PRECONDITION_FAILED", gjson.Get(w.Body.String(), "meta.error.errorCode").String())
+	assert.NotEqual(t, tag, w.Header().Get("ETag"))
+
+	ctx, w = request(http.MethodPut, `{"name": "my rename"}`, http.Header{"If-Match": {tag}})
+	newServer(storedTask, 1).UpdateTask(ctx)
+	assert.EqualValues(t, http.StatusNoContent, w.Code)
+}
+
+func TestPatchTaskHandler(t *testing.T) {
+	storedTask := `{"_id": "TSK_Mock", "name": "mock task", "parentId": "mockParentId", "created_by": "mockUserId"}`
+	cases := []struct {
+		Name               string
+		ContentType        string
+		RequestBody        string
+		DBResponses        []interface{}
+		ExpectedStatusCode int
+		PathToResult       string
+		ExpectedResult     string
+	}{
+		{
+			Name:               "happy path - can patch task",
+			ContentType:        "application/merge-patch+json",
+			RequestBody:        `{"is_completed": true}`,
+			DBResponses:        []interface{}{storedTask, 1},
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "fields outside the whitelist are rejected",
+			RequestBody:        `{"created_by": "someoneElse", "parentId": "otherKickback"}`,
+			DBResponses:        []interface{}{storedTask},
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.#.field",
+			ExpectedResult:     `["created_by", "parentId"]`,
+		},
+		{
+			Name:               "patched document is validated",
+			RequestBody:        `{"name": null}`,
+			DBResponses:        []interface{}{storedTask},
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details",
+			ExpectedResult:     `[{"field": "name", "rule": "required", "message": "is required"}]`,
+		},
+		{
+			Name:               "json patch is not supported",
+			ContentType:        "application/json-patch+json",
+			RequestBody:        `[{"op": "replace", "path": "/name", "value": "x"}]`,
+			DBResponses:        []interface{}{storedTask},
+			ExpectedStatusCode: http.StatusUnsupportedMediaType,
+			PathToResult:       "meta.error.errorCode",
+			ExpectedResult:     `"UNSUPPORTED_MEDIA_TYPE"`,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: http.Header{"Content-Type": {c.ContentType}}}
+		ctx.Params = []gin.Param{{Key: "taskId", Value: "TSK_Mock"}}
+		callcount := 0
+		mockServer := server.S{
+			TaskService: services.TaskService{
+				DBClient: utils.MockDBClient{
+					CallCount:       &callcount,
+					DefaultResponse: 0,
+					Responses:       c.DBResponses,
+				},
+			},
+		}
+		utils.MockRequest(ctx, http.MethodPatch, c.RequestBody)
+		mockServer.PatchTask(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		if c.ExpectedResult != "" {
+			actual := gjson.Get(w.Body.String(), c.PathToResult).Raw
+			assert.JSONEq(t, c.ExpectedResult, actual, c.Name)
+		}
+	}
+}
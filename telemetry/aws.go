@@ -0,0 +1,41 @@
+package telemetry
+
+import (
+	"github.com/aws/aws-sdk-go/aws/request"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentAWS traces every call made through an aws client, e.g.
+// telemetry.InstrumentAWS(&s3Client.Handlers)
+func InstrumentAWS(h *request.Handlers) {
+	h.Validate.PushFrontNamed(request.NamedHandler{
+		Name: "telemetry.StartSpan",
+		Fn: func(r *request.Request) {
+			ctx, _ := tracer().Start(r.Context(), r.ClientInfo.ServiceName+"."+r.Operation.Name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("rpc.system", "aws-api"),
+					attribute.String("rpc.service", r.ClientInfo.ServiceName),
+					attribute.String("rpc.method", r.Operation.Name),
+				),
+			)
+			r.SetContext(ctx)
+		},
+	})
+	h.Complete.PushBackNamed(request.NamedHandler{
+		Name: "telemetry.EndSpan",
+		Fn: func(r *request.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if r.HTTPResponse != nil {
+				span.SetAttributes(attribute.Int("http.status_code", r.HTTPResponse.StatusCode))
+			}
+			if r.Error != nil {
+				span.RecordError(r.Error)
+				span.SetStatus(codes.Error, r.Error.Error())
+			}
+			span.End()
+		},
+	})
+}
@@ -0,0 +1,86 @@
+// Package telemetry sets up OpenTelemetry tracing for the API.
+//
+// Spans are exported according to telemetry.traces_exporter: "stdout" prints
+// them, "otlp" sends them over OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT (e.g.
+// a collector container on http://localhost:4318) and "none" disables
+// exporting. Trace context is propagated with W3C traceparent headers.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/kickback-app/api/server"
+
+// Setup installs the global tracer provider and propagator. The returned
+// func flushes buffered spans and must be called before the process exits.
+func Setup(ctx context.Context, cfg config.Telemetry) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.TracesExporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "otlp":
+		// endpoint, headers and TLS are read from the standard OTEL_EXPORTER_OTLP_* vars
+		exporter, err = otlptracehttp.New(ctx)
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("unsupported traces exporter %q", cfg.TracesExporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create trace exporter: %v", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartSpan starts a child of the request's current span, spans started from
+// c while it's open become its children. Call the returned func with the
+// outcome of the traced work to end it.
+func StartSpan(c *gin.Context, name string, attrs ...attribute.KeyValue) func(error) {
+	parent := c.Request.Context()
+	ctx, span := tracer().Start(parent, name, trace.WithAttributes(attrs...))
+	c.Request = c.Request.WithContext(ctx)
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		c.Request = c.Request.WithContext(parent)
+	}
+}
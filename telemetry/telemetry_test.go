@@ -0,0 +1,39 @@
+package telemetry_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/server/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/v1/tasks/1", nil)
+	reqCtx := c.Request.Context()
+
+	endOuter := telemetry.StartSpan(c, "outer")
+	endInner := telemetry.StartSpan(c, "inner")
+	endInner(errors.New("boom"))
+	endOuter(nil)
+	assert.Equal(t, reqCtx, c.Request.Context(), "request context should be restored")
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		inner, outer := spans[0], spans[1]
+		assert.Equal(t, "inner", inner.Name())
+		assert.Equal(t, outer.SpanContext().SpanID(), inner.Parent().SpanID())
+		assert.Equal(t, codes.Error, inner.Status().Code)
+		assert.Equal(t, codes.Unset, outer.Status().Code)
+	}
+}
@@ -0,0 +1,90 @@
+package twilio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is where twilio's REST API is served
+const DefaultBaseURL = "https://api.twilio.com"
+
+// Client sends SMS through twilio's Messages API
+// https://www.twilio.com/docs/sms/api/message-resource#create-a-message-resource
+type Client struct {
+	AccountSID string
+	AuthToken  string
+	// From is the number messages are sent from, or the SID of the
+	// messaging service that picks one (MG...)
+	From string
+	// StatusCallback is where twilio posts how each message was delivered,
+	// statuses aren't reported when it is empty
+	StatusCallback string
+	// BaseURL defaults to DefaultBaseURL
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// APIError is the error twilio returned for a request
+// https://www.twilio.com/docs/usage/twilios-response#response-formats-exceptions
+type APIError struct {
+	Status  int    `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e APIError) Error() string {
+	return fmt.Sprintf("twilio returned %d: %s (code %d)", e.Status, e.Message, e.Code)
+}
+
+// Send texts body to the phone number to and returns the SID twilio gave the
+// message, the statuses posted to StatusCallback are keyed by it
+func (c Client) Send(ctx context.Context, to, body string) (string, error) {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if strings.HasPrefix(c.From, "MG") {
+		form.Set("MessagingServiceSid", c.From)
+	} else {
+		form.Set("From", c.From)
+	}
+	if c.StatusCallback != "" {
+		form.Set("StatusCallback", c.StatusCallback)
+	}
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/2010-04-01/Accounts/" + url.PathEscape(c.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.AccountSID, c.AuthToken)
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		apiErr := APIError{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return "", apiErr
+	}
+	var message struct {
+		SID string `json:"sid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return "", fmt.Errorf("unable to read the message twilio created: %v", err)
+	}
+	if message.SID == "" {
+		return "", fmt.Errorf("twilio didn't return the SID of the message")
+	}
+	return message.SID, nil
+}
@@ -0,0 +1,47 @@
+package twilio_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kickback-app/api/server/twilio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientSend(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC1/Messages.json", r.URL.Path)
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "AC1", user)
+		assert.Equal(t, "secret", pass)
+		r.ParseForm()
+		if r.PostForm.Get("To") == "+15005550001" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": 21211, "message": "The 'To' number is not a valid phone number.", "status": 400}`))
+			return
+		}
+		assert.Equal(t, "+15005550006", r.PostForm.Get("To"))
+		assert.Equal(t, "hello", r.PostForm.Get("Body"))
+		assert.Equal(t, "MG1", r.PostForm.Get("MessagingServiceSid"))
+		assert.Equal(t, "https://api.kickbackapp.io/sms-status", r.PostForm.Get("StatusCallback"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid": "SM1", "status": "queued"}`))
+	}))
+	defer api.Close()
+	client := twilio.Client{
+		AccountSID:     "AC1",
+		AuthToken:      "secret",
+		From:           "MG1",
+		StatusCallback: "https://api.kickbackapp.io/sms-status",
+		BaseURL:        api.URL,
+	}
+
+	sid, err := client.Send(context.Background(), "+15005550006", "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "SM1", sid)
+
+	_, err = client.Send(context.Background(), "+15005550001", "hello")
+	assert.Equal(t, twilio.APIError{Status: 400, Code: 21211, Message: "The 'To' number is not a valid phone number."}, err)
+}
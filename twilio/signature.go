@@ -0,0 +1,64 @@
+package twilio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const HeaderSignature = "X-Twilio-Signature"
+
+// Signature computes the X-Twilio-Signature for a webhook request: the full
+// URL followed by every POST param name and value sorted by name, signed
+// with HMAC-SHA1 using the account's auth token
+// https://www.twilio.com/docs/usage/security#validating-requests
+func Signature(authToken, fullURL string, params url.Values) string {
+	var b strings.Builder
+	b.WriteString(fullURL)
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		values := append([]string{}, params[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			b.WriteString(k)
+			b.WriteString(v)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(b.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidSignature reports whether signature matches the request
+func ValidSignature(authToken, fullURL string, params url.Values, signature string) bool {
+	if authToken == "" || signature == "" {
+		return false
+	}
+	expected := Signature(authToken, fullURL, params)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// RequestURL reconstructs the URL twilio called. Behind the heroku router the
+// request reaches us over plain http, so either an explicit public base URL
+// or the X-Forwarded-Proto header is needed to get the scheme right.
+func RequestURL(r *http.Request, publicBaseURL string) string {
+	if publicBaseURL != "" {
+		return strings.TrimSuffix(publicBaseURL, "/") + r.URL.RequestURI()
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
@@ -0,0 +1,37 @@
+package twilio_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/kickback-app/api/server/twilio"
+	"github.com/stretchr/testify/assert"
+)
+
+// example from https://www.twilio.com/docs/usage/security#validating-requests
+func TestSignature(t *testing.T) {
+	params := url.Values{
+		"CallSid": {"CA1234567890ABCDE"},
+		"Caller":  {"+12349013030"},
+		"Digits":  {"1234"},
+		"From":    {"+12349013030"},
+		"To":      {"+18005551212"},
+	}
+	fullURL := "https://mycompany.com/myapp.php?foo=1&bar=2"
+	assert.Equal(t, "0/KCTR6DLpKmkAf8muzZqo1nDgQ=", twilio.Signature("12345", fullURL, params))
+	assert.True(t, twilio.ValidSignature("12345", fullURL, params, "0/KCTR6DLpKmkAf8muzZqo1nDgQ="))
+	assert.False(t, twilio.ValidSignature("54321", fullURL, params, "0/KCTR6DLpKmkAf8muzZqo1nDgQ="))
+	assert.False(t, twilio.ValidSignature("", fullURL, params, twilio.Signature("", fullURL, params)))
+
+	params.Set("Digits", "4321")
+	assert.False(t, twilio.ValidSignature("12345", fullURL, params, "0/KCTR6DLpKmkAf8muzZqo1nDgQ="))
+}
+
+func TestRequestURL(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "http://internal:8080/sms-status?x=1", nil)
+	r.Host = "api.kickbackapp.io"
+	r.Header.Set("X-Forwarded-Proto", "https")
+	assert.Equal(t, "https://api.kickbackapp.io/sms-status?x=1", twilio.RequestURL(r, ""))
+	assert.Equal(t, "https://kickback.example/sms-status?x=1", twilio.RequestURL(r, "https://kickback.example/"))
+}
@@ -0,0 +1,202 @@
+package twilio
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+// https://www.twilio.com/docs/sms/api/message-resource#message-status-values
+const (
+	StatusQueued      = "queued"
+	StatusSent        = "sent"
+	StatusDelivered   = "delivered"
+	StatusUndelivered = "undelivered"
+	StatusFailed      = "failed"
+)
+
+var (
+	ErrStatusNotFound       = errors.New("sms status not found")
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// DeliveryStatus is the latest status twilio reported for a message, linked
+// to the notification that caused it to be sent when we know it
+type DeliveryStatus struct {
+	MessageSID     string    `json:"message_sid"`
+	AccountSID     string    `json:"account_sid"`
+	From           string    `json:"from"`
+	To             string    `json:"to"`
+	Status         string    `json:"status"`
+	ErrorCode      string    `json:"error_code,omitempty"`
+	NotificationID string    `json:"notificationId,omitempty"`
+	UserID         string    `json:"userId,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Failed reports whether the message will never reach the recipient
+func (d DeliveryStatus) Failed() bool {
+	return d.Status == StatusFailed || d.Status == StatusUndelivered
+}
+
+// ParseStatusCallback reads the form payload twilio posts to the StatusCallback url
+func ParseStatusCallback(form url.Values) (DeliveryStatus, error) {
+	status := DeliveryStatus{
+		MessageSID: form.Get("MessageSid"),
+		AccountSID: form.Get("AccountSid"),
+		From:       form.Get("From"),
+		To:         form.Get("To"),
+		Status:     form.Get("MessageStatus"),
+		ErrorCode:  form.Get("ErrorCode"),
+		UpdatedAt:  time.Now(),
+	}
+	if status.Status == "" {
+		// older callbacks only send SmsStatus
+		status.Status = form.Get("SmsStatus")
+	}
+	if status.MessageSID == "" || status.Status == "" {
+		return DeliveryStatus{}, errors.New("status callback is missing MessageSid or MessageStatus")
+	}
+	return status, nil
+}
+
+// DeliveryStore persists delivery statuses keyed by message SID, and who sent
+// each notification so only they can read how it was delivered.
+//
+// Track links the SID twilio returned when an SMS was sent to the notification
+// and user it was sent for. Callbacks can arrive before Track is called, Save
+// and Track link them in either order.
+type DeliveryStore interface {
+	// Sent records that the user sentBy sent notificationID
+	Sent(notificationID, sentBy string) error
+	// SentBy returns ErrNotificationNotFound for notifications Sent wasn't called for
+	SentBy(notificationID string) (string, error)
+	Track(messageSID, notificationID, userID string) error
+	Save(status DeliveryStatus) (DeliveryStatus, error)
+	Get(messageSID string) (DeliveryStatus, error)
+	ForNotification(notificationID string) ([]DeliveryStatus, error)
+}
+
+// delivery is a DeliveryStatus as stored, Status is empty while no callback
+// arrived for a message that was tracked
+type delivery struct {
+	DeliveryStatus
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (d delivery) Expires() time.Time {
+	return d.ExpiresAt
+}
+
+// sender records who sent a notification
+type sender struct {
+	SentBy    string    `json:"sentBy"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (s sender) Expires() time.Time {
+	return s.ExpiresAt
+}
+
+func messageKey(messageSID string) string          { return "message:" + messageSID }
+func notificationKey(notificationID string) string { return "notification:" + notificationID }
+
+// DBDeliveryStore keeps delivery statuses and senders in the database, shared
+// by every instance. Each is deleted retention after it was last written.
+type DBDeliveryStore struct {
+	docs      docstore.Collection
+	retention time.Duration
+}
+
+func NewDBDeliveryStore(docs docstore.Collection, retention time.Duration) *DBDeliveryStore {
+	return &DBDeliveryStore{docs: docs, retention: retention}
+}
+
+// NewMemoryDeliveryStore keeps delivery statuses in memory for a day, for
+// tests and single instance deployments
+func NewMemoryDeliveryStore() *DBDeliveryStore {
+	return NewDBDeliveryStore(docstore.NewMemory().Collection("sms_deliveries"), 24*time.Hour)
+}
+
+func (d *DBDeliveryStore) Sent(notificationID, sentBy string) error {
+	return docstore.Retry(func() error {
+		var stored sender
+		version, err := d.docs.Get(notificationKey(notificationID), &stored)
+		if err != nil && err != docstore.ErrNotFound {
+			return err
+		}
+		_, err = d.docs.Put(notificationKey(notificationID), version, sender{SentBy: sentBy, ExpiresAt: time.Now().Add(d.retention)})
+		return err
+	})
+}
+
+func (d *DBDeliveryStore) SentBy(notificationID string) (string, error) {
+	var stored sender
+	_, err := d.docs.Get(notificationKey(notificationID), &stored)
+	if err == docstore.ErrNotFound {
+		return "", ErrNotificationNotFound
+	}
+	return stored.SentBy, err
+}
+
+// update applies change to the stored delivery of messageSID, or to an empty
+// one, and stores the result
+func (d *DBDeliveryStore) update(messageSID string, change func(stored *delivery)) (delivery, error) {
+	var stored delivery
+	err := docstore.Retry(func() error {
+		stored = delivery{}
+		version, err := d.docs.Get(messageKey(messageSID), &stored)
+		if err != nil && err != docstore.ErrNotFound {
+			return err
+		}
+		change(&stored)
+		stored.MessageSID = messageSID
+		stored.ExpiresAt = time.Now().Add(d.retention)
+		_, err = d.docs.Put(messageKey(messageSID), version, stored)
+		return err
+	})
+	return stored, err
+}
+
+func (d *DBDeliveryStore) Track(messageSID, notificationID, userID string) error {
+	_, err := d.update(messageSID, func(stored *delivery) {
+		stored.NotificationID = notificationID
+		stored.UserID = userID
+	})
+	return err
+}
+
+func (d *DBDeliveryStore) Save(status DeliveryStatus) (DeliveryStatus, error) {
+	stored, err := d.update(status.MessageSID, func(stored *delivery) {
+		// the link Track made outlives every status
+		status.NotificationID = stored.NotificationID
+		status.UserID = stored.UserID
+		stored.DeliveryStatus = status
+	})
+	return stored.DeliveryStatus, err
+}
+
+func (d *DBDeliveryStore) Get(messageSID string) (DeliveryStatus, error) {
+	var stored delivery
+	_, err := d.docs.Get(messageKey(messageSID), &stored)
+	if err == docstore.ErrNotFound || (err == nil && stored.Status == "") {
+		return DeliveryStatus{}, ErrStatusNotFound
+	}
+	return stored.DeliveryStatus, err
+}
+
+func (d *DBDeliveryStore) ForNotification(notificationID string) ([]DeliveryStatus, error) {
+	var stored []delivery
+	if err := d.docs.Find(map[string]interface{}{"notificationId": notificationID}, &stored); err != nil {
+		return nil, err
+	}
+	statuses := []DeliveryStatus{}
+	for _, delivery := range stored {
+		if delivery.Status != "" {
+			statuses = append(statuses, delivery.DeliveryStatus)
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,91 @@
+package twilio_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/twilio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStatusCallback(t *testing.T) {
+	status, err := twilio.ParseStatusCallback(url.Values{
+		"MessageSid":    {"SM1"},
+		"AccountSid":    {"AC1"},
+		"To":            {"+15005550006"},
+		"MessageStatus": {"undelivered"},
+		"ErrorCode":     {"30003"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "SM1", status.MessageSID)
+	assert.Equal(t, "30003", status.ErrorCode)
+	assert.True(t, status.Failed())
+
+	status, err = twilio.ParseStatusCallback(url.Values{"MessageSid": {"SM1"}, "SmsStatus": {"sent"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "sent", status.Status)
+
+	_, err = twilio.ParseStatusCallback(url.Values{"MessageStatus": {"sent"}})
+	assert.Error(t, err)
+}
+
+func TestDeliveryStoreLinksNotification(t *testing.T) {
+	store := twilio.NewMemoryDeliveryStore()
+	store.Track("SM1", "NTF_1", "userA")
+	store.Track("SM2", "NTF_2", "userA")
+
+	// two messages to the same number in flight each get their own outcome
+	second, _ := store.Save(twilio.DeliveryStatus{MessageSID: "SM2", To: "+15005550006", Status: "failed"})
+	assert.Equal(t, "NTF_2", second.NotificationID)
+	first, _ := store.Save(twilio.DeliveryStatus{MessageSID: "SM1", To: "+15005550006", Status: "sent"})
+	assert.Equal(t, "NTF_1", first.NotificationID)
+	// later callbacks for the same message keep the link
+	first, _ = store.Save(twilio.DeliveryStatus{MessageSID: "SM1", To: "+15005550006", Status: "delivered"})
+	assert.Equal(t, "NTF_1", first.NotificationID)
+	// a callback that beats Track is linked once it's tracked
+	early, _ := store.Save(twilio.DeliveryStatus{MessageSID: "SM3", To: "+15005550007", Status: "queued"})
+	assert.Equal(t, "", early.NotificationID)
+	store.Track("SM3", "NTF_1", "userB")
+	unknown, _ := store.Save(twilio.DeliveryStatus{MessageSID: "SM4", To: "+15005550008", Status: "sent"})
+	assert.Equal(t, "", unknown.NotificationID)
+
+	statuses, _ := store.ForNotification("NTF_1")
+	assert.Len(t, statuses, 2)
+	got, err := store.Get("SM1")
+	assert.NoError(t, err)
+	assert.Equal(t, "delivered", got.Status)
+	_, err = store.Get("SM9")
+	assert.Equal(t, twilio.ErrStatusNotFound, err)
+}
+
+func TestDeliveryStoreSenders(t *testing.T) {
+	store := twilio.NewMemoryDeliveryStore()
+	assert.NoError(t, store.Sent("NTF_1", "userA"))
+	sentBy, err := store.SentBy("NTF_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "userA", sentBy)
+	_, err = store.SentBy("NTF_2")
+	assert.Equal(t, twilio.ErrNotificationNotFound, err)
+}
+
+func TestDeliveryStoreRetention(t *testing.T) {
+	docs := docstore.NewMemory().Collection("sms_deliveries")
+	kept := twilio.NewDBDeliveryStore(docs, time.Hour)
+	kept.Track("SM1", "NTF_1", "userA")
+	kept.Sent("NTF_1", "userA")
+	// another instance sees the link
+	status, _ := twilio.NewDBDeliveryStore(docs, time.Hour).Save(twilio.DeliveryStatus{MessageSID: "SM1", Status: "sent"})
+	assert.Equal(t, "NTF_1", status.NotificationID)
+
+	expired := twilio.NewDBDeliveryStore(docstore.NewMemory().Collection("sms_deliveries"), 0)
+	expired.Track("SM1", "NTF_1", "userA")
+	expired.Sent("NTF_1", "userA")
+	status, _ = expired.Save(twilio.DeliveryStatus{MessageSID: "SM1", Status: "sent"})
+	assert.Equal(t, "", status.NotificationID)
+	_, err := expired.Get("SM1")
+	assert.Equal(t, twilio.ErrStatusNotFound, err)
+	_, err = expired.SentBy("NTF_1")
+	assert.Equal(t, twilio.ErrNotificationNotFound, err)
+}
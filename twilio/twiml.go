@@ -0,0 +1,22 @@
+package twilio
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// MessagingResponse renders the TwiML twilio expects back from an incoming
+// message webhook, an empty message replies with nothing
+// https://www.twilio.com/docs/messaging/twiml
+func MessagingResponse(message string) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<Response>")
+	if message != "" {
+		b.WriteString("<Message>")
+		xml.EscapeText(&b, []byte(message))
+		b.WriteString("</Message>")
+	}
+	b.WriteString("</Response>")
+	return b.Bytes()
+}
@@ -0,0 +1,19 @@
+package twilio_test
+
+import (
+	"testing"
+
+	"github.com/kickback-app/api/server/twilio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessagingResponse(t *testing.T) {
+	assert.Equal(t,
+		`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<Response><Message>Game night &amp; pizza &lt;3</Message></Response>`,
+		string(twilio.MessagingResponse("Game night & pizza <3")),
+	)
+	assert.Equal(t,
+		`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<Response></Response>`,
+		string(twilio.MessagingResponse("")),
+	)
+}
@@ -32,7 +32,7 @@ func (s S) GetUser(c *gin.Context) {
 func (s S) CreateUser(c *gin.Context) {
 	var user models.User
 	if err := json.NewDecoder(c.Request.Body).Decode(&user); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	userID, err := s.UserService.CreateUser(c, &user)
@@ -48,7 +48,7 @@ func (s S) UpdateUser(c *gin.Context) {
 	userID := utils.CurrentUser(c).ID
 	var updates models.UserUpdates
 	if err := json.NewDecoder(c.Request.Body).Decode(&updates); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	err := s.UserService.UpdateUser(c, userID, &updates)
@@ -60,6 +60,38 @@ func (s S) UpdateUser(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
 }
 
+// PatchUser applies a merge patch to the current user's profile, see
+// applyMergePatch
+func (s S) PatchUser(c *gin.Context) {
+	userID := utils.CurrentUser(c).ID
+	release, err := s.guardWrite(c, "user", userID, func() (interface{}, error) {
+		return s.UserService.GetUserByID(c, userID)
+	})
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	defer release()
+	current, err := s.UserService.GetUserByID(c, userID)
+	if err != nil {
+		handlers.EncodeError(c, handlers.NotFound("user", err))
+		return
+	}
+	var patched models.User
+	var updates models.UserUpdates
+	if err := applyMergePatch(c, userPatchable, current, &patched, &updates); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	err = s.UserService.UpdateUser(c, userID, &updates)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	logger.Info(c, "successfully patched user %s", userID)
+	handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+}
+
 func (s S) DeleteUser(c *gin.Context) {
 	userID := utils.CurrentUser(c).ID
 	err := s.UserService.DeleteUser(c, userID)
@@ -96,7 +128,7 @@ func (s S) GetSponsoredUsers(c *gin.Context) {
 func (s S) SearchUsers(c *gin.Context) {
 	var usersToFind models.UserSearch
 	if err := json.NewDecoder(c.Request.Body).Decode(&usersToFind); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	searchOutput, err := s.UserService.FindUsers(c, &usersToFind)
@@ -107,18 +139,22 @@ func (s S) SearchUsers(c *gin.Context) {
 	handlers.EncodeSuccess(c, http.StatusOK, gin.H{"users": searchOutput})
 }
 
+// inviteUserRequest creates an unverified account for someone who isn't on
+// kickback yet and texts them an invite
+type inviteUserRequest struct {
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	PhoneNumber  string `json:"phone_number"`
+	Email        string `json:"email"`
+	SourceName   string `json:"source_name"`
+	Description  string `json:"description"`
+	KickbackName string `json:"kickback_name"`
+}
+
 func (s S) InviteUser(c *gin.Context) {
-	var invite struct {
-		FirstName    string `json:"first_name"`
-		LastName     string `json:"last_name"`
-		PhoneNumber  string `json:"phone_number"`
-		Email        string `json:"email"`
-		SourceName   string `json:"source_name"`
-		Description  string `json:"description"`
-		KickbackName string `json:"kickback_name"`
-	}
+	var invite inviteUserRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&invite); err != nil {
-		handlers.EncodeError(c, handlers.MalformedBodyError{})
+		handlers.EncodeError(c, handlers.MalformedBody(err))
 		return
 	}
 	newUser := models.User{
@@ -153,7 +189,7 @@ func (s S) InviteUser(c *gin.Context) {
 	logger.Info(c, "created new user with id %s", ID)
 	// send notification
 	msg := fmt.Sprintf("%s invited you to %s\n\nDescription: %s\nView in app: <link>\nView on web: <link>", invite.SourceName, invite.KickbackName, invite.Description)
-	err = s.NotificationService.SendSMS(c, msg, invite.PhoneNumber)
+	_, err = s.sendSMS(c, msg, invite.PhoneNumber)
 	sentSMS := true
 	if err != nil {
 		logger.Error(c, "unable to send invite: %v", err)
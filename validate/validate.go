@@ -0,0 +1,84 @@
+// Package validate checks request bodies and reports every violation at
+// once, so clients can highlight all of the invalid form fields.
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kickback-app/api/server/handlers"
+)
+
+// rule names reported in FieldViolation.Rule
+const (
+	RuleRequired  = "required"
+	RuleMaxLength = "max_length"
+	RuleMin       = "min"
+	RuleMax       = "max"
+	RuleUnique    = "unique"
+	RuleOneOf     = "one_of"
+	RuleFormat    = "format"
+	RuleType      = "type"
+	// RuleUnknownField is reported for body fields the endpoint doesn't accept
+	RuleUnknownField = "unknown_field"
+)
+
+type Validator struct {
+	violations []handlers.FieldViolation
+}
+
+func (v *Validator) Add(field, rule, message string) {
+	v.violations = append(v.violations, handlers.FieldViolation{Field: field, Rule: rule, Message: message})
+}
+
+// Check records a violation when ok is false
+func (v *Validator) Check(ok bool, field, rule, message string) {
+	if !ok {
+		v.Add(field, rule, message)
+	}
+}
+
+// Required fails blank strings too
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, RuleRequired, "is required")
+}
+
+func (v *Validator) MaxLength(field, value string, max int) {
+	v.Check(utf8.RuneCountInString(value) <= max, field, RuleMaxLength, fmt.Sprintf("must be at most %d characters", max))
+}
+
+func (v *Validator) Min(field string, value, min float64) {
+	v.Check(value >= min, field, RuleMin, fmt.Sprintf("must be at least %v", min))
+}
+
+func (v *Validator) Max(field string, value, max float64) {
+	v.Check(value <= max, field, RuleMax, fmt.Sprintf("must be at most %v", max))
+}
+
+// OneOf requires value to be one of allowed
+func (v *Validator) OneOf(field, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Add(field, RuleOneOf, fmt.Sprintf("must be one of %s", strings.Join(allowed, ", ")))
+}
+
+// Unique flags every repeated value after its first occurrence
+func (v *Validator) Unique(field string, values []string) {
+	seen := map[string]bool{}
+	for i, value := range values {
+		v.Check(!seen[value], fmt.Sprintf("%s[%d]", field, i), RuleUnique, fmt.Sprintf("'%s' is listed more than once", value))
+		seen[value] = true
+	}
+}
+
+// Err returns a handlers.ValidationError with every violation, or nil
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return handlers.ValidationError{Violations: v.violations}
+}
@@ -0,0 +1,30 @@
+package validate_test
+
+import (
+	"testing"
+
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	var v validate.Validator
+	assert.NoError(t, v.Err())
+
+	v.Required("name", "  ")
+	v.Required("description", "fine")
+	v.MaxLength("title", "kickback", 4)
+	v.Min("amount", -1, 0)
+	v.Unique("assignees", []string{"a", "b", "a"})
+
+	err := v.Err()
+	if assert.IsType(t, handlers.ValidationError{}, err) {
+		assert.Equal(t, []handlers.FieldViolation{
+			{Field: "name", Rule: "required", Message: "is required"},
+			{Field: "title", Rule: "max_length", Message: "must be at most 4 characters"},
+			{Field: "amount", Rule: "min", Message: "must be at least 0"},
+			{Field: "assignees[2]", Rule: "unique", Message: "'a' is listed more than once"},
+		}, err.(handlers.ValidationError).Violations)
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/kickback-app/api/utils"
+)
+
+const (
+	maxNameLength        = 200
+	maxDescriptionLength = 5000
+)
+
+func validateEvent(e models.Event) error {
+	var v validate.Validator
+	v.Required("name", e.Name)
+	v.MaxLength("name", e.Name, maxNameLength)
+	v.MaxLength("description", e.Description, maxDescriptionLength)
+	return v.Err()
+}
+
+func validateEventUpdates(u models.EventUpdates) error {
+	var v validate.Validator
+	if u.Name != nil {
+		v.Required("name", *u.Name)
+		v.MaxLength("name", *u.Name, maxNameLength)
+	}
+	if u.Description != nil {
+		v.MaxLength("description", *u.Description, maxDescriptionLength)
+	}
+	return v.Err()
+}
+
+func validateTask(t models.Task) error {
+	var v validate.Validator
+	v.Required("name", t.Name)
+	v.MaxLength("name", t.Name, maxNameLength)
+	v.Min("due_by", float64(t.DueBy), 0)
+	validateAssigneeIDs(&v, t.Assignees)
+	return v.Err()
+}
+
+func validateTaskUpdates(u models.TaskUpdates) error {
+	var v validate.Validator
+	if u.Name != nil {
+		v.Required("name", *u.Name)
+		v.MaxLength("name", *u.Name, maxNameLength)
+	}
+	if u.Assignees != nil {
+		validateAssigneeIDs(&v, *u.Assignees)
+	}
+	return v.Err()
+}
+
+// validateExpense checks a new or patched expense, strict also requires
+// assignees with positive amounts (config expenses.strict_validation)
+func validateExpense(e models.Expense, strict bool) error {
+	var v validate.Validator
+	v.Required("name", e.Name)
+	v.MaxLength("name", e.Name, maxNameLength)
+	validateExpenseAssignees(&v, e.Assignees, strict)
+	return v.Err()
+}
+
+func validateExpenseUpdates(u models.ExpenseUpdates, strict bool) error {
+	var v validate.Validator
+	if u.Name != nil {
+		v.Required("name", *u.Name)
+		v.MaxLength("name", *u.Name, maxNameLength)
+	}
+	if u.Assignees != nil {
+		validateExpenseAssignees(&v, *u.Assignees, strict)
+	}
+	return v.Err()
+}
+
+func validateAssigneeIDs(v *validate.Validator, ids []string) {
+	for i, id := range ids {
+		v.Required(fmt.Sprintf("assignees[%d]", i), id)
+	}
+	v.Unique("assignees", ids)
+}
+
+func validateExpenseAssignees(v *validate.Validator, assignees []models.ExpenseAssignee, strict bool) {
+	if strict {
+		v.Check(len(assignees) > 0, "assignees", validate.RuleRequired, "must have at least one assignee")
+	}
+	ids := make([]string, 0, len(assignees))
+	for i, a := range assignees {
+		v.Required(fmt.Sprintf("assignees[%d].userId", i), a.UserID)
+		if strict {
+			v.Check(a.Amount > 0, fmt.Sprintf("assignees[%d].amount", i), validate.RuleMin, "must be greater than 0")
+		}
+		ids = append(ids, a.UserID)
+	}
+	v.Unique("assignees", ids)
+}
+
+func validateEventSettings(settings models.EventSettings, channels []models.Channel) error {
+	var v validate.Validator
+	channelIDs := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channelIDs = append(channelIDs, channel.ID)
+	}
+	lists := []struct {
+		field string
+		ids   []string
+	}{
+		{"chat.open_channels", settings.Chat.OpenChannels},
+		{"chat.muted_channels", settings.Chat.MutedChannels},
+	}
+	for _, list := range lists {
+		for i, id := range list.ids {
+			v.Check(utils.ContainsString(channelIDs, id), fmt.Sprintf("%s[%d]", list.field, i), validate.RuleOneOf, fmt.Sprintf("'%s' is not a channel of this event", id))
+		}
+		v.Unique(list.field, list.ids)
+	}
+	return v.Err()
+}
+
+func validateChannel(ch models.Channel) error {
+	var v validate.Validator
+	v.Required("name", ch.Name)
+	v.MaxLength("name", ch.Name, maxNameLength)
+	return v.Err()
+}
@@ -0,0 +1,82 @@
+package waitlist
+
+import (
+	"errors"
+
+	"github.com/kickback-app/api/server/docstore"
+)
+
+var ErrNotFound = errors.New("waitlist not found")
+
+// Store persists the lists of kickbacks with a capacity keyed by event ID,
+// kickbacks without one have no list. Save only applies while the stored list
+// is still at the Version it was read at, and a new list only while there is
+// none, otherwise it returns docstore.ErrConflict. Remove takes a list off the
+// same way, Delete whatever its version.
+type Store interface {
+	Get(eventID string) (List, error)
+	Save(list List) error
+	Remove(list List) error
+	Delete(eventID string) error
+}
+
+// DBStore keeps the lists in the database, shared by every instance
+type DBStore struct {
+	docs docstore.Collection
+}
+
+func NewDBStore(docs docstore.Collection) *DBStore {
+	return &DBStore{docs: docs}
+}
+
+// NewMemoryStore keeps the lists in memory, for tests and single instance
+// deployments
+func NewMemoryStore() *DBStore {
+	return NewDBStore(docstore.NewMemory().Collection("waitlists"))
+}
+
+func (d *DBStore) Get(eventID string) (List, error) {
+	var list List
+	version, err := d.docs.Get(eventID, &list)
+	if err == docstore.ErrNotFound {
+		return List{}, ErrNotFound
+	}
+	if err != nil {
+		return List{}, err
+	}
+	list.Version = version
+	if list.Spots == nil {
+		list.Spots = map[string]int{}
+	}
+	if list.Waiting == nil {
+		list.Waiting = []Entry{}
+	}
+	return list, nil
+}
+
+func (d *DBStore) Save(list List) error {
+	_, err := d.docs.Put(list.EventID, list.Version, list)
+	return err
+}
+
+func (d *DBStore) Remove(list List) error {
+	if list.Version == 0 {
+		// was never saved
+		return nil
+	}
+	return d.docs.Delete(list.EventID, list.Version)
+}
+
+func (d *DBStore) Delete(eventID string) error {
+	return docstore.Retry(func() error {
+		var list List
+		version, err := d.docs.Get(eventID, &list)
+		if err == docstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.docs.Delete(eventID, version)
+	})
+}
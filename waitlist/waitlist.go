@@ -0,0 +1,169 @@
+package waitlist
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotWaiting = errors.New("member is not on the waitlist")
+
+// List is the capacity of a kickback, the members holding a spot with the size
+// of their party and the members waiting for one once it's full, in the order
+// they get promoted. Spots are taken on the list rather than counted from the
+// members going, so two members answering at once can't both take the last
+// one: only one of their saves applies.
+type List struct {
+	EventID  string         `json:"eventId"`
+	Capacity int            `json:"capacity"`
+	Spots    map[string]int `json:"spots"`
+	Waiting  []Entry        `json:"waiting"`
+	// Version is what the list was read at, see Store
+	Version int64 `json:"-"`
+}
+
+// Entry is a member waiting for a spot, Position starts at 1
+type Entry struct {
+	UserID   string    `json:"userId"`
+	Position int       `json:"position"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+func New(eventID string, capacity int) List {
+	return List{EventID: eventID, Capacity: capacity, Spots: map[string]int{}, Waiting: []Entry{}}
+}
+
+// Fits reports whether a party of size people still fits when going people
+// are going already
+func (l List) Fits(going, size int) bool {
+	return going+size <= l.Capacity
+}
+
+// Going is how many people hold a spot, guests included
+func (l List) Going() int {
+	going := 0
+	for _, size := range l.Spots {
+		going += size
+	}
+	return going
+}
+
+// Holds reports whether userID has a spot
+func (l List) Holds(userID string) bool {
+	_, ok := l.Spots[userID]
+	return ok
+}
+
+// Claim gives userID a spot for a party of size if it fits, members holding a
+// spot already can change the size of their party
+func (l *List) Claim(userID string, size int) bool {
+	if !l.Fits(l.Going()-l.Spots[userID], size) {
+		return false
+	}
+	l.Grant(userID, size)
+	return true
+}
+
+// Grant gives userID a spot for a party of size, even when it doesn't fit
+func (l *List) Grant(userID string, size int) {
+	if l.Spots == nil {
+		l.Spots = map[string]int{}
+	}
+	l.Spots[userID] = size
+}
+
+// Release frees the spot of userID
+func (l *List) Release(userID string) {
+	delete(l.Spots, userID)
+}
+
+// Promote takes members off the head of the waitlist and gives them a spot as
+// long as their party fits, size is the size of the party of a member
+func (l *List) Promote(size func(userID string) int) []Entry {
+	promoted := []Entry{}
+	for len(l.Waiting) > 0 && l.Claim(l.Waiting[0].UserID, size(l.Waiting[0].UserID)) {
+		e, _ := l.Next()
+		promoted = append(promoted, e)
+	}
+	return promoted
+}
+
+// Return puts members that were promoted back at the head of the waitlist, in
+// their order, and frees their spots
+func (l *List) Return(entries []Entry) {
+	waiting := []Entry{}
+	for _, e := range entries {
+		l.Release(e.UserID)
+		l.Leave(e.UserID)
+		waiting = append(waiting, e)
+	}
+	l.Waiting = append(waiting, l.Waiting...)
+	l.renumber()
+}
+
+// Position is the place of userID on the waitlist, 0 if they aren't on it
+func (l List) Position(userID string) int {
+	for i, e := range l.Waiting {
+		if e.UserID == userID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Join puts userID at the end of the waitlist and returns their position,
+// members already waiting keep their place
+func (l *List) Join(userID string, at time.Time) int {
+	if p := l.Position(userID); p != 0 {
+		return p
+	}
+	l.Waiting = append(l.Waiting, Entry{UserID: userID, JoinedAt: at})
+	l.renumber()
+	return len(l.Waiting)
+}
+
+// Leave takes userID off the waitlist, it reports whether they were on it
+func (l *List) Leave(userID string) bool {
+	p := l.Position(userID)
+	if p == 0 {
+		return false
+	}
+	l.Waiting = append(l.Waiting[:p-1], l.Waiting[p:]...)
+	l.renumber()
+	return true
+}
+
+// Move puts userID at position, positions past the end move them last
+func (l *List) Move(userID string, position int) error {
+	p := l.Position(userID)
+	if p == 0 {
+		return ErrNotWaiting
+	}
+	if position < 1 {
+		position = 1
+	}
+	if position > len(l.Waiting) {
+		position = len(l.Waiting)
+	}
+	e := l.Waiting[p-1]
+	l.Waiting = append(l.Waiting[:p-1], l.Waiting[p:]...)
+	l.Waiting = append(l.Waiting[:position-1], append([]Entry{e}, l.Waiting[position-1:]...)...)
+	l.renumber()
+	return nil
+}
+
+// Next takes the member at the head of the waitlist off it
+func (l *List) Next() (Entry, bool) {
+	if len(l.Waiting) == 0 {
+		return Entry{}, false
+	}
+	e := l.Waiting[0]
+	l.Waiting = l.Waiting[1:]
+	l.renumber()
+	return e, true
+}
+
+func (l *List) renumber() {
+	for i := range l.Waiting {
+		l.Waiting[i].Position = i + 1
+	}
+}
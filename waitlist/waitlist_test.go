@@ -0,0 +1,106 @@
+package waitlist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/waitlist"
+	"github.com/stretchr/testify/assert"
+)
+
+func userIDs(list waitlist.List) []string {
+	out := []string{}
+	for _, e := range list.Waiting {
+		out = append(out, e.UserID)
+	}
+	return out
+}
+
+func TestList(t *testing.T) {
+	at := time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC)
+	list := waitlist.New("EVT_1", 2)
+	assert.True(t, list.Fits(1, 1))
+	assert.False(t, list.Fits(2, 1))
+	assert.False(t, list.Fits(0, 3), "guests take spots too")
+
+	assert.Equal(t, 1, list.Join("USR_1", at))
+	assert.Equal(t, 2, list.Join("USR_2", at))
+	assert.Equal(t, 3, list.Join("USR_3", at))
+	assert.Equal(t, 1, list.Join("USR_1", at.Add(time.Hour)), "members already waiting keep their place")
+
+	assert.NoError(t, list.Move("USR_3", 1))
+	assert.Equal(t, []string{"USR_3", "USR_1", "USR_2"}, userIDs(list))
+	assert.NoError(t, list.Move("USR_3", 10))
+	assert.Equal(t, []string{"USR_1", "USR_2", "USR_3"}, userIDs(list))
+	assert.ErrorIs(t, list.Move("USR_4", 1), waitlist.ErrNotWaiting)
+
+	assert.True(t, list.Leave("USR_2"))
+	assert.False(t, list.Leave("USR_2"))
+	e, ok := list.Next()
+	assert.True(t, ok)
+	assert.Equal(t, "USR_1", e.UserID)
+	assert.Equal(t, []waitlist.Entry{{UserID: "USR_3", Position: 1, JoinedAt: at}}, list.Waiting)
+}
+
+func TestSpots(t *testing.T) {
+	at := time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC)
+	list := waitlist.New("EVT_1", 4)
+	assert.True(t, list.Claim("USR_1", 2))
+	assert.True(t, list.Claim("USR_1", 3), "members holding a spot can bring more guests while it fits")
+	assert.False(t, list.Claim("USR_2", 2))
+	assert.True(t, list.Claim("USR_2", 1))
+	assert.Equal(t, 4, list.Going())
+	assert.True(t, list.Holds("USR_2"))
+
+	list.Join("USR_3", at)
+	list.Join("USR_4", at)
+	list.Join("USR_5", at)
+	list.Release("USR_1")
+	sizes := map[string]int{"USR_3": 1, "USR_4": 2, "USR_5": 1}
+	promoted := list.Promote(func(userID string) int { return sizes[userID] })
+	assert.Equal(t, []string{"USR_3", "USR_4"}, []string{promoted[0].UserID, promoted[1].UserID}, "promotes while the head of the list fits")
+	assert.Equal(t, []string{"USR_5"}, userIDs(list))
+	assert.Equal(t, 4, list.Going())
+
+	list.Return(promoted)
+	assert.Equal(t, []string{"USR_3", "USR_4", "USR_5"}, userIDs(list))
+	assert.Equal(t, 1, list.Going())
+	assert.False(t, list.Holds("USR_3"))
+}
+
+func TestStore(t *testing.T) {
+	store := waitlist.NewMemoryStore()
+	_, err := store.Get("EVT_1")
+	assert.ErrorIs(t, err, waitlist.ErrNotFound)
+
+	list := waitlist.New("EVT_1", 10)
+	list.Join("USR_1", time.Now())
+	assert.NoError(t, store.Save(list))
+	list.Waiting[0].UserID = "USR_2"
+	saved, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "USR_1", saved.Waiting[0].UserID)
+
+	// two members claim the last spot at once, only one of them gets it
+	first, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	second, err := store.Get("EVT_1")
+	assert.NoError(t, err)
+	assert.True(t, first.Claim("USR_2", 10))
+	assert.True(t, second.Claim("USR_3", 10))
+	assert.NoError(t, store.Save(first))
+	assert.ErrorIs(t, store.Save(second), docstore.ErrConflict)
+	assert.ErrorIs(t, store.Save(waitlist.New("EVT_1", 10)), docstore.ErrConflict, "a new list can't replace a stored one")
+
+	assert.ErrorIs(t, store.Remove(second), docstore.ErrConflict)
+	saved, err = store.Get("EVT_1")
+	assert.NoError(t, err)
+	assert.NoError(t, store.Remove(saved))
+	_, err = store.Get("EVT_1")
+	assert.ErrorIs(t, err, waitlist.ErrNotFound)
+
+	assert.NoError(t, store.Save(waitlist.New("EVT_1", 10)))
+	assert.NoError(t, store.Delete("EVT_1"))
+	assert.NoError(t, store.Delete("EVT_1"))
+}
@@ -0,0 +1,541 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/logger"
+	"github.com/kickback-app/api/pkg/models"
+	"github.com/kickback-app/api/server/docstore"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/handlers"
+	"github.com/kickback-app/api/server/metrics"
+	"github.com/kickback-app/api/server/recurrence"
+	"github.com/kickback-app/api/server/validate"
+	"github.com/kickback-app/api/server/waitlist"
+)
+
+// capacityRequest limits how many members can be going, null lifts the limit
+type capacityRequest struct {
+	Capacity *int `json:"capacity"`
+}
+
+// waitlistMoveRequest puts a waiting member at a new position, starting at 1
+type waitlistMoveRequest struct {
+	Position int `json:"position"`
+}
+
+// waitlistResponse is the capacity of an event, how many people are going
+// with their guests and who is waiting in the order they get promoted
+type waitlistResponse struct {
+	Capacity int              `json:"capacity"`
+	Going    int              `json:"going"`
+	Waiting  []waitlist.Entry `json:"waiting"`
+}
+
+func newWaitlistResponse(list waitlist.List) waitlistResponse {
+	return waitlistResponse{Capacity: list.Capacity, Going: list.Going(), Waiting: list.Waiting}
+}
+
+func waitlistNotFound(err error) error {
+	if err == waitlist.ErrNotFound {
+		return handlers.NotFoundError{Resource: "waitlist", Err: err}
+	}
+	return err
+}
+
+// waitlistConflict asks the client to retry a change to who is going that
+// kept losing to other changes of the same waitlist
+func waitlistConflict(err error) error {
+	if err == docstore.ErrConflict {
+		return handlers.ConflictError{Resource: "waitlist"}
+	}
+	return err
+}
+
+// newWaitlist limits the event to capacity, the members going already hold
+// their spots
+func newWaitlist(event models.Event, guestList guests.List, capacity int) waitlist.List {
+	list := waitlist.New(event.ID, capacity)
+	for _, m := range event.Members {
+		if m.Status == models.MemberStatusGoing {
+			list.Grant(m.UserID, guestList.Party(m.UserID).Size())
+		}
+	}
+	return list
+}
+
+// partySizes is the size of the party of each member, for List.Promote
+func partySizes(guestList guests.List) func(string) int {
+	return func(userID string) int {
+		return guestList.Party(userID).Size()
+	}
+}
+
+// eventAndGuests reads the event and its guest list, which changes to its
+// waitlist need
+func (s S) eventAndGuests(c *gin.Context, eventID string) (models.Event, guests.List, error) {
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		return event, guests.List{}, handlers.NotFound("event", err)
+	}
+	guestList, err := s.Guests.Get(eventID)
+	return event, guestList, err
+}
+
+// spot is where a member stood on the waitlist before changing their answer
+type spot struct {
+	size    int
+	held    bool
+	waiting *waitlist.Entry
+}
+
+func spotOf(list waitlist.List, userID string) spot {
+	var sp spot
+	sp.size, sp.held = list.Spots[userID]
+	if p := list.Position(userID); p != 0 {
+		entry := list.Waiting[p-1]
+		sp.waiting = &entry
+	}
+	return sp
+}
+
+// restoreSpot puts userID back where they stood on the waitlist when their
+// answer couldn't be set after all
+func (s S) restoreSpot(c *gin.Context, eventID, userID string, sp spot) {
+	err := docstore.Retry(func() error {
+		list, err := s.Waitlists.Get(eventID)
+		if err != nil {
+			return err
+		}
+		list.Release(userID)
+		list.Leave(userID)
+		if sp.held {
+			list.Grant(userID, sp.size)
+		}
+		if sp.waiting != nil {
+			list.Join(userID, sp.waiting.JoinedAt)
+			_ = list.Move(userID, sp.waiting.Position)
+		}
+		return s.Waitlists.Save(list)
+	})
+	if err != nil {
+		logger.Error(c, "unable to restore the spot of %s on the waitlist of event %s: %v", userID, eventID, err)
+	}
+}
+
+// setRSVP sets the status of the member and the guests they bring, a nil
+// party keeps the guests they brought before. Wanting to go to a full event
+// puts them on its waitlist instead, the entry is nil when their status was
+// set. Spots are taken on the waitlist before the status is set, so the
+// capacity holds however many instances take answers at once.
+func (s S) setRSVP(c *gin.Context, eventID, userID string, status models.MemberStatus, party *guests.Party) (*waitlist.Entry, error) {
+	if err := s.checkRSVPOpen(eventID, status); err != nil {
+		return nil, err
+	}
+	guestList, err := s.Guests.Get(eventID)
+	if err != nil {
+		return nil, err
+	}
+	p := guestList.Party(userID)
+	switch {
+	case status == models.MemberStatusNotGoing || status == models.MemberStatusInvited:
+		p = guests.Party{}
+	case party != nil:
+		p = *party
+		if err := checkParty(guestList.Policy, p); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		before spot
+		entry  *waitlist.Entry
+	)
+	err = docstore.Retry(func() error {
+		list, err := s.Waitlists.Get(eventID)
+		if err != nil {
+			return err
+		}
+		before, entry = spotOf(list, userID), nil
+		switch {
+		case status != models.MemberStatusGoing:
+			list.Release(userID)
+			list.Leave(userID)
+		case list.Claim(userID, p.Size()):
+			list.Leave(userID)
+		case before.held:
+			// they keep their spot, just not the extra guests
+			return handlers.EventFullError{Capacity: list.Capacity}
+		default:
+			position := list.Join(userID, time.Now())
+			waiting := list.Waiting[position-1]
+			entry = &waiting
+		}
+		return s.Waitlists.Save(list)
+	})
+	if err == waitlist.ErrNotFound {
+		if err := s.EventService.RSVP(c, eventID, userID, status); err != nil {
+			return nil, err
+		}
+		return nil, s.saveParty(eventID, userID, p)
+	}
+	if err != nil {
+		return nil, waitlistConflict(err)
+	}
+	if entry != nil {
+		return entry, s.saveParty(eventID, userID, p)
+	}
+	if err := s.EventService.RSVP(c, eventID, userID, status); err != nil {
+		s.restoreSpot(c, eventID, userID, before)
+		return nil, err
+	}
+	if err := s.saveParty(eventID, userID, p); err != nil {
+		return nil, err
+	}
+	guestList.SetParty(userID, p)
+	if status != models.MemberStatusGoing || p.Size() < before.size {
+		s.fillOpenSpots(c, eventID, guestList)
+	}
+	return nil, nil
+}
+
+// fillOpenSpots promotes members from the head of the waitlist as long as
+// their party fits. Failures are only logged, the next change to the event
+// retries.
+func (s S) fillOpenSpots(c *gin.Context, eventID string, guestList guests.List) {
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err == nil {
+		_, err = s.changeWaitlist(c, event, guestList, nil)
+	}
+	if err != nil {
+		logger.Error(c, "unable to fill the open spots of event %s: %v", eventID, err)
+	}
+}
+
+// changeWaitlist applies change, if any, to the waitlist of the event and
+// promotes the members that fit afterwards. change runs again on a fresh copy
+// when another write saved the list first, so it must not have side effects:
+// the promoted members are only set as going once the list is saved.
+func (s S) changeWaitlist(c *gin.Context, event models.Event, guestList guests.List, change func(*waitlist.List) error) (waitlist.List, error) {
+	var (
+		list     waitlist.List
+		promoted []waitlist.Entry
+	)
+	err := docstore.Retry(func() error {
+		var err error
+		list, err = s.Waitlists.Get(event.ID)
+		if err != nil {
+			return err
+		}
+		if change != nil {
+			if err := change(&list); err != nil {
+				return err
+			}
+		}
+		promoted = list.Promote(partySizes(guestList))
+		if change == nil && len(promoted) == 0 {
+			return nil
+		}
+		return s.Waitlists.Save(list)
+	})
+	if err != nil {
+		return list, waitlistConflict(err)
+	}
+	s.promoteAll(c, event, promoted)
+	return list, nil
+}
+
+// promoteAll sets the members promoted on the waitlist of the event as going,
+// the ones that can't be keep their place at the head of the list
+func (s S) promoteAll(c *gin.Context, event models.Event, promoted []waitlist.Entry) {
+	for i, entry := range promoted {
+		if err := s.promote(c, event, entry.UserID); err != nil {
+			logger.Error(c, "unable to promote %s from the waitlist of event %s: %v", entry.UserID, event.ID, err)
+			s.returnToWaitlist(c, event.ID, promoted[i:])
+			return
+		}
+	}
+}
+
+// returnToWaitlist puts promoted members that couldn't be set as going back at
+// the head of the waitlist
+func (s S) returnToWaitlist(c *gin.Context, eventID string, entries []waitlist.Entry) {
+	err := docstore.Retry(func() error {
+		list, err := s.Waitlists.Get(eventID)
+		if err != nil {
+			return err
+		}
+		list.Return(entries)
+		return s.Waitlists.Save(list)
+	})
+	if err != nil {
+		logger.Error(c, "unable to put %d members back on the waitlist of event %s: %v", len(entries), eventID, err)
+	}
+}
+
+// promote sets a waiting member as going and lets them know by push and SMS
+func (s S) promote(c *gin.Context, event models.Event, userID string) error {
+	if err := s.EventService.RSVP(c, event.ID, userID, models.MemberStatusGoing); err != nil {
+		return err
+	}
+	metrics.RSVP(string(models.MemberStatusGoing), "waitlist")
+	msg := fmt.Sprintf("A spot opened up at %v, you're now going!", event.Name)
+	s.sendInBackground(c, models.Notification{
+		Type:     models.EventMemberAttending,
+		Channels: []string{"push", "sms"},
+		To:       []string{userID},
+		Title:    "You're off the waitlist",
+		Body:     msg,
+		Data: map[string]string{
+			"eventId": event.ID,
+		},
+		SMSmessage: msg,
+	})
+	logger.Info(c, "promoted %s from the waitlist of event %s", userID, event.ID)
+	return nil
+}
+
+// checkOccurrenceCapacity rejects going to an occurrence of a recurring event
+// that is already full, occurrences have no waitlist of their own
+func (s S) checkOccurrenceCapacity(event models.Event, series recurrence.Series, occurrenceID, userID string) error {
+	list, err := s.Waitlists.Get(event.ID)
+	if err == waitlist.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	guestList, err := s.Guests.Get(event.ID)
+	if err != nil {
+		return err
+	}
+	going := 0
+	for _, m := range event.Members {
+		status := m.Status
+		if answered, ok := series.RSVPs[occurrenceID][m.UserID]; ok {
+			status = answered
+		}
+		if status != models.MemberStatusGoing {
+			continue
+		}
+		if m.UserID == userID {
+			// already has a spot
+			return nil
+		}
+		going += guestList.Party(m.UserID).Size()
+	}
+	if !list.Fits(going, guestList.Party(userID).Size()) {
+		return handlers.EventFullError{Capacity: list.Capacity}
+	}
+	return nil
+}
+
+func (s S) GetWaitlist(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	list, err := s.Waitlists.Get(eventID)
+	if err != nil {
+		handlers.EncodeError(c, waitlistNotFound(err))
+		return
+	}
+	handlers.EncodeSuccess(c, http.StatusOK, newWaitlistResponse(list))
+}
+
+// UpdateEventCapacity sets how many members can be going. Raising or lifting
+// it promotes members from the waitlist, lowering it doesn't take anyone's
+// spot.
+func (s S) UpdateEventCapacity(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	var req capacityRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	if req.Capacity != nil {
+		var v validate.Validator
+		v.Min("capacity", float64(*req.Capacity), 1)
+		if err := v.Err(); err != nil {
+			handlers.EncodeError(c, err)
+			return
+		}
+	}
+	list, err := s.setCapacity(c, eventID, req.Capacity)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	if req.Capacity == nil {
+		logger.Info(c, "lifted the capacity of event %s", eventID)
+		handlers.EncodeSuccess(c, http.StatusNoContent, nil)
+		return
+	}
+	logger.Info(c, "set the capacity of event %s to %d", eventID, *req.Capacity)
+	handlers.EncodeSuccess(c, http.StatusOK, newWaitlistResponse(list))
+}
+
+// setCapacity limits the event to capacity going members, nil lifts the limit
+// and promotes everyone waiting
+func (s S) setCapacity(c *gin.Context, eventID string, capacity *int) (waitlist.List, error) {
+	if capacity == nil {
+		return s.liftCapacity(c, eventID)
+	}
+	event, guestList, err := s.eventAndGuests(c, eventID)
+	if err != nil {
+		return waitlist.List{}, err
+	}
+	var (
+		list     waitlist.List
+		promoted []waitlist.Entry
+	)
+	err = docstore.Retry(func() error {
+		var err error
+		list, err = s.Waitlists.Get(eventID)
+		switch {
+		case err == waitlist.ErrNotFound:
+			list = newWaitlist(event, guestList, *capacity)
+		case err != nil:
+			return err
+		default:
+			list.Capacity = *capacity
+		}
+		promoted = list.Promote(partySizes(guestList))
+		return s.Waitlists.Save(list)
+	})
+	if err != nil {
+		return list, waitlistConflict(err)
+	}
+	s.promoteAll(c, event, promoted)
+	return list, nil
+}
+
+// liftCapacity removes the waitlist of the event and promotes everyone who
+// was waiting
+func (s S) liftCapacity(c *gin.Context, eventID string) (waitlist.List, error) {
+	var list waitlist.List
+	err := docstore.Retry(func() error {
+		var err error
+		list, err = s.Waitlists.Get(eventID)
+		if err != nil {
+			return err
+		}
+		return s.Waitlists.Remove(list)
+	})
+	if err == waitlist.ErrNotFound {
+		return list, nil
+	}
+	if err != nil {
+		return list, waitlistConflict(err)
+	}
+	if len(list.Waiting) == 0 {
+		return list, nil
+	}
+	event, err := s.EventService.GetEvent(c, eventID)
+	if err != nil {
+		return list, handlers.NotFound("event", err)
+	}
+	for _, entry := range list.Waiting {
+		if err := s.promote(c, event, entry.UserID); err != nil {
+			logger.Error(c, "unable to promote %s from the waitlist of event %s: %v", entry.UserID, eventID, err)
+		}
+	}
+	return list, nil
+}
+
+// MoveWaitlistMember lets hosts reorder the waitlist
+func (s S) MoveWaitlistMember(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	param = "userId"
+	userID := c.Param(param)
+	if userID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	var req waitlistMoveRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		handlers.EncodeError(c, handlers.MalformedBody(err))
+		return
+	}
+	var v validate.Validator
+	v.Min("position", float64(req.Position), 1)
+	if err := v.Err(); err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	event, guestList, err := s.eventAndGuests(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	list, err := s.changeWaitlist(c, event, guestList, func(list *waitlist.List) error {
+		if err := list.Move(userID, req.Position); err != nil {
+			return handlers.NotFoundError{Resource: "waitlist_member", Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		handlers.EncodeError(c, waitlistNotFound(err))
+		return
+	}
+	logger.Info(c, "moved %s to position %d on the waitlist of event %s", userID, req.Position, eventID)
+	handlers.EncodeSuccess(c, http.StatusOK, newWaitlistResponse(list))
+}
+
+// PromoteWaitlistMember lets hosts give a waiting member a spot, even when the
+// event is full
+func (s S) PromoteWaitlistMember(c *gin.Context) {
+	param := "eventId"
+	eventID := c.Param(param)
+	if eventID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	param = "userId"
+	userID := c.Param(param)
+	if userID == "" {
+		handlers.EncodeError(c, handlers.MissingPathParamError{Param: param})
+		return
+	}
+	event, guestList, err := s.eventAndGuests(c, eventID)
+	if err != nil {
+		handlers.EncodeError(c, err)
+		return
+	}
+	var entry waitlist.Entry
+	list, err := s.changeWaitlist(c, event, guestList, func(list *waitlist.List) error {
+		p := list.Position(userID)
+		if p == 0 {
+			return handlers.NotFoundError{Resource: "waitlist_member", Err: waitlist.ErrNotWaiting}
+		}
+		entry = list.Waiting[p-1]
+		list.Leave(userID)
+		list.Grant(userID, guestList.Party(userID).Size())
+		return nil
+	})
+	if err != nil {
+		handlers.EncodeError(c, waitlistNotFound(err))
+		return
+	}
+	if err := s.promote(c, event, userID); err != nil {
+		s.returnToWaitlist(c, eventID, []waitlist.Entry{entry})
+		handlers.EncodeError(c, err)
+		return
+	}
+	handlers.EncodeSuccess(c, http.StatusOK, newWaitlistResponse(list))
+}
@@ -0,0 +1,256 @@
+package server_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kickback-app/api/internal/services"
+	"github.com/kickback-app/api/server"
+	"github.com/kickback-app/api/server/guests"
+	"github.com/kickback-app/api/server/reminders"
+	"github.com/kickback-app/api/server/waitlist"
+	"github.com/kickback-app/api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestWaitlistHandlers(t *testing.T) {
+	mockServer := server.S{Waitlists: waitlist.NewMemoryStore()}
+	cases := []struct {
+		Name               string
+		Method             string
+		Body               string
+		Handler            func(server.S) gin.HandlerFunc
+		ExpectedStatusCode int
+		PathToResult       string
+		ExpectedResult     string
+	}{
+		{
+			Name:               "events without a capacity have no waitlist",
+			Method:             http.MethodGet,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.GetWaitlist },
+			ExpectedStatusCode: http.StatusNotFound,
+			PathToResult:       "meta.error.errorCode",
+			ExpectedResult:     `"WAITLIST_NOT_FOUND"`,
+		},
+		{
+			Name:               "capacity is checked",
+			Method:             http.MethodPut,
+			Body:               `{"capacity": 0}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventCapacity },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.0.field",
+			ExpectedResult:     `"capacity"`,
+		},
+		{
+			Name:               "lifting a capacity that was never set",
+			Method:             http.MethodPut,
+			Body:               `{"capacity": null}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.UpdateEventCapacity },
+			ExpectedStatusCode: http.StatusNoContent,
+			PathToResult:       "result",
+			ExpectedResult:     ``,
+		},
+		{
+			Name:               "position is checked",
+			Method:             http.MethodPut,
+			Body:               `{"position": 0}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.MoveWaitlistMember },
+			ExpectedStatusCode: http.StatusBadRequest,
+			PathToResult:       "meta.error.details.0.field",
+			ExpectedResult:     `"position"`,
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", "mockUserId")
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}, {Key: "userId", Value: "mockWaitingUserId"}}
+		utils.MockRequest(ctx, c.Method, c.Body)
+		c.Handler(mockServer)(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		if c.ExpectedResult != "" {
+			assert.JSONEq(t, c.ExpectedResult, gjson.Get(w.Body.String(), c.PathToResult).Raw, c.Name)
+		}
+	}
+}
+
+func TestWaitlistPromotions(t *testing.T) {
+	mockEvent := `{"_id": "mockEventId", "name": "Game night", "createdBy": "mockHostId", "members": [
+		{"userId": "mockHostId", "status": "going"},
+		{"userId": "mockUserId", "status": "going"},
+		{"userId": "mockWaitingUserId", "status": "invited"}
+	]}`
+	joined := time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC)
+	// full waitlists the cases start from, mockUserId holds the last spot
+	full := func(waiting ...string) waitlist.List {
+		list := waitlist.New("mockEventId", 2)
+		list.Grant("mockHostId", 1)
+		list.Grant("mockUserId", 1)
+		for _, userID := range waiting {
+			list.Join(userID, joined)
+		}
+		return list
+	}
+	cases := []struct {
+		Name                    string
+		UserID                  string
+		Method                  string
+		Body                    string
+		Handler                 func(server.S) gin.HandlerFunc
+		Waitlist                waitlist.List
+		EventServiceDBResponses []interface{}
+		ExpectedStatusCode      int
+		PathToResult            string
+		ExpectedResult          string
+		ExpectedSpots           map[string]int
+		ExpectedWaiting         []string
+	}{
+		{
+			Name:               "going to a full event puts the member on the waitlist",
+			UserID:             "mockWaitingUserId",
+			Method:             http.MethodPut,
+			Body:               `{"status": "going"}`,
+			Handler:            func(s server.S) gin.HandlerFunc { return s.RSVP },
+			Waitlist:           full("mockOtherUserId"),
+			ExpectedStatusCode: http.StatusAccepted,
+			PathToResult:       "result.position",
+			ExpectedResult:     `2`,
+			ExpectedSpots:      map[string]int{"mockHostId": 1, "mockUserId": 1},
+			ExpectedWaiting:    []string{"mockOtherUserId", "mockWaitingUserId"},
+		},
+		{
+			Name:                    "members holding a spot can't bring guests that don't fit",
+			UserID:                  "mockUserId",
+			Method:                  http.MethodPut,
+			Body:                    `{"status": "going", "guests": 1}`,
+			Handler:                 func(s server.S) gin.HandlerFunc { return s.RSVP },
+			Waitlist:                full(),
+			EventServiceDBResponses: []interface{}{},
+			ExpectedStatusCode:      http.StatusConflict,
+			PathToResult:            "meta.error.errorCode",
+			ExpectedResult:          `"EVENT_FULL"`,
+			ExpectedSpots:           map[string]int{"mockHostId": 1, "mockUserId": 1},
+			ExpectedWaiting:         []string{},
+		},
+		{
+			Name:    "happy path - dropping out promotes the head of the waitlist",
+			UserID:  "mockUserId",
+			Method:  http.MethodPut,
+			Body:    `{"status": "not_going"}`,
+			Handler: func(s server.S) gin.HandlerFunc { return s.RSVP },
+			// their rsvp, the event to promote to, the promotion and the event to answer with
+			Waitlist:                full("mockWaitingUserId", "mockOtherUserId"),
+			EventServiceDBResponses: []interface{}{int64(1), mockEvent, int64(1), mockEvent},
+			ExpectedStatusCode:      http.StatusOK,
+			PathToResult:            "result.mockUserId",
+			ExpectedResult:          `"not_going"`,
+			ExpectedSpots:           map[string]int{"mockHostId": 1, "mockWaitingUserId": 1},
+			ExpectedWaiting:         []string{"mockOtherUserId"},
+		},
+		{
+			Name:                    "the spot is given back when the rsvp can't be set",
+			UserID:                  "mockUserId",
+			Method:                  http.MethodPut,
+			Body:                    `{"status": "not_going"}`,
+			Handler:                 func(s server.S) gin.HandlerFunc { return s.RSVP },
+			Waitlist:                full("mockWaitingUserId"),
+			EventServiceDBResponses: []interface{}{utils.MockCaughtError{StatusCode: http.StatusInternalServerError}},
+			ExpectedStatusCode:      http.StatusInternalServerError,
+			ExpectedSpots:           map[string]int{"mockHostId": 1, "mockUserId": 1},
+			ExpectedWaiting:         []string{"mockWaitingUserId"},
+		},
+		{
+			Name:                    "happy path - hosts promote a waiting member past the capacity",
+			UserID:                  "mockHostId",
+			Method:                  http.MethodPost,
+			Handler:                 func(s server.S) gin.HandlerFunc { return s.PromoteWaitlistMember },
+			Waitlist:                full("mockOtherUserId", "mockWaitingUserId"),
+			EventServiceDBResponses: []interface{}{mockEvent, int64(1)},
+			ExpectedStatusCode:      http.StatusOK,
+			PathToResult:            "result",
+			ExpectedResult:          `{"capacity": 2, "going": 3, "waiting": [{"userId": "mockOtherUserId", "position": 1, "joined_at": "2026-10-16T20:00:00Z"}]}`,
+			ExpectedSpots:           map[string]int{"mockHostId": 1, "mockUserId": 1, "mockWaitingUserId": 1},
+			ExpectedWaiting:         []string{"mockOtherUserId"},
+		},
+		{
+			Name:                    "only waiting members can be promoted",
+			UserID:                  "mockHostId",
+			Method:                  http.MethodPost,
+			Handler:                 func(s server.S) gin.HandlerFunc { return s.PromoteWaitlistMember },
+			Waitlist:                full(),
+			EventServiceDBResponses: []interface{}{mockEvent},
+			ExpectedStatusCode:      http.StatusNotFound,
+			PathToResult:            "meta.error.errorCode",
+			ExpectedResult:          `"WAITLIST_MEMBER_NOT_FOUND"`,
+			ExpectedSpots:           map[string]int{"mockHostId": 1, "mockUserId": 1},
+			ExpectedWaiting:         []string{},
+		},
+	}
+	for i, c := range cases {
+		fmt.Printf("executing case %d: %v\n", i, c.Name)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Set("userId", c.UserID)
+		ctx.Request = &http.Request{Header: make(http.Header)}
+		ctx.Params = []gin.Param{{Key: "eventId", Value: "mockEventId"}, {Key: "userId", Value: "mockWaitingUserId"}}
+		waitlists := waitlist.NewMemoryStore()
+		if err := waitlists.Save(c.Waitlist); err != nil {
+			t.Fatal(err)
+		}
+		guestLists := guests.NewMemoryStore()
+		guestList := guests.New("mockEventId")
+		guestList.Policy.MaxPerMember = 2
+		if err := guestLists.Save(guestList); err != nil {
+			t.Fatal(err)
+		}
+		mockServer := server.S{
+			EventService: services.EventService{
+				DBClient: utils.MockDBClient{
+					CallCount: new(int),
+					Responses: c.EventServiceDBResponses,
+				},
+			},
+			UserService: services.UserService{
+				DBClient: utils.MockDBClient{
+					CallCount:       new(int),
+					DefaultResponse: `{"_id": "mockUserId", "first_name": "Mock"}`,
+				},
+				Cache: &utils.MockCache{
+					Callcount: new(int),
+					Items:     map[string]interface{}{},
+				},
+			},
+			NotificationService: services.NotificationService{
+				DBClient: utils.MockDBClient{
+					CallCount:       new(int),
+					DefaultResponse: "NTF_Mock",
+				},
+			},
+			Waitlists: waitlists,
+			Guests:    guestLists,
+			Deadlines: reminders.NewMemoryStore(),
+		}
+		utils.MockRequest(ctx, c.Method, c.Body)
+		c.Handler(mockServer)(ctx)
+		assert.EqualValues(t, c.ExpectedStatusCode, w.Code, c.Name)
+		if c.ExpectedResult != "" {
+			assert.JSONEq(t, c.ExpectedResult, gjson.Get(w.Body.String(), c.PathToResult).Raw, c.Name)
+		}
+		list, err := waitlists.Get("mockEventId")
+		if assert.NoError(t, err, c.Name) {
+			assert.Equal(t, c.ExpectedSpots, list.Spots, c.Name)
+			waiting := []string{}
+			for _, e := range list.Waiting {
+				waiting = append(waiting, e.UserID)
+			}
+			assert.Equal(t, c.ExpectedWaiting, waiting, c.Name)
+		}
+	}
+}